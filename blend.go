@@ -0,0 +1,344 @@
+package gart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/svg"
+	"golang.org/x/image/draw"
+)
+
+// BlendMode is how a group is combined with what's under it, see BeginGroup.
+type BlendMode int
+
+// The blend modes, as in CSS and PDF
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendAdd
+	BlendDarken
+	BlendLighten
+	BlendDifference
+)
+
+var blendNames = []string{"normal", "multiply", "screen", "overlay", "add", "darken", "lighten", "difference"}
+
+func (m BlendMode) String() string {
+	if m < 0 || int(m) >= len(blendNames) {
+		return fmt.Sprintf("BlendMode(%d)", int(m))
+	}
+	return blendNames[m]
+}
+
+// cssName is the mix-blend-mode for SVG. plus-lighter is recent, where it's
+// not supported the group is drawn as normal.
+func (m BlendMode) cssName() string {
+	if m == BlendAdd {
+		return "plus-lighter"
+	}
+	return m.String()
+}
+
+// group is what's drawn between BeginGroup and EndGroup, or with a clip
+type group struct {
+	c       *canvas.Canvas
+	parent  canvas.Renderer
+	mode    BlendMode
+	opacity float64
+	clip    *clipMask   // set for the groups Clip and Mask open
+	clips   []*clipMask // the clips in effect at BeginGroup
+}
+
+// BeginGroup draws what follows offscreen until EndGroup, which composites it
+// onto what's under it with `mode` and `opacity` (0 to 1). Groups can nest.
+// PNG and the other raster formats support every mode. SVG maps them to
+// mix-blend-mode, BlendAdd to plus-lighter which many renderers don't know and
+// draw as normal. PDF maps them to its blend modes, it has no add so BlendAdd
+// is Screen, the same where either color is black and darker elsewhere. EPS
+// has no transparency and draws the group in place.
+func (ctx *Context) BeginGroup(mode BlendMode, opacity float64) {
+	g := &group{
+		c:       canvas.New(ctx.c.W, ctx.c.H),
+		parent:  ctx.ctx.Renderer,
+		mode:    mode,
+		opacity: Clamp(opacity, 0, 1),
+		clips:   ctx.state.clips,
+	}
+	ctx.groups = append(ctx.groups, g)
+	ctx.ctx.Renderer = g.c
+}
+
+// EndGroup composites the group started by the last BeginGroup. If there's no
+// group this does nothing.
+func (ctx *Context) EndGroup() {
+	i := ctx.blendGroup()
+	if i < 0 {
+		return
+	}
+	ctx.closeGroups(i + 1)
+	g := ctx.groups[i]
+	ctx.groups = ctx.groups[:i]
+	ctx.ctx.Renderer = g.parent
+	if !g.c.Empty() {
+		g.parent.RenderImage(groupImage{g}, Identity)
+	}
+	ctx.applyClips()
+}
+
+// groupImage carries a group through the canvas, which only knows paths, text
+// and images. The renderers in gart draw it, to anything else it's a blank pixel.
+type groupImage struct {
+	*group
+}
+
+func (gi groupImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (gi groupImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, 1, 1)
+}
+
+func (gi groupImage) At(x, y int) color.Color {
+	return color.RGBA64{}
+}
+
+// rasterize draws the group on a page of `size` pixels, masked by its clip.
+func (g *group) rasterize(size image.Point, res float64, antialias bool) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rectangle{Max: size})
+	drawCanvas(img, g.c, res, antialias)
+	if g.clip == nil {
+		return img
+	}
+	clipped := image.NewRGBA64(img.Bounds())
+	draw.DrawMask(clipped, img.Bounds(), img, image.Point{}, g.clip.alpha(size, res, antialias), image.Point{}, draw.Src)
+	return clipped
+}
+
+// blendImage composites `src` onto `dst`, which are the same size.
+func blendImage(dst draw.Image, src *image.RGBA64, mode BlendMode, opacity float64) {
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			s := src.RGBA64At(x-b.Min.X, y-b.Min.Y)
+			if s.A == 0 {
+				continue
+			}
+			dr, dg, db, da := dst.At(x, y).RGBA()
+			sa := float64(s.A) / 0xffff * opacity
+			ua := float64(da) / 0xffff
+			alpha := sa + ua - sa*ua
+			if mode == BlendAdd {
+				alpha = math.Min(1, sa+ua)
+			}
+			ch := func(sc uint16, dc uint32) uint16 {
+				cs := float64(sc) / 0xffff * opacity
+				cd := float64(dc) / 0xffff
+				return uint16(Clamp(blendChannel(mode, cs, sa, cd, ua), 0, alpha)*0xffff + 0.5)
+			}
+			dst.Set(x, y, color.RGBA64{ch(s.R, dr), ch(s.G, dg), ch(s.B, db), uint16(alpha*0xffff + 0.5)})
+		}
+	}
+}
+
+// blendChannel blends the premultiplied source cs,sa onto cd,da.
+func blendChannel(mode BlendMode, cs, sa, cd, da float64) float64 {
+	if mode == BlendAdd {
+		return math.Min(1, cs+cd)
+	}
+	// the blend functions take colors without the alpha
+	s, d := 0.0, 0.0
+	if sa > 0 {
+		s = cs / sa
+	}
+	if da > 0 {
+		d = cd / da
+	}
+	var mixed float64
+	switch mode {
+	case BlendMultiply:
+		mixed = s * d
+	case BlendScreen:
+		mixed = s + d - s*d
+	case BlendOverlay:
+		if d <= 0.5 {
+			mixed = 2 * s * d
+		} else {
+			mixed = 1 - 2*(1-s)*(1-d)
+		}
+	case BlendDarken:
+		mixed = math.Min(s, d)
+	case BlendLighten:
+		mixed = math.Max(s, d)
+	case BlendDifference:
+		mixed = math.Abs(s - d)
+	default:
+		mixed = s
+	}
+	return cs*(1-da) + cd*(1-sa) + sa*da*mixed
+}
+
+// svgGroups is the SVG renderer with groups written as <g> elements, and paints.
+type svgGroups struct {
+	*svg.SVG
+	w     io.Writer
+	ids   *int                 // paint servers and clips written so far
+	clips map[*clipMask]string // their ids
+}
+
+func newSVGGroups(r *svg.SVG, w io.Writer) svgGroups {
+	return svgGroups{r, w, new(int), map[*clipMask]string{}}
+}
+
+func (s svgGroups) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	s.SVG.RenderPath(path, svgStyle(style), m)
+}
+
+func (s svgGroups) RenderImage(img image.Image, m canvas.Matrix) {
+	if pi, ok := img.(paintImage); ok {
+		s.renderPaint(pi)
+		return
+	}
+	g, ok := img.(groupImage)
+	if !ok {
+		s.SVG.RenderImage(img, m)
+		return
+	}
+	if g.c.Empty() {
+		return
+	}
+	attrs, style := "", ""
+	if g.clip != nil {
+		attrs = s.clipAttr(g.clip)
+	}
+	if g.mode != BlendNormal {
+		style += "mix-blend-mode:" + g.mode.cssName() + ";"
+	}
+	if g.opacity < 1 {
+		style += "opacity:" + formatNum(g.opacity) + ";"
+	}
+	if style != "" {
+		attrs += ` style="` + style + `"`
+	}
+	fmt.Fprintf(s.w, `<g%s>`, attrs)
+	g.c.Render(s)
+	io.WriteString(s.w, `</g>`)
+}
+
+// clipAttr returns the attribute clipping a group to `cm`, writing it the
+// first time as a clipPath or, with a soft edge, as a mask.
+func (s svgGroups) clipAttr(cm *clipMask) string {
+	kind := "clip-path"
+	if cm.mask != nil {
+		kind = "mask"
+	}
+	id, ok := s.clips[cm]
+	if ok {
+		return fmt.Sprintf(` %s="url(#%s)"`, kind, id)
+	}
+	*s.ids++
+	id = fmt.Sprintf("clip%d", *s.ids)
+	s.clips[cm] = id
+	w, h := s.SVG.Size()
+	if cm.mask == nil {
+		d := cm.path.Transform(Identity.ReflectYAbout(h / 2)).ToSVG()
+		fmt.Fprintf(s.w, `<defs><clipPath id="%s" clipPathUnits="userSpaceOnUse"><path d="%s"/></clipPath></defs>`, id, d)
+	} else {
+		var buf bytes.Buffer
+		r := svg.New(&buf, w, h)
+		cm.mask.Render(svgGroups{r, &buf, s.ids, s.clips})
+		out := buf.String()
+		fmt.Fprintf(s.w, `<defs><mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%s" height="%s">%s</mask></defs>`,
+			id, formatNum(w), formatNum(h), out[strings.Index(out, ">")+1:])
+	}
+	return fmt.Sprintf(` %s="url(#%s)"`, kind, id)
+}
+
+// flattenGroups returns `c` with the blend groups drawn in place and their
+// opacity applied to each path, for the plotters, or `c` itself when it has
+// none. The clip groups are kept.
+func flattenGroups(c *canvas.Canvas) *canvas.Canvas {
+	gf := &groupFinder{}
+	c.Render(gf)
+	if !gf.found {
+		return c
+	}
+	flat := canvas.New(c.W, c.H)
+	c.Render(&flattener{flat, 1})
+	return flat
+}
+
+// groupFinder is a canvas.Renderer looking for groups
+type groupFinder struct {
+	found bool
+}
+
+func (gf *groupFinder) Size() (float64, float64)                             { return 0, 0 }
+func (gf *groupFinder) RenderPath(*canvas.Path, canvas.Style, canvas.Matrix) {}
+func (gf *groupFinder) RenderText(*canvas.Text, canvas.Matrix)               {}
+func (gf *groupFinder) RenderImage(img image.Image, _ canvas.Matrix) {
+	_, ok := img.(groupImage)
+	gf.found = gf.found || ok
+}
+
+// flattener copies onto `dst` with the groups drawn in place and their
+// opacity applied to each path.
+type flattener struct {
+	dst     *canvas.Canvas
+	opacity float64
+}
+
+func (f *flattener) Size() (float64, float64) {
+	return f.dst.Size()
+}
+
+func (f *flattener) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if f.opacity < 1 {
+		style.FillColor = fade(style.FillColor, f.opacity)
+		style.StrokeColor = fade(style.StrokeColor, f.opacity)
+	}
+	f.dst.RenderPath(path, style, m)
+}
+
+func (f *flattener) RenderText(text *canvas.Text, m canvas.Matrix) {
+	f.dst.RenderText(text, m)
+}
+
+func (f *flattener) RenderImage(img image.Image, m canvas.Matrix) {
+	switch img := img.(type) {
+	case groupImage:
+		if img.clip == nil {
+			img.c.Render(&flattener{f.dst, f.opacity * img.opacity})
+			return
+		}
+		g := *img.group
+		g.c = canvas.New(f.dst.W, f.dst.H)
+		img.c.Render(&flattener{g.c, f.opacity})
+		f.dst.RenderImage(groupImage{&g}, m)
+		return
+	case paintImage:
+		if f.opacity < 1 {
+			img.style.FillColor = fade(img.style.FillColor, f.opacity)
+			img.style.StrokeColor = fade(img.style.StrokeColor, f.opacity)
+		}
+		f.dst.RenderImage(img, m)
+		return
+	}
+	f.dst.RenderImage(img, m)
+}
+
+// fade scales the premultiplied color by opacity
+func fade(c color.RGBA, opacity float64) color.RGBA {
+	scale := func(v uint8) uint8 {
+		return uint8(float64(v)*opacity + 0.5)
+	}
+	return color.RGBA{scale(c.R), scale(c.G), scale(c.B), scale(c.A)}
+}
@@ -0,0 +1,90 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestBlendModes(t *testing.T) {
+	yellow := color.RGBA{0xff, 0xff, 0, 0xff}
+	cyan := color.RGBA{0, 0xff, 0xff, 0xff}
+	tests := []struct {
+		mode    BlendMode
+		opacity float64
+		want    color.RGBA
+	}{
+		{BlendNormal, 1, cyan},
+		{BlendNormal, 0.5, color.RGBA{0x80, 0xff, 0x80, 0xff}},
+		{BlendMultiply, 1, color.RGBA{0, 0xff, 0, 0xff}},
+		{BlendScreen, 1, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{BlendAdd, 1, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{BlendDarken, 1, color.RGBA{0, 0xff, 0, 0xff}},
+		{BlendLighten, 1, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{BlendDifference, 1, color.RGBA{0xff, 0, 0xff, 0xff}},
+		{BlendOverlay, 1, color.RGBA{0xff, 0xff, 0, 0xff}},
+	}
+	for _, test := range tests {
+		ctx := NewContext(10, 10)
+		ctx.SetFillColor(yellow)
+		ctx.FillRect(0, 0, 10, 10)
+		ctx.BeginGroup(test.mode, test.opacity)
+		ctx.SetFillColor(cyan)
+		ctx.FillRect(0, 0, 5, 10)
+		ctx.EndGroup()
+
+		img, err := PNGOptions{Resolution: 1}.Rasterize(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, g, b, a := img.At(2, 5).RGBA()
+		got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if colorDist(got, test.want) > 1 {
+			t.Errorf("%v at %.1f = %v, want %v", test.mode, test.opacity, got, test.want)
+		}
+		r, g, b, _ = img.At(7, 5).RGBA()
+		if got := (color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff}); got != yellow {
+			t.Errorf("%v changed the pixels outside the group to %v", test.mode, got)
+		}
+	}
+}
+
+func TestGroupFormats(t *testing.T) {
+	ctx := NewContext(10, 10)
+	ctx.BeginGroup(BlendMultiply, 0.5)
+	ctx.SetStrokeColor(color.Black)
+	ctx.MoveTo(1, 1)
+	ctx.LineTo(9, 9)
+	ctx.Stroke()
+	ctx.EndGroup()
+
+	var buf bytes.Buffer
+	if err := SVGWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<g style="mix-blend-mode:multiply;opacity:0.5;"><path`; !strings.Contains(buf.String(), want) {
+		t.Errorf("SVG = %s, want it to contain %s", buf.String(), want)
+	}
+	buf.Reset()
+	if err := PDFWriter(&buf, ctx); err != nil {
+		t.Errorf("PDF failed: %v", err)
+	}
+	if want := "/BM /Multiply /CA 0.5 /ca 0.5"; !strings.Contains(buf.String(), want) {
+		t.Errorf("PDF doesn't contain %s", want)
+	}
+	if paths, _ := PlotPaths(ctx, 0.1); len(paths) != 1 {
+		t.Errorf("plotted %d paths, want the one in the group", len(paths))
+	}
+	// PDF has nothing for it so it's screened
+	ctx.BeginGroup(BlendAdd, 1)
+	ctx.FillRect(0, 0, 5, 5)
+	ctx.EndGroup()
+	buf.Reset()
+	if err := PDFWriter(&buf, ctx); err != nil {
+		t.Errorf("PDF with BlendAdd failed: %v", err)
+	}
+	if want := "/BM /Screen "; !strings.Contains(buf.String(), want) {
+		t.Errorf("PDF doesn't contain %s", want)
+	}
+}
@@ -0,0 +1,222 @@
+package gart
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// Corner is where on the page a Caption goes.
+type Corner int
+
+const (
+	BottomRight Corner = iota
+	BottomLeft
+	TopLeft
+	TopRight
+)
+
+var (
+	cornerNames    = []string{"bottom-right", "bottom-left", "top-left", "top-right"}
+	cornerInitials = []string{"br", "bl", "tl", "tr"}
+)
+
+func (c Corner) String() string {
+	if c < 0 || int(c) >= len(cornerNames) {
+		return fmt.Sprintf("Corner(%d)", int(c))
+	}
+	return cornerNames[c]
+}
+
+// ParseCorner reads a corner name like "bottom-right", or its initials "br".
+func ParseCorner(s string) (Corner, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range cornerNames {
+		if s == name || s == cornerInitials[i] {
+			return Corner(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown corner %q, want one of %s", s, strings.Join(cornerNames, ", "))
+}
+
+// Caption is a line like "substrate  1a2b3c4d  2020-10-21" with the sketch,
+// hex seed and date, to sign prints with. See SetCaption to add it to what's saved.
+type Caption struct {
+	Corner Corner
+	Font   *Font       // Hershey when nil
+	Size   float64     // mm, 3 when 0
+	Margin float64     // mm from the edges of the page, 5 when 0
+	Color  color.Color // black when nil
+}
+
+// Text returns the caption for a file with the provenance `p`.
+func (c Caption) Text(p *Provenance) string {
+	ts := p.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	var parts []string
+	for _, s := range []string{p.Sketch, p.Seed, ts.Format("2006-01-02")} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Stamp draws the caption for `p` on `d`. Only a Context can draw TTF and
+// OTF fonts, the other Drawers use Hershey.
+func (c Caption) Stamp(d Drawer, p *Provenance) {
+	size, margin, col, font := c.Size, c.Margin, c.Color, c.Font
+	if size <= 0 {
+		size = 3
+	}
+	if margin <= 0 {
+		margin = 5
+	}
+	if col == nil {
+		col = color.Black
+	}
+	if font == nil {
+		font = Hershey
+	}
+	width, height := d.Size()
+	x, y, align := margin, margin, AlignLeft
+	if c.Corner == BottomRight || c.Corner == TopRight {
+		x, align = width-margin, AlignRight
+	}
+	if c.Corner == TopLeft || c.Corner == TopRight {
+		y = height - margin - 0.7*size // the capitals' height
+	}
+	text := c.Text(p)
+
+	d.Push()
+	defer d.Pop()
+	d.ResetClip()
+	d.SetMatrix(Identity) // mm whatever the units
+	d.SetFillColor(col)
+	d.SetStrokeColor(col)
+	d.SetStrokeWidth(size / 15)
+	if ctx, ok := d.(*Context); ok {
+		ctx.SetFont(font, size)
+		ctx.SetTextAlign(align)
+		ctx.DrawText(text, x, y, 0)
+		return
+	}
+	for _, line := range hersheyLines(text, size, align) {
+		for i, pt := range line {
+			if i == 0 {
+				d.MoveTo(x+pt.X, y+pt.Y)
+			} else {
+				d.LineTo(x+pt.X, y+pt.Y)
+			}
+		}
+	}
+	d.Stroke()
+}
+
+// exportCaption is stamped on what's saved, see SetCaption
+var exportCaption *Caption
+
+// SetCaption stamps `c` on the files saved by SafeWrite, SafeWriteAll and
+// SafeWriteLayers from now on, nil stops it. The Drawer itself is left as it was.
+func SetCaption(c *Caption) {
+	exportCaption = c
+}
+
+// CaptionFlag defines the -caption flag, the corner to stamp a caption in,
+// and returns the Caption it turns on so its size etc. can be changed.
+func CaptionFlag() *Caption {
+	c := &Caption{}
+	flag.Var(captionValue{c}, "caption", "Stamp the sketch, seed and date in this corner of the files saved, one of "+
+		strings.Join(cornerNames, ", ")+" or none")
+	return c
+}
+
+type captionValue struct{ c *Caption }
+
+func (v captionValue) String() string {
+	if v.c == nil || exportCaption != v.c {
+		return ""
+	}
+	return v.c.Corner.String()
+}
+
+func (v captionValue) Set(s string) error {
+	if s == "" || s == "none" {
+		SetCaption(nil)
+		return nil
+	}
+	corner, err := ParseCorner(s)
+	if err != nil {
+		return err
+	}
+	v.c.Corner = corner
+	SetCaption(v.c)
+	return nil
+}
+
+// stamped returns `d` with the export caption for `p` drawn on a copy of
+// it, or `d` itself when there's no caption.
+func stamped(d Drawer, p *Provenance) Drawer {
+	c := exportCaption
+	if c == nil || p == nil {
+		return d
+	}
+	var cp Drawer
+	switch d := d.(type) {
+	case *Context:
+		cp = d.clone()
+	case *GGContext:
+		cp = d.clone()
+	case *Recorder:
+		r := *d
+		r.Ops = append([]Op(nil), d.Ops...)
+		r.views = append([]Matrix(nil), d.views...)
+		cp = &r
+	default:
+		return d
+	}
+	c.Stamp(cp, p)
+	return cp
+}
+
+// clone returns a Context drawing on top of a copy of what's on this one,
+// in the default layer.
+func (ctx *Context) clone() *Context {
+	c := *ctx.c
+	tags := *ctx.tags
+	tags.Canvas = &c
+	tags.cur = 0
+	cp := &Context{
+		pathBuilder: pathBuilder{path: &canvas.Path{}},
+		c:           &c,
+		tags:        &tags,
+		layers:      append([]string(nil), ctx.layers...),
+		unit:        ctx.unit,
+		provenance:  ctx.provenance,
+	}
+	cp.ctx = canvas.NewContext(cp.tags)
+	cp.ResetMatrix()
+	return cp
+}
+
+// clone returns a GGContext drawing on a copy of this one's image.
+func (g *GGContext) clone() *GGContext {
+	cp := *g
+	cp.pathBuilder = pathBuilder{path: &canvas.Path{}}
+	cp.stack = append([]ggStyle(nil), g.stack...)
+	src := g.dc.Image()
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	cp.dc = gg.NewContextForRGBA(img)
+	cp.dc.SetLineCapButt()
+	return &cp
+}
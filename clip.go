@@ -0,0 +1,272 @@
+package gart
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// clipMask is a region drawing is restricted to, see Clip and Mask.
+type clipMask struct {
+	path *canvas.Path   // in mm on the page, the outline of a mask
+	mask *canvas.Canvas // drawn in white with the alpha of the mask, nil for a clip
+}
+
+// Clip restricts what's drawn from now on to the inside of the current path,
+// and starts a new one. It intersects with the clip already set, Pop goes back
+// to the clip there was at Push and ResetClip removes them all.
+// PNG, SVG, PDF and EPS are clipped exactly and plotters clip the strokes'
+// center lines.
+func (ctx *Context) Clip() {
+	ctx.addClip(&clipMask{path: ctx.path.Transform(ctx.ctx.View())})
+	ctx.path = &canvas.Path{}
+}
+
+// ClipRect clips to the rectangle x,y to x+w,y+h like Clip, leaving the current path alone.
+func (ctx *Context) ClipRect(x, y, w, h float64) {
+	ctx.addClip(&clipMask{path: canvas.Rectangle(w, h).Translate(x, y).Transform(ctx.ctx.View())})
+}
+
+// ResetClip removes the clips and masks, until the next Pop brings back the ones pushed.
+func (ctx *Context) ResetClip() {
+	ctx.state.clips = nil
+	ctx.applyClips()
+}
+
+// Mask is Clip with a soft edge, the current path is filled with the fill
+// color or paint and how opaque that is is how much of what's drawn from now
+// on shows through, ex. with a RadialGradient fading to transparent.
+// Plotters can't do partial ink so they clip to the path, as does EPS.
+func (ctx *Context) Mask() {
+	view := ctx.ctx.View()
+	style := ctx.ctx.Style
+	style.StrokeColor = canvas.Transparent
+	style.FillColor = color.RGBAModel.Convert(whiten(style.FillColor)).(color.RGBA)
+	c := canvas.New(ctx.c.W, ctx.c.H)
+	if ctx.state.fill == nil {
+		c.RenderPath(ctx.path, style, view)
+	} else {
+		c.RenderImage(paintImage{ctx.path.Copy(), style, view, maskPaint(ctx.state.fill), nil}, Identity)
+	}
+	ctx.addClip(&clipMask{path: ctx.path.Transform(view), mask: c})
+	ctx.path = &canvas.Path{}
+}
+
+// MaskImage masks like Mask with the alpha of `img` stretched over the
+// rectangle x,y to x+w,y+h, outside it nothing shows.
+func (ctx *Context) MaskImage(img image.Image, x, y, w, h float64) {
+	b := img.Bounds()
+	if b.Empty() {
+		ctx.ClipRect(x, y, 0, 0)
+		return
+	}
+	m := ctx.ctx.View().Translate(x, y).Scale(w/float64(b.Dx()), h/float64(b.Dy()))
+	c := canvas.New(ctx.c.W, ctx.c.H)
+	c.RenderImage(whiteImage(img), m)
+	ctx.addClip(&clipMask{path: canvas.Rectangle(w, h).Translate(x, y).Transform(ctx.ctx.View()), mask: c})
+}
+
+func (ctx *Context) addClip(cm *clipMask) {
+	n := len(ctx.state.clips)
+	ctx.state.clips = append(ctx.state.clips[:n:n], cm)
+	ctx.applyClips()
+}
+
+// applyClips opens and closes clip groups to match the clips in effect.
+// Inside a group those set before BeginGroup stay until its EndGroup.
+func (ctx *Context) applyClips() {
+	want := ctx.state.clips
+	first := ctx.blendGroup() + 1
+	if first > 0 {
+		outside := ctx.groups[first-1].clips
+		i := 0
+		for i < len(want) && i < len(outside) && want[i] == outside[i] {
+			i++
+		}
+		want = want[i:]
+	}
+	have := ctx.groups[first:]
+	i := 0
+	for i < len(want) && i < len(have) && want[i] == have[i].clip {
+		i++
+	}
+	ctx.closeGroups(first + i)
+	for _, cm := range want[i:] {
+		g := &group{
+			c:       canvas.New(ctx.c.W, ctx.c.H),
+			parent:  ctx.ctx.Renderer,
+			opacity: 1,
+			clip:    cm,
+		}
+		// it goes in now, what's drawn in it is read when it's written
+		g.parent.RenderImage(groupImage{g}, Identity)
+		ctx.groups = append(ctx.groups, g)
+		ctx.ctx.Renderer = g.c
+	}
+}
+
+// closeGroups closes the clip groups past the first n groups.
+func (ctx *Context) closeGroups(n int) {
+	for len(ctx.groups) > n {
+		g := ctx.groups[len(ctx.groups)-1]
+		ctx.groups = ctx.groups[:len(ctx.groups)-1]
+		ctx.ctx.Renderer = g.parent
+	}
+}
+
+// blendGroup returns the index of the innermost group BeginGroup opened, or -1.
+func (ctx *Context) blendGroup() int {
+	for i := len(ctx.groups) - 1; i >= 0; i-- {
+		if ctx.groups[i].clip == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// alpha returns how much shows through each pixel of a page of `size` pixels.
+func (cm *clipMask) alpha(size image.Point, res float64, antialias bool) *image.Alpha {
+	if cm.mask == nil {
+		return coverage(cm.path, size.X, size.Y, res, antialias)
+	}
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	drawCanvas(img, cm.mask, res, antialias)
+	a := image.NewAlpha(img.Bounds())
+	for i := range a.Pix {
+		a.Pix[i] = img.Pix[4*i+3]
+	}
+	return a
+}
+
+// whiten returns white with the alpha of `col`, so a mask is the same
+// whether its alpha or luminance is used, as SVG does.
+func whiten(col color.Color) color.Color {
+	_, _, _, a := col.RGBA()
+	return color.RGBA64{uint16(a), uint16(a), uint16(a), uint16(a)}
+}
+
+func whiteImage(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		a := out.Pix[i+3]
+		out.Pix[i], out.Pix[i+1], out.Pix[i+2] = a, a, a
+	}
+	return out
+}
+
+// maskPaint returns `p` in white with its alpha, keeping the kinds SVG has.
+func maskPaint(p Paint) Paint {
+	white := func(g Gradient) Gradient {
+		stops := make([]Stop, len(g.Stops))
+		for i, s := range g.Stops {
+			stops[i] = Stop{s.Offset, whiten(s.Color)}
+		}
+		return Gradient{stops, SRGB} // the alpha is mixed linearly in any space
+	}
+	switch p := p.(type) {
+	case LinearGradient:
+		p.Gradient = white(p.Gradient)
+		return p
+	case RadialGradient:
+		p.Gradient = white(p.Gradient)
+		return p
+	case Pattern:
+		p.Image = whiteImage(p.Image)
+		return p
+	}
+	return whitePaint{p}
+}
+
+type whitePaint struct {
+	Paint
+}
+
+func (wp whitePaint) At(x, y float64) color.Color {
+	return whiten(wp.Paint.At(x, y))
+}
+
+// clipPolyline returns the parts of `points` inside `rings`, closed
+// polylines filled with the nonzero rule.
+func clipPolyline(points []canvas.Point, rings [][]canvas.Point) [][]canvas.Point {
+	var lines [][]canvas.Point
+	var cur []canvas.Point
+	flush := func() {
+		if len(cur) > 1 {
+			lines = append(lines, cur)
+		}
+		cur = nil
+	}
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		ts := []float64{0, 1}
+		for _, ring := range rings {
+			for j := 1; j < len(ring); j++ {
+				if t, ok := crossing(a, b, ring[j-1], ring[j]); ok {
+					ts = append(ts, t)
+				}
+			}
+		}
+		sort.Float64s(ts)
+		for k := 1; k < len(ts); k++ {
+			if ts[k] <= ts[k-1] {
+				continue
+			}
+			p0, p1 := a.Interpolate(b, ts[k-1]), a.Interpolate(b, ts[k])
+			if !insideRings(rings, p0.Interpolate(p1, 0.5)) {
+				flush()
+				continue
+			}
+			if len(cur) == 0 {
+				cur = append(cur, p0)
+			}
+			cur = append(cur, p1)
+		}
+	}
+	flush()
+	return lines
+}
+
+// crossing returns how far along a,b it crosses c,d, from 0 to 1.
+func crossing(a, b, c, d canvas.Point) (float64, bool) {
+	ab, cd, ac := b.Sub(a), d.Sub(c), c.Sub(a)
+	denom := ab.X*cd.Y - ab.Y*cd.X
+	if denom == 0 {
+		return 0, false // parallel
+	}
+	t := (ac.X*cd.Y - ac.Y*cd.X) / denom
+	u := (ac.X*ab.Y - ac.Y*ab.X) / denom
+	return t, 0 <= t && t <= 1 && 0 <= u && u <= 1
+}
+
+// insideRings is true when `p` has a nonzero winding number around `rings`.
+func insideRings(rings [][]canvas.Point, p canvas.Point) bool {
+	winding := 0
+	for _, ring := range rings {
+		for j := 1; j < len(ring); j++ {
+			a, b := ring[j-1], ring[j]
+			side := (b.X-a.X)*(p.Y-a.Y) - (p.X-a.X)*(b.Y-a.Y)
+			if a.Y <= p.Y && b.Y > p.Y && side > 0 {
+				winding++
+			} else if a.Y > p.Y && b.Y <= p.Y && side < 0 {
+				winding--
+			}
+		}
+	}
+	return winding != 0
+}
+
+// clipRings returns the clip's outline flattened into closed polylines.
+func (cm *clipMask) clipRings(tolerance float64) [][]canvas.Point {
+	rings := flatten(cm.path, tolerance)
+	for i, ring := range rings {
+		if ring[0] != ring[len(ring)-1] {
+			rings[i] = append(ring, ring[0])
+		}
+	}
+	return rings
+}
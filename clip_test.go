@@ -0,0 +1,192 @@
+package gart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+// drawClipped fills the page inside a triangle clip intersected with the left
+// half, then after Pop the bottom row without a clip.
+func drawClipped(d Drawer) {
+	d.SetFillColor(color.Black)
+	d.Push()
+	d.Polygon(0, 0, 10, 0, 10, 10)
+	d.Clip()
+	d.ClipRect(0, 0, 5, 10)
+	d.FillRect(0, 0, 10, 10)
+	d.Pop()
+	d.FillRect(0, 9, 10, 1)
+}
+
+func TestClip(t *testing.T) {
+	rec := NewRecorder(10, 10)
+	drawClipped(rec)
+	gg := NewGGContext(10, 10, 1)
+	drawClipped(gg)
+	ctx := NewContext(10, 10)
+	drawClipped(ctx)
+	for _, d := range []Drawer{ctx, gg, rec} {
+		img, err := rasterize(d, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range []struct {
+			x, y  int
+			drawn bool
+		}{
+			{4, 8, true},  // in both
+			{8, 8, false}, // in the triangle only
+			{2, 2, false}, // in the left half only
+			{8, 0, true},  // after Pop
+			{2, 0, true},
+		} {
+			if _, _, _, a := img.At(test.x, test.y).RGBA(); (a > 0x8000) != test.drawn {
+				t.Errorf("%T pixel %d,%d has alpha %x, want drawn %v", d, test.x, test.y, a, test.drawn)
+			}
+		}
+	}
+}
+
+func TestClipGroups(t *testing.T) {
+	ctx := NewContext(10, 10)
+	ctx.ClipRect(0, 0, 5, 10)
+	ctx.BeginGroup(BlendNormal, 1)
+	ctx.Push()
+	ctx.ClipRect(0, 0, 10, 5)
+	ctx.SetLayer("top")
+	ctx.FillRect(0, 0, 10, 10)
+	ctx.ResetClip() // only back to the clip outside the group
+	ctx.FillRect(0, 8, 10, 2)
+	ctx.Pop()
+	ctx.EndGroup()
+	ctx.ResetClip()
+	ctx.FillRect(9, 0, 1, 1)
+
+	img, err := rasterize(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		x, y  int
+		drawn bool
+	}{
+		{2, 8, true},
+		{7, 8, false},
+		{2, 0, true},
+		{7, 0, false},
+		{2, 3, false},
+		{9, 9, true},
+	} {
+		if _, _, _, a := img.At(test.x, test.y).RGBA(); (a > 0x8000) != test.drawn {
+			t.Errorf("pixel %d,%d has alpha %x, want drawn %v", test.x, test.y, a, test.drawn)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	ctx := NewContext(20, 10)
+	ctx.Push()
+	ctx.SetFillPaint(NewLinearGradient(0, 0, 10, 0, EvenStops(color.Black, color.Transparent)...))
+	ctx.Rect(0, 0, 10, 10)
+	ctx.Mask()
+	ctx.SetFillColor(color.Black)
+	ctx.FillRect(0, 0, 20, 10)
+	ctx.Pop()
+	alpha := image.NewAlpha(image.Rect(0, 0, 2, 1))
+	alpha.Pix[1] = 0xff
+	ctx.MaskImage(alpha, 10, 0, 10, 10)
+	ctx.FillRect(0, 0, 20, 10)
+
+	img, err := PNGOptions{Resolution: 1}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		x     int
+		alpha uint32
+	}{
+		{0, 0xf2},
+		{5, 0x73},
+		{9, 0x0d},
+		{11, 0},
+		{18, 0xff},
+	} {
+		if _, _, _, a := img.At(test.x, 5).RGBA(); int(a>>8)-int(test.alpha) > 8 || int(test.alpha)-int(a>>8) > 8 {
+			t.Errorf("pixel %d has alpha %x, want %x", test.x, a>>8, test.alpha)
+		}
+	}
+}
+
+func TestClipFormats(t *testing.T) {
+	ctx := NewContext(100, 100)
+	ctx.SetStrokeColor(color.Black)
+	ctx.Circle(50, 50, 20)
+	ctx.Clip()
+	ctx.MoveTo(0, 50)
+	ctx.LineTo(100, 50)
+	ctx.Stroke()
+	ctx.SetFillPaint(NewRadialGradient(50, 50, 10, EvenStops(color.Black, color.Transparent)...))
+	ctx.Circle(50, 50, 10)
+	ctx.Mask()
+	ctx.FillRect(0, 0, 100, 100)
+
+	var buf bytes.Buffer
+	if err := SVGWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<clipPath id="clip1"`, `<g clip-path="url(#clip1)">`, `<mask id="clip2"`, `<g mask="url(#clip2)">`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("SVG = %s, want it to contain %s", buf.String(), want)
+		}
+	}
+	// PDF clips to the path and masks with a soft mask, EPS just clips
+	for _, f := range []struct {
+		name  string
+		write WriterFunc
+		want  []string
+	}{
+		{"PDF", PDFWriter, []string{" h W n ", "/SMask << /Type /Mask /G "}},
+		{"EPS", EPSWriter, []string{" closepath clip newpath "}},
+	} {
+		buf.Reset()
+		if err := f.write(&buf, ctx); err != nil {
+			t.Errorf("%s failed: %v", f.name, err)
+			continue
+		}
+		for _, want := range f.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s doesn't contain %q", f.name, want)
+			}
+		}
+	}
+	paths, err := PlotPaths(ctx, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || len(paths[0].Points) != 2 {
+		t.Fatalf("plotted %v, want the line across the circle", paths)
+	}
+	if p := paths[0]; !p.Start().Equals(canvas.Point{X: 30, Y: 50}) || !p.End().Equals(canvas.Point{X: 70, Y: 50}) {
+		t.Errorf("plotted %v, want 30,50 to 70,50", p.Points)
+	}
+}
+
+func TestClipPolyline(t *testing.T) {
+	// a U shape, the polyline crosses its gap
+	ring := line(color.RGBA{}, 0, 0, 3, 0, 3, 3, 2, 3, 2, 1, 1, 1, 1, 3, 0, 3, 0, 0).Points
+	got := clipPolyline(line(color.RGBA{}, -1, 2, 4, 2).Points, [][]canvas.Point{ring})
+	want := []PlotPath{line(color.RGBA{}, 0, 2, 1, 2), line(color.RGBA{}, 2, 2, 3, 2)}
+	if len(got) != len(want) {
+		t.Fatalf("clipped into %v, want %v", got, want)
+	}
+	for i, w := range want {
+		if len(got[i]) != 2 || !got[i][0].Equals(w.Start()) || !got[i][1].Equals(w.End()) {
+			t.Errorf("piece %d = %v, want %v", i, got[i], w.Points)
+		}
+	}
+}
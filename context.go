@@ -4,100 +4,169 @@ import (
 	"image/color"
 
 	"github.com/tdewolff/canvas"
-	"github.com/tdewolff/canvas/pdf"
-	"github.com/tdewolff/canvas/rasterizer"
-	"github.com/tdewolff/canvas/svg"
 )
 
-// Context is my abstraction for Canvas (or gg)
+// Context is the Drawer backed by tdewolff/canvas, it records everything
+// drawn so it can be written to both vector and raster formats.
 type Context struct {
-	c   *canvas.Canvas
-	ctx *canvas.Context
+	pathBuilder // current path, rendered by Stroke, Fill, etc.
+	c           *canvas.Canvas
+	ctx         *canvas.Context
+	tags        *layerTagger // which layer each draw call went to
+	layers      []string     // layer names, see SetLayer
+	unit        float64      // mm per unit, see NewPage
+	groups      []*group     // open groups, see BeginGroup and Clip
+	state       drawState    // what Push saves besides the canvas style
+	stack       []drawState
+	provenance  *Provenance
+}
+
+// drawState is the Context's draw state that canvas doesn't keep
+type drawState struct {
+	fill, stroke Paint
+	font         *Font
+	fontSize     float64
+	align        TextAlign
+	join         LineJoin
+	miterLimit   float64     // 0 for the default
+	clips        []*clipMask // all intersected, see Clip
 }
 
 func NewContext(width, height float64) *Context {
 	ctx := &Context{
-		c: canvas.New(width, height),
+		pathBuilder: pathBuilder{path: &canvas.Path{}},
+		c:           canvas.New(width, height),
+		layers:      []string{DefaultLayer},
+		unit:        1,
 	}
-	ctx.ctx = canvas.NewContext(ctx.c)
+	ctx.tags = &layerTagger{Canvas: ctx.c}
+	ctx.ctx = canvas.NewContext(ctx.tags)
 	return ctx
 }
 
+// SetProvenance sets the record that writers embed in the files they write.
+func (ctx *Context) SetProvenance(p *Provenance) {
+	ctx.provenance = p
+}
+
+// Provenance returns the record to embed, or nil if there is none.
+func (ctx *Context) Provenance() *Provenance {
+	return ctx.provenance
+}
+
 // WritePNG writes to a PNG file
 func (ctx *Context) WritePNG(fname string) error {
-	return ctx.c.WriteFile(fname, rasterizer.PNGWriter(3.2))
+	return ctx.WriteFile(fname, PNGWriter(defaultResolution))
 }
 
 // WriteSVG writes to an SVG file
 func (ctx *Context) WriteSVG(fname string) error {
-	return ctx.c.WriteFile(fname, svg.Writer)
+	return ctx.WriteFile(fname, SVGWriter)
 }
 
 // WritePDF writes to a PDF file
 func (ctx *Context) WritePDF(fname string) error {
-	return ctx.c.WriteFile(fname, pdf.Writer)
+	return ctx.WriteFile(fname, PDFWriter)
 }
 
+// WriteFile writes to fname using the writer `fn`
+func (ctx *Context) WriteFile(fname string, fn WriterFunc) error {
+	return writeFile(fname, ctx, fn)
+}
+
+// setUnit makes the coordinates `unit` mm
+func (ctx *Context) setUnit(unit float64) {
+	ctx.unit = unit
+	ctx.ResetMatrix()
+}
+
+// Size returns the width and height in mm
+func (ctx *Context) Size() (float64, float64) {
+	return ctx.c.W, ctx.c.H
+}
+
+// Push saves the current draw state (colors, paints, font, stroke style, transformation and clip) so it
+// can be restored by Pop.
 func (ctx *Context) Push() {
 	ctx.ctx.Push()
+	ctx.stack = append(ctx.stack, ctx.state)
 }
 
 // Pop restores the last pushed draw state and uses that as the current draw state. If there are no
 // states on the stack, this will do nothing.
 func (ctx *Context) Pop() {
 	ctx.ctx.Pop()
+	if n := len(ctx.stack); n > 0 {
+		ctx.state = ctx.stack[n-1]
+		ctx.stack = ctx.stack[:n-1]
+		ctx.applyClips()
+	}
 }
 
-// Reset empties the canvas.
+// Reset empties the canvas, the layers are kept.
 func (ctx *Context) Reset() {
 	ctx.c.Reset()
+	ctx.tags.reset()
+	ctx.ctx.Renderer = ctx.tags
+	ctx.groups = nil
+	ctx.applyClips()
 }
 
 func (ctx *Context) SetFillColor(col color.Color) {
 	ctx.ctx.SetFillColor(col)
+	ctx.state.fill = nil
 }
 
 func (ctx *Context) SetStrokeColor(col color.Color) {
 	ctx.ctx.SetStrokeColor(col)
+	ctx.state.stroke = nil
 }
 
 func (ctx *Context) SetStrokeWidth(width float64) {
 	ctx.ctx.SetStrokeWidth(width)
 }
 
-// MoveTo moves the path to x,y without connecting the path. It starts a new independent subpath.
-// Multiple subpaths can be useful when negating parts of a previous path by overlapping it with a
-// path in the opposite direction. The behaviour for overlapping paths depend on the FillRule.
-func (ctx *Context) MoveTo(x, y float64) {
-	ctx.ctx.MoveTo(x, y)
-}
-
 // Point draws a 1 pixel rectangle at point
 func (ctx *Context) Point(x, y float64) {
-	ctx.ctx.DrawPath(x, y, canvas.Rectangle(1, 1))
-}
-
-// LineTo adds a linear path to x,y.
-func (ctx *Context) LineTo(x, y float64) {
-	ctx.ctx.LineTo(x, y)
-}
-
-// QuadTo adds a quadratic Bézier path with control point cpx,cpy and end point x,y.
-func (ctx *Context) QuadTo(cpx, cpy, x, y float64) {
-	ctx.ctx.QuadTo(cpx, cpy, x, y)
+	ctx.drawRect(x, y, 1, 1)
 }
 
 // FillRect draws a rectable path
 func (ctx *Context) FillRect(x, y, w, h float64) {
-	ctx.ctx.DrawPath(x, y, canvas.Rectangle(w, h))
+	ctx.drawRect(x, y, w, h)
+}
+
+func (ctx *Context) drawRect(x, y, w, h float64) {
+	if ctx.state.fill == nil && ctx.state.stroke == nil {
+		ctx.ctx.DrawPath(x, y, canvas.Rectangle(w, h))
+		return
+	}
+	ctx.draw(canvas.Rectangle(w, h).Translate(x, y), ctx.ctx.Style, ctx.ctx.View(), ctx.state.fill, ctx.state.stroke)
 }
 
 // Stroke strokes the current path and resets it.
 func (ctx *Context) Stroke() {
-	ctx.ctx.Stroke()
+	style := ctx.ctx.Style
+	style.FillColor = canvas.Transparent
+	ctx.render(style, nil, ctx.state.stroke)
+}
+
+// Fill fills the current path and resets it.
+func (ctx *Context) Fill() {
+	style := ctx.ctx.Style
+	style.StrokeColor = canvas.Transparent
+	ctx.render(style, ctx.state.fill, nil)
 }
 
-// Close closes the current path
-func (ctx *Context) Close() {
-	ctx.ctx.Close()
+// FillStroke fills and then strokes the current path and resets it.
+func (ctx *Context) FillStroke() {
+	ctx.render(ctx.ctx.Style, ctx.state.fill, ctx.state.stroke)
+}
+
+// render draws the current path with `style` and the paints, and starts a new one
+func (ctx *Context) render(style canvas.Style, fill, stroke Paint) {
+	if !ctx.path.Empty() {
+		ctx.draw(ctx.path, style, ctx.ctx.View(), fill, stroke)
+	}
+	ctx.path = &canvas.Path{}
 }
@@ -0,0 +1,118 @@
+package gart
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+)
+
+// Drawer is what sketches draw on. Context records the drawing with
+// tdewolff/canvas so it can be written as vectors or rasters, while
+// GGContext rasterizes straight away with fogleman/gg which needs far less
+// memory for sketches with millions of strokes.
+// Coordinates are in mm with the origin at the bottom left.
+//
+// It's what all three backends can do. Layers (SetLayer), paints
+// (SetFillPaint, SetStrokePaint), text (SetFont, DrawText, SetTextAlign),
+// blend groups (BeginGroup, EndGroup), Rasters (NewRaster) and soft masks
+// (Mask, MaskImage) are only on Context. Recorder's display list is numbers
+// that round trip through JSON, with no room for fonts, images or nested
+// drawings, and GGContext draws straight onto one image so it has nothing to
+// keep layers or groups apart in. Recorder and GGContext don't capture them,
+// so sketches type assert for *Context to use them and do without on the
+// others, see substrate.
+type Drawer interface {
+	// Size returns the width and height in mm
+	Size() (width, height float64)
+
+	SetProvenance(p *Provenance)
+	Provenance() *Provenance
+	WriteFile(fname string, fn WriterFunc) error
+
+	Push()
+	Pop()
+	Reset()
+
+	SetFillColor(col color.Color)
+	SetStrokeColor(col color.Color)
+	SetStrokeWidth(width float64)
+	SetLineCap(c LineCap)
+	SetLineJoin(j LineJoin)
+	SetMiterLimit(limit float64)
+	SetDashes(offset float64, dashes ...float64)
+
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	QuadTo(cpx, cpy, x, y float64)
+	CubicTo(cpx1, cpy1, cpx2, cpy2, x, y float64)
+	ArcTo(rx, ry, rot float64, large, sweep bool, x, y float64)
+	Arc(x, y, r, angle0, angle1 float64)
+	Close()
+
+	Circle(x, y, r float64)
+	Ellipse(x, y, rx, ry float64)
+	Rect(x, y, w, h float64)
+	RoundedRect(x, y, w, h, r float64)
+	RegularPolygon(n int, x, y, r, rotation float64)
+	Polyline(xy ...float64)
+	Polygon(xy ...float64)
+	TaperedPolyline(width func(i int) float64, xy ...float64)
+
+	Point(x, y float64)
+	FillRect(x, y, w, h float64)
+	Stroke()
+	Fill()
+	FillStroke()
+
+	Clip()
+	ClipRect(x, y, w, h float64)
+	ResetClip()
+
+	Translate(x, y float64)
+	Rotate(angle float64)
+	RotateAbout(angle, x, y float64)
+	Scale(sx, sy float64)
+	Skew(ax, ay float64)
+	Matrix() Matrix
+	SetMatrix(m Matrix)
+	ResetMatrix()
+	FitBounds(minX, minY, maxX, maxY, margin float64)
+}
+
+// The backends for NewDrawer
+const (
+	CanvasBackend = "canvas"
+	GGBackend     = "gg"
+)
+
+// NewDrawer returns a Drawer of width x height mm using `backend`,
+// handy for a -backend flag.
+// GGBackend rasterizes at the default PNG resolution.
+func NewDrawer(backend string, width, height float64) (Drawer, error) {
+	switch backend {
+	case CanvasBackend, "":
+		return NewContext(width, height), nil
+	case GGBackend:
+		return NewGGContext(width, height, defaultResolution), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q, want %q or %q", backend, CanvasBackend, GGBackend)
+}
+
+// writeFile writes `d` to fname using the writer `fn`
+func writeFile(fname string, d Drawer, fn WriterFunc) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := fn(f, d); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	_ Drawer = (*Context)(nil)
+	_ Drawer = (*GGContext)(nil)
+	_ Drawer = (*Recorder)(nil)
+)
@@ -0,0 +1,153 @@
+package gart
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// writeEPS writes `c` as encapsulated PostScript, in points like a PDF. The
+// canvas writer it replaces only filled, dropping strokes and images, and
+// had no way to clip.
+// PostScript has no alpha so colors are written opaque, images are keyed out
+// where they're less than half opaque, strokes are filled as their outlines
+// and the groups are drawn in place, clipped to their paths.
+func writeEPS(w io.Writer, c *canvas.Canvas) error {
+	bw := bufio.NewWriter(w)
+	width, height := c.W*ptPerMM, c.H*ptPerMM
+	fmt.Fprintf(bw, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %d %d\n%%%%HiResBoundingBox: 0 0 %s %s\n%%%%EndComments\n",
+		int(math.Ceil(width)), int(math.Ceil(height)), pdfNum(width), pdfNum(height))
+	fmt.Fprintf(bw, "%s %s scale", pdfNum(ptPerMM), pdfNum(ptPerMM))
+	c.Render(&epsRenderer{w: bw, width: c.W, height: c.H})
+	bw.WriteString("\n%%EOF\n")
+	return bw.Flush()
+}
+
+// epsRenderer is the canvas.Renderer writing the EPS
+type epsRenderer struct {
+	w             *bufio.Writer
+	width, height float64
+	color         string // the last setrgbcolor
+}
+
+func (r *epsRenderer) Size() (float64, float64) {
+	return r.width, r.height
+}
+
+func (r *epsRenderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	path = path.Transform(m)
+	if style.FillColor.A != 0 {
+		op := "fill"
+		if style.FillRule == canvas.EvenOdd {
+			op = "eofill"
+		}
+		r.fill(path, style.FillColor, op)
+	}
+	if stroked(style) {
+		r.fill(strokeOutline(path, style), style.StrokeColor, "fill")
+	}
+}
+
+func (r *epsRenderer) fill(path *canvas.Path, col color.RGBA, op string) {
+	if path.Empty() {
+		return
+	}
+	if set, _ := pdfColor(col, "setrgbcolor"); set != r.color {
+		r.color = set
+		fmt.Fprintf(r.w, " %s", set)
+	}
+	// without arcs ToPS doesn't need the ellipse procedure
+	fmt.Fprintf(r.w, " %s %s", path.ReplaceArcs().ToPS(), op)
+}
+
+func (r *epsRenderer) RenderText(text *canvas.Text, m canvas.Matrix) {
+	canvas.RenderTextAsPath(r, text, m)
+}
+
+func (r *epsRenderer) RenderImage(img image.Image, m canvas.Matrix) {
+	switch img := img.(type) {
+	case groupImage:
+		if img.c.Empty() {
+			return
+		}
+		if img.clip == nil {
+			img.c.Render(r)
+			return
+		}
+		// soft masks are clipped to their path, like on a plotter
+		if img.clip.path.Empty() {
+			return
+		}
+		col := r.color
+		fmt.Fprintf(r.w, " gsave %s clip newpath", img.clip.path.ReplaceArcs().ToPS())
+		img.c.Render(r)
+		io.WriteString(r.w, " grestore")
+		r.color = col
+	case paintImage:
+		// with the flat colors standing in for the paints
+		r.RenderPath(img.path, img.style, img.m)
+	default:
+		r.image(img, m)
+	}
+}
+
+// image writes `img`, the Rasters among them. The pixels less than half
+// opaque get a color the others don't use, which an ImageType 4 masks out.
+func (r *epsRenderer) image(img image.Image, m canvas.Matrix) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	rgb := make([]byte, 0, 3*w*h)
+	var clear []int // offsets in rgb
+	used := make([]uint64, 1<<24/64)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			col := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if col.A < 0x80 {
+				clear = append(clear, len(rgb))
+			}
+			i := int(col.R)<<16 | int(col.G)<<8 | int(col.B)
+			used[i/64] |= 1 << uint(i%64)
+			rgb = append(rgb, col.R, col.G, col.B)
+		}
+	}
+	imageType, mask := 1, ""
+	if len(clear) > 0 {
+		key := 0
+		for used[key/64]&(1<<uint(key%64)) != 0 {
+			key++
+		}
+		for _, i := range clear {
+			rgb[i], rgb[i+1], rgb[i+2] = byte(key>>16), byte(key>>8), byte(key)
+		}
+		imageType, mask = 4, fmt.Sprintf(" /MaskColor [%d %d %d]", key>>16, key>>8&0xff, key&0xff)
+	}
+	fmt.Fprintf(r.w, " gsave [%s] concat /DeviceRGB setcolorspace", pdfMatrix(m.Scale(float64(w), float64(h))))
+	fmt.Fprintf(r.w, " << /ImageType %d /Width %d /Height %d /ImageMatrix [%d 0 0 %d 0 %d] /BitsPerComponent 8 /Decode [0 1 0 1 0 1]%s",
+		imageType, w, h, w, -h, h, mask)
+	io.WriteString(r.w, " /DataSource currentfile /ASCII85Decode filter /FlateDecode filter >> image\n")
+	var flate bytes.Buffer
+	zw := zlib.NewWriter(&flate)
+	zw.Write(rgb)
+	zw.Close()
+	data := make([]byte, ascii85.MaxEncodedLen(flate.Len()))
+	data = data[:ascii85.Encode(data, flate.Bytes())]
+	// DSC wants lines under 255 characters
+	for len(data) > 76 {
+		r.w.Write(data[:76])
+		r.w.WriteByte('\n')
+		data = data[76:]
+	}
+	r.w.Write(data)
+	io.WriteString(r.w, "~>\n grestore")
+}
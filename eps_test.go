@@ -0,0 +1,88 @@
+package gart
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"image/color"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+var epsImageRx = regexp.MustCompile(`(?s)/Width (\d+) /Height (\d+) .*?>> image\n(.*?)~>`)
+
+func TestEPSWriter(t *testing.T) {
+	c := canvas.New(20, 10)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(color.NRGBA{255, 0, 0, 128})
+	ctx.SetStrokeColor(color.Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetFillRule(canvas.EvenOdd)
+	ctx.DrawPath(10, 5, canvas.Circle(4))
+
+	var buf bytes.Buffer
+	if err := writeEPS(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	eps := buf.String()
+	for _, want := range []string{
+		"%!PS-Adobe-3.0 EPSF-3.0\n",
+		"%%BoundingBox: 0 0 57 29\n",
+		"2.83465 2.83465 scale",
+		"1 0 0 setrgbcolor", // opaque, there's no alpha
+		" eofill",
+		"0 0 0 setrgbcolor", // the stroke's outline
+		"%%EOF",
+	} {
+		if !strings.Contains(eps, want) {
+			t.Errorf("EPS doesn't contain %q", want)
+		}
+	}
+	if strings.Contains(eps, " stroke") {
+		t.Errorf("EPS strokes, want the outlines filled")
+	}
+}
+
+func TestEPSRaster(t *testing.T) {
+	ctx := NewContext(4, 3)
+	ctx.SetFillColor(color.Black)
+	ctx.FillRect(0, 0, 4, 3)
+	ctx.NewRaster(1).Set(1.5, 1.5, color.NRGBA{255, 0, 0, 255})
+
+	var buf bytes.Buffer
+	if err := EPSWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	eps := buf.String()
+	if !strings.Contains(eps, "/ImageType 4 /Width 4 /Height 3 ") {
+		t.Fatalf("EPS = %s, want the raster keyed out", eps)
+	}
+	m := epsImageRx.FindStringSubmatch(eps)
+	if m == nil {
+		t.Fatalf("EPS = %s, want the image data", eps)
+	}
+	data := make([]byte, len(m[3]))
+	n, _, err := ascii85.Decode(data, []byte(strings.Replace(m[3], "\n", "", -1)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgb, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rgb) != 4*3*3 {
+		t.Fatalf("image has %d bytes, want %d", len(rgb), 4*3*3)
+	}
+	// the pixel is in the middle row, second column
+	if got := rgb[3*(4+1) : 3*(4+1)+3]; !bytes.Equal(got, []byte{255, 0, 0}) {
+		t.Errorf("pixel = %v, want red", got)
+	}
+}
@@ -0,0 +1,173 @@
+package gart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/rasterizer"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+)
+
+// defaultResolution is the dots per mm used by the raster formats,
+// letter size comes out at ~690x894 pixels.
+const defaultResolution = canvas.DPMM(3.2)
+
+// WriterFunc encodes a Drawer into a particular file format.
+type WriterFunc func(w io.Writer, d Drawer) error
+
+// ErrNotVector is returned when writing a vector format from a Drawer that
+// only has pixels, like GGContext.
+var ErrNotVector = errors.New("gart: vector formats need a Context or Recorder")
+
+var (
+	formatsMux sync.RWMutex
+	formats    = map[string]WriterFunc{}
+)
+
+func init() {
+	RegisterFormat(".png", PNGWriter(defaultResolution))
+	RegisterFormat(".jpg", JPEGWriter(defaultResolution, &jpeg.Options{Quality: 90}))
+	RegisterFormat(".jpeg", JPEGWriter(defaultResolution, &jpeg.Options{Quality: 90}))
+	RegisterFormat(".tif", TIFFWriter(defaultResolution, &tiff.Options{Compression: tiff.Deflate}))
+	RegisterFormat(".tiff", TIFFWriter(defaultResolution, &tiff.Options{Compression: tiff.Deflate}))
+	RegisterFormat(".webp", WebPWriter(defaultResolution))
+	RegisterFormat(".svg", SVGWriter)
+	RegisterFormat(".pdf", PDFWriter)
+	RegisterFormat(".eps", EPSWriter)
+	RegisterFormat(".hpgl", HPGLWriter(DefaultPlotterOptions))
+	RegisterFormat(".gcode", GCodeWriter(DefaultPlotterOptions))
+}
+
+// RegisterFormat makes a writer available to SafeWrite for files ending in `ext`
+// (including the leading dot, ex. ".png").
+// Registering an extension again replaces the previous writer, which is how a
+// sketch changes the options of a built in format.
+func RegisterFormat(ext string, fn WriterFunc) {
+	formatsMux.Lock()
+	defer formatsMux.Unlock()
+	formats[strings.ToLower(ext)] = fn
+}
+
+// LookupFormat returns the writer registered for `ext`.
+func LookupFormat(ext string) (WriterFunc, bool) {
+	formatsMux.RLock()
+	defer formatsMux.RUnlock()
+	fn, ok := formats[strings.ToLower(ext)]
+	return fn, ok
+}
+
+// Formats returns the sorted list of registered extensions.
+func Formats() []string {
+	formatsMux.RLock()
+	defer formatsMux.RUnlock()
+	exts := make([]string, 0, len(formats))
+	for ext := range formats {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// ImageWriter adapts an image encoder (ex. png.Encode) into a WriterFunc
+// by rasterizing the context at `resolution` dots per mm.
+// A GGContext is already rasterized so it's written at its own resolution.
+func ImageWriter(resolution canvas.DPMM, encode func(io.Writer, image.Image) error) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		img, err := rasterize(d, resolution)
+		if err != nil {
+			return err
+		}
+		return encode(w, img)
+	}
+}
+
+func rasterize(d Drawer, resolution canvas.DPMM) (image.Image, error) {
+	if g, ok := d.(*GGContext); ok {
+		return g.Image(), nil
+	}
+	ctx, err := toContext(d)
+	if err != nil {
+		return nil, err
+	}
+	res := float64(resolution)
+	img := image.NewRGBA(image.Rect(0, 0, int(ctx.c.W*res+0.5), int(ctx.c.H*res+0.5)))
+	drawCanvas(img, ctx.c, res, true)
+	return img, nil
+}
+
+// drawCanvas rasterizes `c` onto `img` at `res` dots per mm.
+func drawCanvas(img draw.Image, c *canvas.Canvas, res float64, antialias bool) {
+	var r canvas.Renderer = rasterizer.New(img, canvas.DPMM(res))
+	if !antialias {
+		r = &aliased{rasterizer.New(img, canvas.DPMM(res)), img, res}
+	}
+	c.Render(rasterLayers{r, img, res, antialias})
+}
+
+// toContext returns the Context holding what was drawn on `d`,
+// a Recorder is replayed onto a new one.
+func toContext(d Drawer) (*Context, error) {
+	switch d := d.(type) {
+	case *Context:
+		return d, nil
+	case *Recorder:
+		return d.Context()
+	case *GGContext:
+		return nil, ErrNotVector
+	}
+	return nil, fmt.Errorf("gart: can't write a %T", d)
+}
+
+// canvasWriter adapts a writer of the canvas, like writePDF.
+func canvasWriter(cw canvas.Writer) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		ctx, err := toContext(d)
+		if err != nil {
+			return err
+		}
+		return cw(w, ctx.c)
+	}
+}
+
+// PNGWriter writes PNG files at `resolution` dots per mm, see PNGOptions for more.
+func PNGWriter(resolution canvas.DPMM) WriterFunc {
+	return PNGOptions{Resolution: resolution}.Writer()
+}
+
+// JPEGWriter writes JPEG files at `resolution` dots per mm.
+func JPEGWriter(resolution canvas.DPMM, opts *jpeg.Options) WriterFunc {
+	return withProvenance(ImageWriter(resolution, func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, opts)
+	}), embedJPEG)
+}
+
+// TIFFWriter writes TIFF files at `resolution` dots per mm.
+func TIFFWriter(resolution canvas.DPMM, opts *tiff.Options) WriterFunc {
+	return ImageWriter(resolution, func(w io.Writer, img image.Image) error {
+		return tiff.Encode(w, img, opts)
+	})
+}
+
+// WebPWriter writes lossless WebP files at `resolution` dots per mm.
+func WebPWriter(resolution canvas.DPMM) WriterFunc {
+	return ImageWriter(resolution, EncodeWebP)
+}
+
+// SVGWriter writes SVG files, with Inkscape layers if any were set.
+var SVGWriter = LayeredSVGWriter(0)
+
+// PDFWriter writes PDF files. Gradients are shadings and Patterns tiling
+// patterns, other Paints are rasterized, see Paint.
+var PDFWriter = withProvenance(canvasWriter(writePDF), embedPDF)
+
+// EPSWriter writes encapsulated postscript files, with flat colors for the
+// Paints.
+var EPSWriter = withProvenance(canvasWriter(writeEPS), embedEPS)
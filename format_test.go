@@ -0,0 +1,114 @@
+package gart
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeString returns a WriterFunc that writes `s`, so tests can tell which
+// writer was looked up.
+func writeString(s string) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// unregister removes the formats a test registered.
+func unregister(exts ...string) {
+	formatsMux.Lock()
+	defer formatsMux.Unlock()
+	for _, ext := range exts {
+		delete(formats, ext)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	defer unregister(".test", ".upper")
+	tests := []struct {
+		name     string
+		register map[string]string // extension to what its writer writes
+		lookup   string
+		want     string // what the writer found writes, "" for none
+	}{
+		{"new extension", map[string]string{".test": "a"}, ".test", "a"},
+		{"lookup ignores case", map[string]string{".test": "a"}, ".TeSt", "a"},
+		{"register ignores case", map[string]string{".UPPER": "b"}, ".upper", "b"},
+		{"unknown extension", nil, ".nope", ""},
+		{"without the dot", map[string]string{".test": "a"}, "test", ""},
+		{"built in", nil, ".png", "\x89PNG"},
+	}
+	for _, tt := range tests {
+		for ext, s := range tt.register {
+			RegisterFormat(ext, writeString(s))
+		}
+		fn, ok := LookupFormat(tt.lookup)
+		if ok != (tt.want != "") {
+			t.Errorf("%s: LookupFormat(%q) found %v, want %v", tt.name, tt.lookup, ok, tt.want != "")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := fn(&buf, NewContext(1, 1)); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte(tt.want)) {
+			t.Errorf("%s: LookupFormat(%q) wrote %q, want %q", tt.name, tt.lookup, buf.Bytes(), tt.want)
+		}
+	}
+}
+
+func TestRegisterFormatTwice(t *testing.T) {
+	defer unregister(".test")
+	before := len(Formats())
+	RegisterFormat(".test", writeString("first"))
+	RegisterFormat(".TEST", writeString("second"))
+	if got := len(Formats()); got != before+1 {
+		t.Errorf("Formats() has %d more, want 1", got-before)
+	}
+	fn, _ := LookupFormat(".test")
+	var buf bytes.Buffer
+	fn(&buf, nil)
+	if buf.String() != "second" {
+		t.Errorf("LookupFormat found the writer writing %q, want the second", buf.String())
+	}
+}
+
+func TestFormatsSorted(t *testing.T) {
+	exts := Formats()
+	for i := 1; i < len(exts); i++ {
+		if exts[i-1] >= exts[i] {
+			t.Errorf("Formats() = %q, want them sorted", exts)
+			break
+		}
+	}
+}
+
+func TestSafeWriteCustomFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer unregister(".custom")
+	RegisterFormat(".custom", writeString("custom"))
+
+	s, _ := Init("1f3e")
+	prefix := filepath.Join(dir, "test-")
+	if err := s.SafeWrite(NewContext(1, 1), prefix, ".custom"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(s.GetFilename(prefix, ".custom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "custom" {
+		t.Errorf("SafeWrite wrote %q, want the custom writer's output", got)
+	}
+}
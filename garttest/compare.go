@@ -0,0 +1,124 @@
+package garttest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Diff is how much two images differ.
+type Diff struct {
+	SizeMismatch bool
+	Changed      float64      // fraction of pixels with a channel off by more than the delta
+	MaxDelta     uint8        // largest difference of any channel
+	SSIM         float64      // mean structural similarity of the luminance, 1 is identical
+	Image        *image.NRGBA // the wanted image faded with the changed pixels in red, nil if the sizes differ
+}
+
+// Ok is true if the difference is within the tolerances of `opts`.
+func (d Diff) Ok(opts Options) bool {
+	return !d.SizeMismatch && d.Changed <= opts.MaxChanged && d.SSIM >= opts.MinSSIM
+}
+
+func (d Diff) String() string {
+	if d.SizeMismatch {
+		return "the sizes differ"
+	}
+	return fmt.Sprintf("%.3f%% of pixels changed, max delta %d, SSIM %.4f", 100*d.Changed, d.MaxDelta, d.SSIM)
+}
+
+// Compare compares `got` with `want`, pixels where a channel differs by
+// more than `maxDelta` count as changed.
+func Compare(got, want *image.NRGBA, maxDelta uint8) Diff {
+	if got.Rect.Size() != want.Rect.Size() {
+		return Diff{SizeMismatch: true}
+	}
+	w, h := got.Rect.Dx(), got.Rect.Dy()
+	d := Diff{Image: image.NewNRGBA(image.Rect(0, 0, w, h))}
+	changed := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			g := got.Pix[got.PixOffset(x+got.Rect.Min.X, y+got.Rect.Min.Y):]
+			wa := want.Pix[want.PixOffset(x+want.Rect.Min.X, y+want.Rect.Min.Y):]
+			delta := uint8(0)
+			for c := 0; c < 4; c++ {
+				if cd := absDiff(g[c], wa[c]); cd > delta {
+					delta = cd
+				}
+			}
+			if delta > d.MaxDelta {
+				d.MaxDelta = delta
+			}
+			if delta > maxDelta {
+				changed++
+				d.Image.SetNRGBA(x, y, color.NRGBA{255, 0, 0, 255})
+			} else {
+				l := 191 + luma(wa)/4 // faded so the red stands out
+				d.Image.SetNRGBA(x, y, color.NRGBA{l, l, l, 255})
+			}
+		}
+	}
+	if w*h > 0 {
+		d.Changed = float64(changed) / float64(w*h)
+	}
+	d.SSIM = SSIM(got, want)
+	return d
+}
+
+const ssimWindow = 8
+
+// SSIM returns the mean structural similarity of the luminance of two images of the
+// same size, computed over 8x8 windows. It's 1 for identical images and drops
+// towards 0 (or below) as the structure differs, while being forgiving of the
+// small shifts in anti-aliasing that a per pixel comparison flags.
+func SSIM(a, b *image.NRGBA) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	if w != b.Rect.Dx() || h != b.Rect.Dy() {
+		return 0
+	}
+	total, windows := 0.0, 0
+	for y0 := 0; y0 < h; y0 += ssimWindow {
+		for x0 := 0; x0 < w; x0 += ssimWindow {
+			var sa, sb, saa, sbb, sab, n float64
+			for y := y0; y < y0+ssimWindow && y < h; y++ {
+				for x := x0; x < x0+ssimWindow && x < w; x++ {
+					la := float64(luma(a.Pix[a.PixOffset(x+a.Rect.Min.X, y+a.Rect.Min.Y):]))
+					lb := float64(luma(b.Pix[b.PixOffset(x+b.Rect.Min.X, y+b.Rect.Min.Y):]))
+					sa += la
+					sb += lb
+					saa += la * la
+					sbb += lb * lb
+					sab += la * lb
+					n++
+				}
+			}
+			ma, mb := sa/n, sb/n
+			va, vb := saa/n-ma*ma, sbb/n-mb*mb
+			cov := sab/n - ma*mb
+			total += ((2*ma*mb + c1) * (2*cov + c2)) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+			windows++
+		}
+	}
+	if windows == 0 {
+		return 1
+	}
+	return total / float64(windows)
+}
+
+// luma of a non premultiplied pixel, composited over white.
+func luma(p []uint8) uint8 {
+	y := (299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) / 1000
+	a := int(p[3])
+	return uint8((y*a + 255*(255-a)) / 255)
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
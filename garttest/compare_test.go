@@ -0,0 +1,48 @@
+package garttest
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func gradient(w, h int, shift uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / w)
+			img.SetNRGBA(x, y, color.NRGBA{v + shift, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	a := gradient(32, 16, 0)
+	if d := Compare(a, gradient(32, 16, 0), 0); !d.Ok(Options{MinSSIM: 1}) || d.Changed != 0 {
+		t.Errorf("identical images: %v", d)
+	}
+	if d := Compare(a, gradient(32, 16, 3), 2); d.Changed != 1 || d.MaxDelta != 3 || d.Ok(DefaultOptions) {
+		t.Errorf("shifted red: %v, want all changed by 3", d)
+	}
+	if d := Compare(a, gradient(32, 16, 3), 3); d.Changed != 0 {
+		t.Errorf("shifted red within the delta: %v", d)
+	}
+	if d := Compare(a, gradient(16, 16, 0), 0); !d.SizeMismatch || d.Ok(DefaultOptions) {
+		t.Errorf("different sizes: %v", d)
+	}
+
+	b := gradient(32, 16, 0)
+	b.SetNRGBA(4, 4, color.NRGBA{0, 255, 0, 255})
+	d := Compare(a, b, 8)
+	if want := 1.0 / (32 * 16); math.Abs(d.Changed-want) > 1e-9 {
+		t.Errorf("one pixel: changed %f, want %f", d.Changed, want)
+	}
+	if d.SSIM >= 1 {
+		t.Errorf("one pixel: SSIM %f, want < 1", d.SSIM)
+	}
+	if got := d.Image.NRGBAAt(4, 4); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("diff image at the changed pixel = %v, want red", got)
+	}
+}
@@ -0,0 +1,142 @@
+// Package garttest renders sketches and compares them with golden PNGs
+// checked in under testdata, so changes to gart or a sketch can't silently
+// change what it draws.
+//
+//	func TestGolden(t *testing.T) {
+//		g := garttest.Seed(t, "1f3e")
+//		ctx := gart.NewContext(width, height)
+//		draw(ctx, g)
+//		garttest.Golden(t, "horzlines", ctx, garttest.DefaultOptions)
+//	}
+//
+// Run the tests with -update to (re)write the golden files.
+package garttest
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+	"github.com/tdewolff/canvas"
+)
+
+var update = flag.Bool("update", false, "Update the golden files instead of comparing with them")
+
+// Options are how the image is rendered and how different it can be.
+type Options struct {
+	Resolution canvas.DPMM // dots per mm, small is fast and hides anti-aliasing noise
+	MaxDelta   uint8       // largest difference of any channel before a pixel counts as changed
+	MaxChanged float64     // fraction of changed pixels allowed, 0 to 1
+	MinSSIM    float64     // lowest structural similarity allowed, 1 is identical
+}
+
+// DefaultOptions renders at 1 dot per mm and lets a few pixels change a bit.
+var DefaultOptions = Options{
+	Resolution: 1,
+	MaxDelta:   8,
+	MaxChanged: 0.001,
+	MinSSIM:    0.99,
+}
+
+// Seed returns the seed for `hexSeed`, failing the test if it isn't valid.
+func Seed(t testing.TB, hexSeed string) gart.Seed {
+	t.Helper()
+	g, err := gart.Init(hexSeed)
+	if err != nil {
+		t.Fatalf("bad seed %q: %v", hexSeed, err)
+	}
+	return g
+}
+
+// Golden renders `d` and compares it with testdata/<name>.png.
+// On failure the render and a diff image are written next to it as
+// <name>.got.png and <name>.diff.png, which .gitignore should skip.
+func Golden(t testing.TB, name string, d gart.Drawer, opts Options) {
+	t.Helper()
+	got, err := Render(d, opts.Resolution)
+	if err != nil {
+		t.Fatalf("unable to render %s: %v", name, err)
+	}
+	fname := filepath.Join("testdata", name+".png")
+	if *update {
+		if err := writePNG(fname, got); err != nil {
+			t.Fatalf("unable to update %s: %v", fname, err)
+		}
+		t.Logf("updated %s", fname)
+		return
+	}
+	want, err := readPNG(fname)
+	if err != nil {
+		t.Fatalf("unable to read the golden file, run with -update to create it: %v", err)
+	}
+
+	diff := Compare(got, want, opts.MaxDelta)
+	if diff.Ok(opts) {
+		return
+	}
+	t.Errorf("%s differs from %s: %v", name, fname, diff)
+	base := filepath.Join("testdata", name)
+	if err := writePNG(base+".got.png", got); err != nil {
+		t.Logf("unable to save the render: %v", err)
+	}
+	if diff.Image != nil {
+		if err := writePNG(base+".diff.png", diff.Image); err != nil {
+			t.Logf("unable to save the diff: %v", err)
+		}
+	}
+	t.Logf("see %s.got.png and %s.diff.png, run with -update if the change is expected", base, base)
+}
+
+// Render rasterizes `d` at `resolution` dots per mm.
+func Render(d gart.Drawer, resolution canvas.DPMM) (*image.NRGBA, error) {
+	var buf bytes.Buffer
+	if err := gart.PNGWriter(resolution)(&buf, d); err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA(img), nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba
+	}
+	b := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+	return nrgba
+}
+
+func readPNG(fname string) (*image.NRGBA, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA(img), nil
+}
+
+func writePNG(fname string, img image.Image) error {
+	if err := gart.MaybeCreateDir(filepath.Dir(fname)); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, buf.Bytes(), 0644)
+}
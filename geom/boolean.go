@@ -0,0 +1,249 @@
+package geom
+
+import (
+	"math"
+	"sort"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// The boolean ops take sets of polygons each filled with the nonzero rule,
+// so the results of one can go straight into the next, holes and all.
+
+// Union returns the area inside `a` or `b`.
+func Union(a, b []Polygon) []Polygon {
+	return boolean(a, b, func(inA, inB bool) bool { return inA || inB })
+}
+
+// Intersection returns the area inside both `a` and `b`.
+func Intersection(a, b []Polygon) []Polygon {
+	return boolean(a, b, func(inA, inB bool) bool { return inA && inB })
+}
+
+// Difference returns the area inside `a` but not `b`.
+func Difference(a, b []Polygon) []Polygon {
+	return boolean(a, b, func(inA, inB bool) bool { return inA && !inB })
+}
+
+// Union returns the area inside either polygon.
+func (p Polygon) Union(o Polygon) []Polygon {
+	return Union([]Polygon{p}, []Polygon{o})
+}
+
+// Intersection returns the area inside both polygons.
+func (p Polygon) Intersection(o Polygon) []Polygon {
+	return Intersection([]Polygon{p}, []Polygon{o})
+}
+
+// Difference returns the area inside p but not o.
+func (p Polygon) Difference(o Polygon) []Polygon {
+	return Difference([]Polygon{p}, []Polygon{o})
+}
+
+// side is how far to either side of an edge is looked at to see what's there
+const side = 1e-6
+
+// boolean cuts all the edges where they cross, keeps the pieces with the
+// area `keep` wants on one side and not the other, and joins them up into
+// polygons with that area on their left.
+func boolean(a, b []Polygon, keep func(inA, inB bool) bool) []Polygon {
+	ea, eb := edgesOf(a), edgesOf(b)
+	inA, inB := newBands(ea), newBands(eb)
+	seen := map[gart.Segment]bool{}
+	var kept []gart.Segment
+	for _, s := range split(append(append([]gart.Segment(nil), ea...), eb...)) {
+		if s.B.X < s.A.X || s.B.X == s.A.X && s.B.Y < s.A.Y {
+			s = gart.Segment{A: s.B, B: s.A}
+		}
+		if seen[s] { // shared by the polygons
+			continue
+		}
+		seen[s] = true
+		d := s.B.Sub(s.A).Normalize()
+		n := gart.Vec2{X: -d.Y, Y: d.X}.Scale(side)
+		left, right := s.At(0.5).Add(n), s.At(0.5).Sub(n)
+		l := keep(inA.winding(left) != 0, inB.winding(left) != 0)
+		r := keep(inA.winding(right) != 0, inB.winding(right) != 0)
+		if l == r {
+			continue
+		}
+		if r {
+			s = gart.Segment{A: s.B, B: s.A}
+		}
+		kept = append(kept, s)
+	}
+	return link(kept)
+}
+
+// snap rounds to a grid of gart.Epsilon, so the same point worked out twice
+// is usually the same exactly.
+func snap(v gart.Vec2) gart.Vec2 {
+	return gart.Vec2{X: math.Round(v.X/gart.Epsilon) * gart.Epsilon, Y: math.Round(v.Y/gart.Epsilon) * gart.Epsilon}
+}
+
+func edgesOf(polys []Polygon) []gart.Segment {
+	var edges []gart.Segment
+	for _, p := range polys {
+		for _, e := range p.Edges() {
+			e = gart.Segment{A: snap(e.A), B: snap(e.B)}
+			if e.A != e.B {
+				edges = append(edges, e)
+			}
+		}
+	}
+	return edges
+}
+
+// split returns the edges cut into pieces wherever they cross or touch another.
+func split(edges []gart.Segment) []gart.Segment {
+	cuts := make([][]gart.Vec2, len(edges))
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return math.Min(edges[order[i]].A.X, edges[order[i]].B.X) < math.Min(edges[order[j]].A.X, edges[order[j]].B.X)
+	})
+	for k, i := range order {
+		e := edges[i]
+		maxX := math.Max(e.A.X, e.B.X) + gart.Epsilon
+		minY, maxY := math.Min(e.A.Y, e.B.Y)-gart.Epsilon, math.Max(e.A.Y, e.B.Y)+gart.Epsilon
+		for _, j := range order[k+1:] {
+			f := edges[j]
+			if math.Min(f.A.X, f.B.X) > maxX {
+				break
+			}
+			if math.Max(f.A.Y, f.B.Y) < minY || math.Min(f.A.Y, f.B.Y) > maxY {
+				continue
+			}
+			cuts[i], cuts[j] = crossings(e, f, cuts[i], cuts[j])
+		}
+	}
+
+	var pieces []gart.Segment
+	for i, e := range edges {
+		pts, d := cuts[i], e.B.Sub(e.A)
+		sort.Slice(pts, func(a, b int) bool { return pts[a].Sub(e.A).Dot(d) < pts[b].Sub(e.A).Dot(d) })
+		prev := e.A
+		for _, p := range append(pts, e.B) {
+			if p != prev {
+				pieces = append(pieces, gart.Segment{A: prev, B: p})
+				prev = p
+			}
+		}
+	}
+	return pieces
+}
+
+// crossings adds where e and f cross or touch to the cuts of each.
+func crossings(e, f gart.Segment, ce, cf []gart.Vec2) ([]gart.Vec2, []gart.Vec2) {
+	onto := func(p gart.Vec2, s gart.Segment, cuts []gart.Vec2) []gart.Vec2 {
+		if p.Dist(s.A) > gart.Epsilon && p.Dist(s.B) > gart.Epsilon && s.Distance(p) < gart.Epsilon {
+			cuts = append(cuts, p)
+		}
+		return cuts
+	}
+	ce = onto(f.A, e, onto(f.B, e, ce))
+	cf = onto(e.A, f, onto(e.B, f, cf))
+
+	r, q := e.B.Sub(e.A), f.B.Sub(f.A)
+	rl, ql := r.Len(), q.Len()
+	denom := r.Cross(q)
+	if math.Abs(denom) <= gart.Epsilon*rl*ql { // parallel, only the ends can touch
+		return ce, cf
+	}
+	ao := f.A.Sub(e.A)
+	t, u := ao.Cross(q)/denom, ao.Cross(r)/denom
+	if t*rl <= gart.Epsilon || (1-t)*rl <= gart.Epsilon || u*ql <= gart.Epsilon || (1-u)*ql <= gart.Epsilon {
+		return ce, cf // at an end or not at all
+	}
+	x := snap(e.At(t))
+	return append(ce, x), append(cf, x)
+}
+
+// link joins the pieces end to end into polygons. Where there's a choice it
+// turns left the most, which keeps polygons that touch at a corner apart.
+func link(pieces []gart.Segment) []Polygon {
+	from := map[gart.Vec2][]int{}
+	for i, s := range pieces {
+		from[s.A] = append(from[s.A], i)
+	}
+	used := make([]bool, len(pieces))
+	var polys []Polygon
+	for i, s := range pieces {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		ring := Polygon{s.A}
+		for s.B != ring[0] {
+			next, best := -1, math.Inf(-1)
+			for _, j := range from[s.B] {
+				if turn := s.B.Sub(s.A).AngleTo(pieces[j].B.Sub(pieces[j].A)); !used[j] && turn > best {
+					next, best = j, turn
+				}
+			}
+			ring = append(ring, s.B)
+			if next < 0 {
+				break // a rounding error left a gap, close it
+			}
+			used[next] = true
+			s = pieces[next]
+		}
+		if ring = tidy(ring); len(ring) >= 3 && ring.Area() > gart.Epsilon*gart.Epsilon {
+			polys = append(polys, ring)
+		}
+	}
+	return polys
+}
+
+// tidy removes the points in line with the ones either side of them.
+func tidy(p Polygon) Polygon {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(p) && len(p) >= 3; {
+			prev, next := p[(i+len(p)-1)%len(p)], p[(i+1)%len(p)]
+			if (gart.Segment{A: prev, B: next}).Distance(p[i]) < gart.Epsilon {
+				p = append(p[:i], p[i+1:]...)
+				changed = true
+				continue
+			}
+			i++
+		}
+	}
+	return p
+}
+
+// bands are the edges of a set of polygons bucketed by height, for winding
+// numbers that only look at the edges level with the point.
+type bands struct {
+	minY, height float64
+	edges        [][]gart.Segment
+}
+
+func newBands(edges []gart.Segment) *bands {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, e := range edges {
+		minY, maxY = math.Min(minY, math.Min(e.A.Y, e.B.Y)), math.Max(maxY, math.Max(e.A.Y, e.B.Y))
+	}
+	n := int(math.Sqrt(float64(len(edges)))) + 1
+	b := &bands{minY: minY, height: (maxY - minY) / float64(n), edges: make([][]gart.Segment, n)}
+	for _, e := range edges {
+		lo, hi := b.band(math.Min(e.A.Y, e.B.Y)), b.band(math.Max(e.A.Y, e.B.Y))
+		for i := lo; i <= hi; i++ {
+			b.edges[i] = append(b.edges[i], e)
+		}
+	}
+	return b
+}
+
+func (b *bands) band(y float64) int {
+	if !(b.height > 0) {
+		return 0
+	}
+	return gart.ClampInt(int((y-b.minY)/b.height), 0, len(b.edges)-1)
+}
+
+func (b *bands) winding(pt gart.Vec2) int {
+	return winding(b.edges[b.band(pt.Y)], pt)
+}
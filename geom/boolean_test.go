@@ -0,0 +1,89 @@
+package geom
+
+import (
+	"math"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// area returns the area of polygons filled with the nonzero rule whose
+// holes go the other way.
+func area(polys []Polygon) float64 {
+	a := 0.0
+	for _, p := range polys {
+		a += p.SignedArea()
+	}
+	return a
+}
+
+func TestBoolean(t *testing.T) {
+	a, b := []Polygon{square(0, 0, 2)}, []Polygon{square(1, 1, 2)}
+	holed := Difference([]Polygon{square(0, 0, 4)}, []Polygon{square(1, 1, 2)})
+	star := []Polygon{{{X: 0, Y: 0}, {X: 2, Y: 6}, {X: 4, Y: 0}, {X: -1, Y: 4}, {X: 5, Y: 4}}}
+	tests := []struct {
+		name    string
+		got     []Polygon
+		polys   int
+		area    float64
+		in, out []gart.Vec2
+	}{
+		{"union", Union(a, b), 1, 7, []gart.Vec2{{X: 0.5, Y: 0.5}, {X: 2.5, Y: 2.5}}, []gart.Vec2{{X: 2.5, Y: 0.5}}},
+		{"intersection", Intersection(a, b), 1, 1, []gart.Vec2{{X: 1.5, Y: 1.5}}, []gart.Vec2{{X: 0.5, Y: 0.5}}},
+		{"difference", Difference(a, b), 1, 3, []gart.Vec2{{X: 0.5, Y: 0.5}}, []gart.Vec2{{X: 1.5, Y: 1.5}}},
+		{"clockwise", Union([]Polygon{a[0].Reverse()}, nil), 1, 4, []gart.Vec2{{X: 1, Y: 1}}, nil},
+		{"apart", Union(a, []Polygon{square(5, 0, 1)}), 2, 5, nil, []gart.Vec2{{X: 3, Y: 0.5}}},
+		{"apart intersection", Intersection(a, []Polygon{square(5, 0, 1)}), 0, 0, nil, nil},
+		{"side by side", Union(a, []Polygon{square(2, 0, 2)}), 1, 8, []gart.Vec2{{X: 2, Y: 1}}, nil},
+		{"corner to corner", Union(a, []Polygon{square(2, 2, 2)}), 2, 8, nil, []gart.Vec2{{X: 1, Y: 3}}},
+		{"same", Union(a, a), 1, 4, nil, nil},
+		{"same difference", Difference(a, a), 0, 0, nil, nil},
+		{"hole", holed, 2, 12, []gart.Vec2{{X: 0.5, Y: 0.5}}, []gart.Vec2{{X: 2, Y: 2}}},
+		{"island", Union(holed, []Polygon{square(1.5, 1.5, 1)}), 3, 13, []gart.Vec2{{X: 2, Y: 2}}, []gart.Vec2{{X: 1.2, Y: 1.2}}},
+		{"star", Union(star, nil), 1, 0, []gart.Vec2{{X: 2, Y: 3}, {X: 2, Y: 5}}, []gart.Vec2{{X: 0, Y: 2}}},
+	}
+	for _, tt := range tests {
+		if len(tt.got) != tt.polys {
+			t.Errorf("%s: %d polygons, want %d: %v", tt.name, len(tt.got), tt.polys, tt.got)
+		}
+		if got := area(tt.got); tt.area > 0 && math.Abs(got-tt.area) > 1e-9 {
+			t.Errorf("%s: area %v, want %v", tt.name, got, tt.area)
+		}
+		for _, p := range tt.in {
+			if !Contains(tt.got, p) {
+				t.Errorf("%s: %v isn't in %v", tt.name, p, tt.got)
+			}
+		}
+		for _, p := range tt.out {
+			if Contains(tt.got, p) {
+				t.Errorf("%s: %v is in %v", tt.name, p, tt.got)
+			}
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	// two squares joined by a thin bar
+	dumbbell := Union(Union([]Polygon{square(0, 0, 4)}, []Polygon{square(6, 0, 4)}),
+		[]Polygon{{{X: 3, Y: 1.75}, {X: 7, Y: 1.75}, {X: 7, Y: 2.25}, {X: 3, Y: 2.25}}})
+	tests := []struct {
+		name  string
+		got   []Polygon
+		polys int
+		area  float64
+	}{
+		{"grown", square(0, 0, 2).Offset(1), 1, 4 + 8 + math.Pi},
+		{"shrunk", square(0, 0, 2).Offset(-0.5), 1, 1},
+		{"gone", square(0, 0, 2).Offset(-1.5), 0, 0},
+		{"split", Offset(dumbbell, -0.5), 2, 18}, // a bit more where the bar was
+		{"joined", Offset([]Polygon{square(0, 0, 1), square(2.5, 0, 1)}, 1), 1, 0},
+	}
+	for _, tt := range tests {
+		if len(tt.got) != tt.polys {
+			t.Errorf("%s: %d polygons, want %d: %v", tt.name, len(tt.got), tt.polys, tt.got)
+		}
+		if got := area(tt.got); tt.area > 0 && math.Abs(got-tt.area) > 0.05 {
+			t.Errorf("%s: area %v, want %v", tt.name, got, tt.area)
+		}
+	}
+}
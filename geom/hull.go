@@ -0,0 +1,100 @@
+package geom
+
+import (
+	"sort"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// ConvexHull returns the smallest convex polygon around the points, counter
+// clockwise and without points in the middle of its sides.
+func ConvexHull(points ...gart.Vec2) Polygon {
+	pts := append([]gart.Vec2(nil), points...)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].X != pts[j].X {
+			return pts[i].X < pts[j].X
+		}
+		return pts[i].Y < pts[j].Y
+	})
+	if len(pts) < 3 {
+		return Polygon(pts)
+	}
+	// Andrew's monotone chain, the lower half then the upper
+	hull := make(Polygon, 0, 2*len(pts))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range pts {
+			for len(hull) >= start+2 && hull[len(hull)-1].Sub(hull[len(hull)-2]).Cross(p.Sub(hull[len(hull)-2])) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1] // it starts the other half
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return hull
+}
+
+// Hull returns the convex hull of the polygon.
+func (p Polygon) Hull() Polygon {
+	return ConvexHull(p...)
+}
+
+// Simplify returns the polyline through `points` with as few of them as
+// Ramer-Douglas-Peucker can keep while staying within `tolerance` of it.
+// The ends are kept.
+func Simplify(points []gart.Vec2, tolerance float64) []gart.Vec2 {
+	if len(points) < 3 {
+		return append([]gart.Vec2(nil), points...)
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	rdp(points, keep, 0, len(points)-1, tolerance)
+	var out []gart.Vec2
+	for i, k := range keep {
+		if k {
+			out = append(out, points[i])
+		}
+	}
+	return out
+}
+
+func rdp(points []gart.Vec2, keep []bool, first, last int, tolerance float64) {
+	side := gart.Segment{A: points[first], B: points[last]}
+	far, dist := -1, tolerance
+	for i := first + 1; i < last; i++ {
+		if d := side.Distance(points[i]); d > dist {
+			far, dist = i, d
+		}
+	}
+	if far < 0 {
+		return
+	}
+	keep[far] = true
+	rdp(points, keep, first, far, tolerance)
+	rdp(points, keep, far, last, tolerance)
+}
+
+// Simplify returns the polygon simplified like the polyline, split at its
+// first point and the point farthest from it. It can end up with fewer than 3
+// points when it's smaller than `tolerance`.
+func (p Polygon) Simplify(tolerance float64) Polygon {
+	if len(p) < 4 {
+		return append(Polygon(nil), p...)
+	}
+	far, dist := 0, 0.0
+	for i, v := range p {
+		if d := v.Dist(p[0]); d > dist {
+			far, dist = i, d
+		}
+	}
+	if far == 0 {
+		return Polygon{p[0]}
+	}
+	ring := append(append([]gart.Vec2(nil), p...), p[0])
+	a := Simplify(ring[:far+1], tolerance)
+	b := Simplify(ring[far:], tolerance)
+	return Polygon(append(a[:len(a)-1], b[:len(b)-1]...))
+}
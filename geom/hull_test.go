@@ -0,0 +1,66 @@
+package geom
+
+import (
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+)
+
+func TestConvexHull(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []gart.Vec2
+		want   Polygon
+	}{
+		{"square with insides", []gart.Vec2{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 0, Y: 2}, {X: 2, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}},
+			Polygon{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}}},
+		{"repeats", []gart.Vec2{{X: 0, Y: 0}, {X: 1, Y: 3}, {X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 0}},
+			Polygon{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 3}}},
+		{"in line", []gart.Vec2{{X: 2, Y: 2}, {X: 0, Y: 0}, {X: 1, Y: 1}}, Polygon{{X: 0, Y: 0}, {X: 2, Y: 2}}},
+		{"two", []gart.Vec2{{X: 1, Y: 0}, {X: 0, Y: 0}}, Polygon{{X: 0, Y: 0}, {X: 1, Y: 0}}},
+		{"none", nil, Polygon{}},
+	}
+	for _, tt := range tests {
+		got := ConvexHull(tt.points...)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: ConvexHull = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: ConvexHull = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	wobbly := []gart.Vec2{{X: 0, Y: 0}, {X: 1, Y: 0.05}, {X: 2, Y: -0.05}, {X: 3, Y: 0}, {X: 3.05, Y: 1}, {X: 3, Y: 2}}
+	tests := []struct {
+		tolerance float64
+		want      []gart.Vec2
+	}{
+		{0.1, []gart.Vec2{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}}},
+		{0.01, wobbly},
+		{10, []gart.Vec2{{X: 0, Y: 0}, {X: 3, Y: 2}}},
+	}
+	for _, tt := range tests {
+		got := Simplify(wobbly, tt.tolerance)
+		if len(got) != len(tt.want) {
+			t.Errorf("Simplify(%v) = %v, want %v", tt.tolerance, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Simplify(%v) = %v, want %v", tt.tolerance, got, tt.want)
+				break
+			}
+		}
+	}
+
+	ring := Polygon{{X: 0, Y: 0}, {X: 1, Y: 0.01}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 1, Y: 2.01}, {X: 0, Y: 2}}
+	if got := ring.Simplify(0.1); len(got) != 4 || got.Area() != 4 {
+		t.Errorf("Simplify = %v, want the square", got)
+	}
+}
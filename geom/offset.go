@@ -0,0 +1,56 @@
+package geom
+
+import (
+	"math"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// arcTolerance is how far the rounded corners of offsets can be from round, in mm
+const arcTolerance = 0.01
+
+// Offset returns the polygon grown by `d` all round, or shrunk for a negative
+// `d`, which can split it up or make it vanish. The corners it goes around
+// are rounded.
+func (p Polygon) Offset(d float64) []Polygon {
+	return Offset([]Polygon{p}, d)
+}
+
+// Offset returns the polygons, filled with the nonzero rule, grown or shrunk
+// by `d` like Polygon.Offset.
+func Offset(polys []Polygon, d float64) []Polygon {
+	r := math.Abs(d)
+	if r < gart.Epsilon {
+		return Union(polys, nil)
+	}
+	// everything within r of an edge
+	var band []Polygon
+	for _, p := range polys {
+		for _, e := range p.Edges() {
+			n := e.B.Sub(e.A).Normalize()
+			if n == (gart.Vec2{}) {
+				continue
+			}
+			n = gart.Vec2{X: -n.Y, Y: n.X}.Scale(r)
+			band = append(band, Polygon{e.A.Sub(n), e.B.Sub(n), e.B.Add(n), e.A.Add(n)}, circle(e.A, r))
+		}
+	}
+	if d > 0 {
+		return Union(polys, band)
+	}
+	return Difference(polys, band)
+}
+
+// circle returns a counter clockwise polygon within arcTolerance of the circle.
+func circle(c gart.Vec2, r float64) Polygon {
+	n := 8
+	if r > arcTolerance {
+		n = int(math.Max(8, math.Ceil(math.Pi/math.Acos(1-arcTolerance/r))))
+	}
+	p := make(Polygon, n)
+	for i := range p {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		p[i] = gart.Vec2{X: c.X + r*cos, Y: c.Y + r*sin}
+	}
+	return p
+}
@@ -0,0 +1,163 @@
+// Package geom has the polygon geometry sketches keep needing: areas,
+// containment, hulls, simplifying, offsetting and boolean ops.
+//
+// Polygons are in the same float coordinates as gart.Vec2, and can be added
+// to a Drawer's path to fill or clip with, or read back from one.
+//
+//	blob := geom.Polygon{{0, 0}, {10, 0}, {5, 8}}.Offset(2)
+//	for _, p := range blob {
+//		p.AddTo(ctx)
+//	}
+//	ctx.Fill()
+package geom
+
+import (
+	"math"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// Polygon is a closed ring of points, the last one joins back to the first.
+// The ops returning several polygons wind the outlines counter clockwise and
+// the holes in them clockwise, so they fill right with either fill rule.
+type Polygon []gart.Vec2
+
+// SignedArea returns the area, positive when the points go counter clockwise.
+func (p Polygon) SignedArea() float64 {
+	a := 0.0
+	for i, v := range p {
+		a += v.Cross(p[(i+1)%len(p)])
+	}
+	return a / 2
+}
+
+// Area returns the area however the points go.
+func (p Polygon) Area() float64 {
+	return math.Abs(p.SignedArea())
+}
+
+// CCW is true when the points go counter clockwise.
+func (p Polygon) CCW() bool {
+	return p.SignedArea() > 0
+}
+
+// Reverse returns the polygon with the points going the other way.
+func (p Polygon) Reverse() Polygon {
+	r := make(Polygon, len(p))
+	for i, v := range p {
+		r[len(p)-1-i] = v
+	}
+	return r
+}
+
+// Centroid returns the center of mass of the area, or the mean of the
+// points when it has none.
+func (p Polygon) Centroid() gart.Vec2 {
+	var c gart.Vec2
+	a := p.SignedArea()
+	if math.Abs(a) < gart.Epsilon*gart.Epsilon {
+		for _, v := range p {
+			c = c.Add(v)
+		}
+		return c.Scale(1 / math.Max(1, float64(len(p))))
+	}
+	for i, v := range p {
+		w := p[(i+1)%len(p)]
+		c = c.Add(v.Add(w).Scale(v.Cross(w)))
+	}
+	return c.Scale(1 / (6 * a))
+}
+
+// Bounds returns the corners of the box around the points.
+func (p Polygon) Bounds() (min, max gart.Vec2) {
+	min = gart.Vec2{X: math.Inf(1), Y: math.Inf(1)}
+	max = gart.Vec2{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, v := range p {
+		min = gart.Vec2{X: math.Min(min.X, v.X), Y: math.Min(min.Y, v.Y)}
+		max = gart.Vec2{X: math.Max(max.X, v.X), Y: math.Max(max.Y, v.Y)}
+	}
+	return min, max
+}
+
+// Edges returns the sides, from each point to the next.
+func (p Polygon) Edges() []gart.Segment {
+	edges := make([]gart.Segment, len(p))
+	for i, v := range p {
+		edges[i] = gart.Segment{A: v, B: p[(i+1)%len(p)]}
+	}
+	return edges
+}
+
+// Winding returns how many times the polygon goes counter clockwise around
+// `pt`, negative for clockwise.
+func (p Polygon) Winding(pt gart.Vec2) int {
+	return winding(p.Edges(), pt)
+}
+
+// Contains is true when `pt` is inside the polygon with the nonzero fill
+// rule, or less than gart.Epsilon from its edges.
+func (p Polygon) Contains(pt gart.Vec2) bool {
+	return Contains([]Polygon{p}, pt)
+}
+
+// Contains is true when `pt` is inside the polygons filled together with the
+// nonzero rule, like the results of the boolean ops, or on their edges.
+func Contains(polys []Polygon, pt gart.Vec2) bool {
+	var edges []gart.Segment
+	for _, p := range polys {
+		edges = append(edges, p.Edges()...)
+	}
+	for _, e := range edges {
+		if e.Distance(pt) < gart.Epsilon {
+			return true
+		}
+	}
+	return winding(edges, pt) != 0
+}
+
+// winding returns the winding number of the edges around `pt`
+func winding(edges []gart.Segment, pt gart.Vec2) int {
+	w := 0
+	for _, e := range edges {
+		side := e.B.Sub(e.A).Cross(pt.Sub(e.A))
+		if e.A.Y <= pt.Y {
+			if e.B.Y > pt.Y && side > 0 {
+				w++
+			}
+		} else if e.B.Y <= pt.Y && side < 0 {
+			w--
+		}
+	}
+	return w
+}
+
+// Pather is a Drawer whose current path can be read back, like gart.Context
+// and gart.GGContext.
+type Pather interface {
+	Polylines(tolerance float64) [][]gart.Vec2
+}
+
+// FromPath returns the subpaths of the current path of `d` as polygons, with
+// the curves flattened to within `tolerance`. Open subpaths are closed.
+func FromPath(d Pather, tolerance float64) []Polygon {
+	var polys []Polygon
+	for _, line := range d.Polylines(tolerance) {
+		if n := len(line); n > 1 && line[0] == line[n-1] {
+			line = line[:n-1]
+		}
+		if len(line) > 2 {
+			polys = append(polys, Polygon(line))
+		}
+	}
+	return polys
+}
+
+// AddTo adds the polygon to the current path of `d` as a closed subpath,
+// to Fill, Stroke or Clip with.
+func (p Polygon) AddTo(d gart.Drawer) {
+	xy := make([]float64, 0, 2*len(p))
+	for _, v := range p {
+		xy = append(xy, v.X, v.Y)
+	}
+	d.Polygon(xy...)
+}
@@ -0,0 +1,106 @@
+package geom
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// square returns the CCW square with its lower left corner at x,y
+func square(x, y, size float64) Polygon {
+	return Polygon{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}}
+}
+
+func TestPolygon(t *testing.T) {
+	ell := Polygon{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 4}, {X: 0, Y: 4}}
+	tests := []struct {
+		name     string
+		p        Polygon
+		area     float64
+		centroid gart.Vec2
+	}{
+		{"square", square(1, 1, 2), 4, gart.Vec2{X: 2, Y: 2}},
+		{"clockwise", square(1, 1, 2).Reverse(), -4, gart.Vec2{X: 2, Y: 2}},
+		{"ell", ell, 7, gart.Vec2{X: 9.5 / 7, Y: 9.5 / 7}},
+		{"triangle", Polygon{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 0, Y: 3}}, 4.5, gart.Vec2{X: 1, Y: 1}},
+		{"flat", Polygon{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 4, Y: 0}}, 0, gart.Vec2{X: 2, Y: 0}},
+	}
+	for _, tt := range tests {
+		if got := tt.p.SignedArea(); math.Abs(got-tt.area) > 1e-12 {
+			t.Errorf("%s: SignedArea = %v, want %v", tt.name, got, tt.area)
+		}
+		if got := tt.p.CCW(); got != (tt.area > 0) {
+			t.Errorf("%s: CCW = %v", tt.name, got)
+		}
+		if got := tt.p.Centroid(); !got.Equals(tt.centroid) {
+			t.Errorf("%s: Centroid = %v, want %v", tt.name, got, tt.centroid)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	const onEdge = 99 // which side is up to rounding
+	// a star drawn in one stroke winds twice around its middle
+	star := Polygon{{X: 0, Y: 0}, {X: 2, Y: 6}, {X: 4, Y: 0}, {X: -1, Y: 4}, {X: 5, Y: 4}}
+	tests := []struct {
+		p        Polygon
+		pt       gart.Vec2
+		winding  int
+		contains bool
+	}{
+		{square(0, 0, 2), gart.Vec2{X: 1, Y: 1}, 1, true},
+		{square(0, 0, 2).Reverse(), gart.Vec2{X: 1, Y: 1}, -1, true},
+		{square(0, 0, 2), gart.Vec2{X: 3, Y: 1}, 0, false},
+		{square(0, 0, 2), gart.Vec2{X: 2, Y: 1}, onEdge, true},
+		{square(0, 0, 2), gart.Vec2{X: 2, Y: 2}, onEdge, true},
+		{star, gart.Vec2{X: 2, Y: 3}, -2, true},
+		{star, gart.Vec2{X: 2, Y: 5}, -1, true},
+		{star, gart.Vec2{X: 0, Y: 2}, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Winding(tt.pt); got != tt.winding && tt.winding != onEdge {
+			t.Errorf("%v.Winding(%v) = %d, want %d", tt.p, tt.pt, got, tt.winding)
+		}
+		if got := tt.p.Contains(tt.pt); got != tt.contains {
+			t.Errorf("%v.Contains(%v) = %v, want %v", tt.p, tt.pt, got, tt.contains)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	ctx := gart.NewContext(20, 20)
+	square(1, 1, 5).AddTo(ctx)
+	ctx.Circle(12, 12, 4)
+	polys := FromPath(ctx, 0.01)
+	if len(polys) != 2 {
+		t.Fatalf("FromPath = %d polygons, want 2", len(polys))
+	}
+	if got := polys[0].Area(); math.Abs(got-25) > 1e-9 {
+		t.Errorf("square area = %v, want 25", got)
+	}
+	if got := polys[1].Area(); math.Abs(got-math.Pi*16) > 0.5 {
+		t.Errorf("circle area = %v, want %v", got, math.Pi*16)
+	}
+
+	// clip to them
+	for _, p := range polys {
+		p.AddTo(ctx)
+	}
+	ctx.Clip()
+	ctx.SetFillColor(color.Black)
+	ctx.FillRect(0, 0, 20, 20)
+	img, err := gart.PNGOptions{Resolution: 1}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		x, y  int
+		drawn bool
+	}{{3, 16, true}, {12, 8, true}, {16, 3, false}, {3, 3, false}} {
+		if _, _, _, a := img.At(tt.x, tt.y).RGBA(); (a > 0x8000) != tt.drawn {
+			t.Errorf("pixel %d,%d has alpha %x, want drawn %v", tt.x, tt.y, a, tt.drawn)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package gart
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/tdewolff/canvas"
+)
+
+// GGContext is the Drawer backed by fogleman/gg. Each path is rasterized as
+// soon as it's stroked or filled, so nothing is kept around, which makes it
+// lighter and usually faster than Context for sketches with millions of strokes.
+// The resolution is fixed when it's created and it can only be written to
+// raster formats.
+type GGContext struct {
+	pathBuilder   // current path, rendered by Stroke, Fill, etc.
+	dc            *gg.Context
+	width, height float64 // mm
+	resolution    canvas.DPMM
+	unit          float64 // mm per unit, see NewPage
+	style         ggStyle
+	stack         []ggStyle
+	provenance    *Provenance
+}
+
+// ggStyle is the draw state saved by Push
+type ggStyle struct {
+	fill, stroke color.Color
+	strokeWidth  float64 // mm
+	cap          LineCap
+	join         LineJoin
+	miterLimit   float64   // 0 for the default
+	dashOffset   float64   // mm
+	dashes       []float64 // mm
+	view         Matrix
+	clip         *image.Alpha // nil when not clipped
+}
+
+// NewGGContext returns a Drawer of width x height mm rasterized at `resolution` dots per mm.
+func NewGGContext(width, height float64, resolution canvas.DPMM) *GGContext {
+	g := &GGContext{
+		pathBuilder: pathBuilder{path: &canvas.Path{}},
+		width:       width,
+		height:      height,
+		resolution:  resolution,
+		unit:        1,
+		style: ggStyle{
+			fill:        canvas.DefaultStyle.FillColor,
+			stroke:      canvas.DefaultStyle.StrokeColor,
+			strokeWidth: canvas.DefaultStyle.StrokeWidth,
+			view:        Identity,
+		},
+	}
+	g.Reset()
+	return g
+}
+
+// Size returns the width and height in mm
+func (g *GGContext) Size() (float64, float64) {
+	return g.width, g.height
+}
+
+// Image returns what has been drawn so far.
+func (g *GGContext) Image() image.Image {
+	return g.dc.Image()
+}
+
+// SetProvenance sets the record that writers embed in the files they write.
+func (g *GGContext) SetProvenance(p *Provenance) {
+	g.provenance = p
+}
+
+// Provenance returns the record to embed, or nil if there is none.
+func (g *GGContext) Provenance() *Provenance {
+	return g.provenance
+}
+
+// WritePNG writes to a PNG file
+func (g *GGContext) WritePNG(fname string) error {
+	return g.WriteFile(fname, PNGWriter(g.resolution))
+}
+
+// WriteFile writes to fname using the writer `fn`
+func (g *GGContext) WriteFile(fname string, fn WriterFunc) error {
+	return writeFile(fname, g, fn)
+}
+
+// Push saves the current draw state (colors, stroke style, transformation and clip) so it can be
+// restored by Pop.
+func (g *GGContext) Push() {
+	g.stack = append(g.stack, g.style)
+}
+
+// Pop restores the last pushed draw state and uses that as the current draw state. If there are no
+// states on the stack, this will do nothing.
+func (g *GGContext) Pop() {
+	if len(g.stack) == 0 {
+		return
+	}
+	g.style = g.stack[len(g.stack)-1]
+	g.stack = g.stack[:len(g.stack)-1]
+	g.setMask()
+}
+
+// Reset empties the image.
+func (g *GGContext) Reset() {
+	w := int(g.width*float64(g.resolution) + 0.5)
+	h := int(g.height*float64(g.resolution) + 0.5)
+	g.dc = gg.NewContext(w, h)
+	g.dc.SetLineCapButt()
+	g.setMask()
+}
+
+func (g *GGContext) SetFillColor(col color.Color) {
+	g.style.fill = col
+}
+
+func (g *GGContext) SetStrokeColor(col color.Color) {
+	g.style.stroke = col
+}
+
+func (g *GGContext) SetStrokeWidth(width float64) {
+	g.style.strokeWidth = width
+}
+
+func (g *GGContext) SetLineCap(c LineCap) {
+	g.style.cap = c
+}
+
+func (g *GGContext) SetLineJoin(j LineJoin) {
+	g.style.join = j
+}
+
+func (g *GGContext) SetMiterLimit(limit float64) {
+	g.style.miterLimit = limit
+}
+
+func (g *GGContext) SetDashes(offset float64, dashes ...float64) {
+	g.style.dashOffset = offset
+	g.style.dashes = append([]float64(nil), dashes...)
+}
+
+// TaperedPolyline adds the outline of a stroke of changing width to the current path, see Context.TaperedPolyline.
+func (g *GGContext) TaperedPolyline(width func(i int) float64, xy ...float64) {
+	g.path = g.path.Append(taperedOutline(g.style.view, width, xy, g.style.miterLimit))
+}
+
+// Point draws a 1 pixel rectangle at point
+func (g *GGContext) Point(x, y float64) {
+	g.render(canvas.Rectangle(1, 1).Translate(x, y), true, true)
+}
+
+// FillRect draws a rectable path
+func (g *GGContext) FillRect(x, y, w, h float64) {
+	g.render(canvas.Rectangle(w, h).Translate(x, y), true, true)
+}
+
+// Stroke strokes the current path and resets it.
+func (g *GGContext) Stroke() {
+	g.render(g.path, false, true)
+	g.path = &canvas.Path{}
+}
+
+// Fill fills the current path and resets it.
+func (g *GGContext) Fill() {
+	g.render(g.path, true, false)
+	g.path = &canvas.Path{}
+}
+
+// FillStroke fills and then strokes the current path and resets it.
+func (g *GGContext) FillStroke() {
+	g.render(g.path, true, true)
+	g.path = &canvas.Path{}
+}
+
+// Clip restricts what's drawn from now on to the inside of the current path,
+// and starts a new one. It intersects with the clip already set, Pop goes back
+// to the clip there was at Push and ResetClip removes them all.
+func (g *GGContext) Clip() {
+	g.clip(g.path)
+	g.path = &canvas.Path{}
+}
+
+// ClipRect clips to the rectangle x,y to x+w,y+h like Clip, leaving the current path alone.
+func (g *GGContext) ClipRect(x, y, w, h float64) {
+	g.clip(canvas.Rectangle(w, h).Translate(x, y))
+}
+
+// ResetClip removes the clips, until the next Pop brings back the ones pushed.
+func (g *GGContext) ResetClip() {
+	g.style.clip = nil
+	g.setMask()
+}
+
+func (g *GGContext) clip(p *canvas.Path) {
+	mask := coverage(p.Transform(g.style.view), g.dc.Width(), g.dc.Height(), float64(g.resolution), true)
+	if g.style.clip != nil {
+		for i, v := range g.style.clip.Pix {
+			mask.Pix[i] = uint8(uint32(mask.Pix[i]) * uint32(v) / 0xff)
+		}
+	}
+	g.style.clip = mask
+	g.setMask()
+}
+
+// setMask hands the clip to gg
+func (g *GGContext) setMask() {
+	if g.style.clip == nil {
+		g.dc.ResetClip()
+	} else {
+		g.dc.SetMask(g.style.clip)
+	}
+}
+
+// Translate moves the origin to x,y
+func (g *GGContext) Translate(x, y float64) {
+	g.style.view = g.style.view.Translate(x, y)
+}
+
+// Rotate rotates counter clockwise around the origin
+func (g *GGContext) Rotate(angle float64) {
+	g.style.view = g.style.view.Rotate(Degrees(angle))
+}
+
+// RotateAbout rotates counter clockwise around x,y
+func (g *GGContext) RotateAbout(angle, x, y float64) {
+	g.style.view = g.style.view.RotateAbout(Degrees(angle), x, y)
+}
+
+// Scale scales by sx,sy around the origin
+func (g *GGContext) Scale(sx, sy float64) {
+	g.style.view = g.style.view.Scale(sx, sy)
+}
+
+// Skew shears along X by angle ax and along Y by angle ay
+func (g *GGContext) Skew(ax, ay float64) {
+	g.style.view = g.style.view.Shear(math.Tan(ax), math.Tan(ay))
+}
+
+// Matrix returns the current transformation
+func (g *GGContext) Matrix() Matrix {
+	return g.style.view
+}
+
+// SetMatrix replaces the current transformation
+func (g *GGContext) SetMatrix(m Matrix) {
+	g.style.view = m
+}
+
+// ResetMatrix goes back to the identity transformation, or the page units
+func (g *GGContext) ResetMatrix() {
+	g.style.view = Identity.Scale(g.unit, g.unit)
+}
+
+// setUnit makes the coordinates `unit` mm
+func (g *GGContext) setUnit(unit float64) {
+	g.unit = unit
+	g.ResetMatrix()
+}
+
+// FitBounds maps the world space box minX,minY to maxX,maxY into the
+// width x height of the image less `margin` on every side, keeping the
+// aspect ratio and centering it.
+func (g *GGContext) FitBounds(minX, minY, maxX, maxY, margin float64) {
+	g.style.view = g.style.view.Mul(fitBounds(g.width/g.unit, g.height/g.unit, minX, minY, maxX, maxY, margin))
+}
+
+// render rasterizes `p`, which is in mm, flipping it so y goes up like canvas.
+// Like canvas the stroke width isn't affected by the transformation.
+func (g *GGContext) render(p *canvas.Path, fill, stroke bool) {
+	if p.Empty() {
+		return
+	}
+	res := float64(g.resolution)
+	toPixels := Identity.Translate(0, float64(g.dc.Height())).Scale(res, -res).Mul(g.style.view)
+	p = p.Transform(toPixels)
+	if g.offImage(p.Bounds(), g.style.strokeWidth*res) {
+		return
+	}
+	g.appendPath(p)
+	if fill && !transparent(g.style.fill) {
+		g.dc.SetFillStyle(gg.NewSolidPattern(g.style.fill))
+		g.dc.FillPreserve()
+	}
+	if stroke && g.style.strokeWidth > 0 && !transparent(g.style.stroke) {
+		s := g.style
+		dashes := make([]float64, len(s.dashes))
+		for i, d := range s.dashes {
+			dashes[i] = d * res
+		}
+		if s.join == JoinMiter && joined(p) {
+			// gg can't miter, so fill canvas' outline of the stroke
+			if len(dashes) > 0 {
+				p = p.Dash(s.dashOffset*res, dashes...)
+			}
+			g.dc.ClearPath()
+			g.appendPath(p.Stroke(s.strokeWidth*res, s.cap.capper(), s.join.joiner(s.miterLimit)))
+			g.dc.SetFillStyle(gg.NewSolidPattern(s.stroke))
+			g.dc.FillPreserve()
+		} else {
+			g.dc.SetStrokeStyle(gg.NewSolidPattern(s.stroke))
+			g.dc.SetLineWidth(s.strokeWidth * res)
+			g.dc.SetLineCap(s.cap.gg())
+			if s.join == JoinRound {
+				g.dc.SetLineJoinRound()
+			} else {
+				g.dc.SetLineJoinBevel()
+			}
+			g.dc.SetDash(dashes...)
+			g.dc.SetDashOffset(s.dashOffset * res)
+			g.dc.StrokePreserve()
+		}
+	}
+	g.dc.ClearPath()
+}
+
+// appendPath adds `p`, in pixels, to gg's path
+func (g *GGContext) appendPath(p *canvas.Path) {
+	p.ReplaceArcs().Iterate(
+		func(_, end canvas.Point) { g.dc.MoveTo(end.X, end.Y) },
+		func(_, end canvas.Point) { g.dc.LineTo(end.X, end.Y) },
+		func(_, cp, end canvas.Point) { g.dc.QuadraticTo(cp.X, cp.Y, end.X, end.Y) },
+		func(_, cp1, cp2, end canvas.Point) { g.dc.CubicTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y) },
+		func(_ canvas.Point, _, _, _ float64, _, _ bool, end canvas.Point) { g.dc.LineTo(end.X, end.Y) },
+		func(_, _ canvas.Point) { g.dc.ClosePath() },
+	)
+}
+
+// joined is true when `p` has corners for a join, a subpath with more than
+// one segment or closed.
+func joined(p *canvas.Path) bool {
+	n, corners := 0, false
+	p.Iterate(
+		func(_, _ canvas.Point) { n = 0 },
+		func(_, _ canvas.Point) { n++; corners = corners || n > 1 },
+		func(_, _, _ canvas.Point) { n++; corners = corners || n > 1 },
+		func(_, _, _, _ canvas.Point) { n++; corners = corners || n > 1 },
+		func(_ canvas.Point, _, _, _ float64, _, _ bool, _ canvas.Point) { n++; corners = corners || n > 1 },
+		func(_, _ canvas.Point) { corners = true },
+	)
+	return corners
+}
+
+// offImage is true when `bounds`, in pixels, grown by `pad` doesn't touch the image,
+// skipping these like canvas does saves most of the time.
+func (g *GGContext) offImage(bounds canvas.Rect, pad float64) bool {
+	return bounds.X+bounds.W+pad < 0 || bounds.Y+bounds.H+pad < 0 ||
+		bounds.X-pad > float64(g.dc.Width()) || bounds.Y-pad > float64(g.dc.Height())
+}
+
+func transparent(col color.Color) bool {
+	_, _, _, a := col.RGBA()
+	return a == 0
+}
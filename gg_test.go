@@ -0,0 +1,43 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestBackendsAgree(t *testing.T) {
+	for _, backend := range []string{CanvasBackend, GGBackend} {
+		d, err := NewDrawer(backend, 20, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetFillColor(color.White)
+		d.FillRect(0, 0, 20, 10)
+		d.SetFillColor(color.Black)
+		d.Rect(0, 0, 10, 5) // bottom left quarter
+		d.Fill()
+
+		img, err := rasterize(d, defaultResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := img.Bounds()
+		if got := b.Size(); got.X != 64 || got.Y != 32 {
+			t.Errorf("%s: size = %v, want 64x32", backend, got)
+		}
+		if r, _, _, _ := img.At(b.Dx()/4, b.Dy()*3/4).RGBA(); r != 0 {
+			t.Errorf("%s: bottom left should be black", backend)
+		}
+		if r, _, _, _ := img.At(b.Dx()/4, b.Dy()/4).RGBA(); r != 0xffff {
+			t.Errorf("%s: top left should be white", backend)
+		}
+	}
+}
+
+func TestGGNotVector(t *testing.T) {
+	g := NewGGContext(20, 10, defaultResolution)
+	if err := SVGWriter(&bytes.Buffer{}, g); err != ErrNotVector {
+		t.Errorf("SVGWriter() = %v, want ErrNotVector", err)
+	}
+}
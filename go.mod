@@ -6,7 +6,7 @@ require (
 	github.com/fogleman/gg v1.3.0
 	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gojp/goreportcard v0.0.0-20200928020921-6cb26c2f6add // indirect
-	github.com/lucasb-eyer/go-colorful v1.0.3 // indirect
+	github.com/lucasb-eyer/go-colorful v1.0.3
 	github.com/tdewolff/canvas v0.0.0-20201021153214-d9228b138ea8
 	golang.org/x/exp v0.0.0-20201008143054-e3b2a7f2fdc7
 	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
@@ -7,22 +7,24 @@ import (
 	"fmt"
 	"image/color"
 	"math"
-	"math/rand"
 
 	"github.com/lucasb-eyer/go-colorful"
 	"github.com/scottkirkwood/gart"
 )
 
 const (
-	width            = 215.9 // mm
-	height           = 279.4 // mm
 	defaultLineWidth = 0.6
 	cols             = 200
 	deltaY           = 10  // pixels
 	muteY            = 0.2 // std of 1/2 height variation
 )
 
-var seedFlag = flag.String("seed", "", "Hex value for the seed to use")
+var (
+	seedFlag    = flag.String("seed", "", "Hex value for the seed to use")
+	paperFlag   = gart.PaperFlag(gart.Letter)
+	pngFlags    = gart.PNGFlags()
+	captionFlag = gart.CaptionFlag()
+)
 
 func main() {
 	flag.Parse()
@@ -31,13 +33,8 @@ func main() {
 		fmt.Printf("Unable to set the seed: %v\n", err)
 	}
 
-	ctx := gart.NewContext(width, height)
-	ctx.SetFillColor(color.Gray{245})
-	ctx.FillRect(0, 0, width, height)
-	ctx.SetStrokeColor(color.Black)
-	ctx.SetStrokeWidth(defaultLineWidth)
-
-	draw(ctx)
+	ctx := newContext()
+	draw(ctx, g)
 
 	if err := g.SafeWrite(ctx, "horzlines-", ".png"); err != nil {
 		fmt.Printf("Unable write image: %v\n", err)
@@ -45,11 +42,28 @@ func main() {
 	}
 }
 
-func draw(ctx *gart.Context) {
+// newContext returns a page with the background filled in
+func newContext() *gart.Context {
+	page := gart.PageOptions{Paper: *paperFlag}
+	ctx := gart.NewPage(page)
+	ctx.SetFillColor(color.Gray{245})
+	w, h := page.Size()
+	ctx.FillRect(0, 0, w, h)
+	ctx.SetStrokeColor(color.Black)
+	ctx.SetStrokeWidth(defaultLineWidth)
+	return ctx
+}
+
+func draw(ctx gart.Drawer, g gart.Seed) {
+	width, height := ctx.Size()
+	// the lines wander off the top of the page, keep them on it for plotters
+	ctx.Push()
+	defer ctx.Pop()
+	ctx.ClipRect(0, 0, width, height)
 	ypoints := make([]float64, cols)
-	deltaX := float64(width / cols)
+	deltaX := width / cols
 
-	rc := colorful.Hsl(30.0+rand.Float64()*50.0, 0.2+rand.Float64()*0.8, 0.3+rand.Float64()*0.7)
+	rc := colorful.Hsl(30.0+g.Float64()*50.0, 0.2+g.Float64()*0.8, 0.3+g.Float64()*0.7)
 	hue, sat, light := rc.Hsl()
 	ctx.SetStrokeColor(rc)
 
@@ -57,7 +71,7 @@ func draw(ctx *gart.Context) {
 	for y := 0.0; y < float64(height)+maxDy; y += float64(deltaY) {
 		ctx.MoveTo(0, float64(y))
 		for i := 0; i < cols; i++ {
-			ypoints[i] += rand.NormFloat64() * deltaY * muteY
+			ypoints[i] += g.NormFloat64() * deltaY * muteY
 			maxDy = math.Max(maxDy, ypoints[i])
 
 		}
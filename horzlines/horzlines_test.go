@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+	"github.com/scottkirkwood/gart/garttest"
+)
+
+func TestDraw(t *testing.T) {
+	record := func() *gart.Recorder {
+		g, err := gart.Init("1f3e")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := gart.NewRecorder(paperFlag.Width, paperFlag.Height)
+		draw(rec, g)
+		return rec
+	}
+	rec := record()
+	width, height := rec.Size()
+	if got, want := rec.Count("Stroke"), int(math.Floor(height/deltaY)); got < want {
+		t.Errorf("%d lines stroked, want at least %d", got, want)
+	}
+	minX, _, maxX, _ := rec.Bounds()
+	if minX < 0 || maxX > width {
+		t.Errorf("lines go from x=%.1f to %.1f, want within 0 to %.1f", minX, maxX, width)
+	}
+	if !reflect.DeepEqual(rec.Ops, record().Ops) {
+		t.Errorf("the same seed drew something different")
+	}
+}
+
+func TestGolden(t *testing.T) {
+	ctx := newContext()
+	draw(ctx, garttest.Seed(t, "1f3e"))
+	garttest.Golden(t, "horzlines", ctx, garttest.DefaultOptions)
+}
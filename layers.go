@@ -0,0 +1,433 @@
+package gart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/svg"
+)
+
+// DefaultLayer holds what's drawn before the first SetLayer.
+const DefaultLayer = "default"
+
+// FillLayer holds the paths without a stroke when splitting by pen, they
+// can't be plotted but they're kept for the SVG.
+const FillLayer = "fill"
+
+// Layer is the part of a drawing plotted with one pen, see SplitLayers.
+type Layer struct {
+	Name    string
+	Color   color.RGBA // the pen, the average stroke color of the layer
+	Context *Context
+}
+
+// SetLayer puts what's drawn from now on in the layer `name`, creating it if
+// needed. Layers are written as Inkscape layers in SVG files and
+// SafeWriteLayers writes a file per layer, to plot them pen by pen.
+func (ctx *Context) SetLayer(name string) {
+	// the clip groups are reopened so what's drawn next goes in one on the new layer
+	ctx.closeGroups(ctx.blendGroup() + 1)
+	defer ctx.applyClips()
+	for i, l := range ctx.layers {
+		if l == name {
+			ctx.tags.cur = i
+			return
+		}
+	}
+	ctx.layers = append(ctx.layers, name)
+	ctx.tags.cur = len(ctx.layers) - 1
+}
+
+// Layers returns the layer names in the order they were created,
+// starting with DefaultLayer.
+func (ctx *Context) Layers() []string {
+	return append([]string(nil), ctx.layers...)
+}
+
+// layered is true when the drawing is split into layers to be written.
+func (ctx *Context) layered(pens int) bool {
+	return len(ctx.layers) > 1 || pens > 0
+}
+
+// SplitLayers returns a Context per layer of `d` holding what was drawn on it,
+// leaving out empty layers.
+// If no layers were set and `pens` is more than 0 the drawing is split by
+// stroke color instead, with the colors quantized down to at most `pens` pens
+// ordered light to dark, plus a FillLayer if anything wasn't stroked.
+func SplitLayers(d Drawer, pens int) ([]Layer, error) {
+	ctx, err := toContext(d)
+	if err != nil {
+		return nil, err
+	}
+	var layers []Layer
+	var pick func(i int, style canvas.Style) int
+	byPen := len(ctx.layers) == 1 && pens > 0
+	if byPen {
+		layers, pick = penLayers(ctx, pens)
+	} else {
+		for _, name := range ctx.layers {
+			layers = append(layers, Layer{Name: name})
+		}
+		pick = ctx.tags.layerOf()
+	}
+
+	sums := make([][4]float64, len(layers)) // r, g, b and the count of stroked paths
+	used := make([]bool, len(layers))
+	for i := range layers {
+		layers[i].Context = NewContext(ctx.c.W, ctx.c.H)
+		layers[i].Context.provenance = ctx.provenance
+	}
+	n := 0
+	ctx.c.Render(&visitor{ctx.c.W, ctx.c.H, byPen, func(style canvas.Style, replay func(canvas.Renderer)) {
+		l := pick(n, style)
+		n++
+		used[l] = true
+		replay(layers[l].Context.tags)
+		if stroked(style) {
+			c := opaque(style.StrokeColor)
+			sums[l][0] += float64(c.R)
+			sums[l][1] += float64(c.G)
+			sums[l][2] += float64(c.B)
+			sums[l][3]++
+		}
+	}})
+
+	var out []Layer
+	for i, l := range layers {
+		if !used[i] {
+			continue
+		}
+		if s := sums[i]; l.Color.A == 0 && s[3] > 0 {
+			l.Color = color.RGBA{uint8(s[0]/s[3] + 0.5), uint8(s[1]/s[3] + 0.5), uint8(s[2]/s[3] + 0.5), 255}
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// penLayers makes a layer per pen, quantizing the stroke colors of `ctx`,
+// and the func picking the layer of each draw call.
+func penLayers(ctx *Context, pens int) ([]Layer, func(int, canvas.Style) int) {
+	index := map[color.RGBA]int{}
+	var colors []color.RGBA
+	var counts []int
+	ctx.c.Render(&visitor{ctx.c.W, ctx.c.H, true, func(style canvas.Style, _ func(canvas.Renderer)) {
+		if !stroked(style) {
+			return
+		}
+		c := opaque(style.StrokeColor)
+		i, ok := index[c]
+		if !ok {
+			i = len(colors)
+			index[c] = i
+			colors = append(colors, c)
+			counts = append(counts, 0)
+		}
+		counts[i]++
+	}})
+
+	centers, cluster := quantizeColors(colors, counts, pens)
+	order := make([]int, len(centers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return luma(centers[order[i]]) > luma(centers[order[j]])
+	})
+	// the fill layer goes first, under the pens, since it's usually the background
+	layers := []Layer{{Name: FillLayer}}
+	pen := make([]int, len(centers))
+	for i, c := range order {
+		pen[c] = i + 1
+		layers = append(layers, Layer{
+			Name:  fmt.Sprintf("pen%d-%02x%02x%02x", i+1, centers[c].R, centers[c].G, centers[c].B),
+			Color: centers[c],
+		})
+	}
+	return layers, func(_ int, style canvas.Style) int {
+		if !stroked(style) {
+			return 0
+		}
+		return pen[cluster[index[opaque(style.StrokeColor)]]]
+	}
+}
+
+const maxQuantizeIterations = 20
+
+// quantizeColors groups `colors`, weighted by `counts`, into at most k
+// clusters with k-means, returning the centers and the cluster of each color.
+// It starts from the most common color and keeps adding the color furthest
+// from the centers so far, so it's deterministic and small but distinct
+// accents still get a pen.
+func quantizeColors(colors []color.RGBA, counts []int, k int) ([]color.RGBA, []int) {
+	cluster := make([]int, len(colors))
+	if len(colors) <= k {
+		for i := range cluster {
+			cluster[i] = i
+		}
+		return append([]color.RGBA(nil), colors...), cluster
+	}
+	first := 0
+	for i, n := range counts {
+		if n > counts[first] {
+			first = i
+		}
+	}
+	centers := [][3]float64{rgb(colors[first])}
+	nearest := func(c [3]float64) (int, float64) {
+		best, bestDist := 0, -1.0
+		for i, center := range centers {
+			if d := dist2(c, center); bestDist < 0 || d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		return best, bestDist
+	}
+	for len(centers) < k {
+		far, farDist := 0, -1.0
+		for i, c := range colors {
+			if _, d := nearest(rgb(c)); d > farDist {
+				far, farDist = i, d
+			}
+		}
+		centers = append(centers, rgb(colors[far]))
+	}
+
+	for iter := 0; iter < maxQuantizeIterations; iter++ {
+		changed := iter == 0
+		for i, c := range colors {
+			if n, _ := nearest(rgb(c)); n != cluster[i] {
+				cluster[i] = n
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][4]float64, len(centers))
+		for i, c := range colors {
+			w := float64(counts[i])
+			v := rgb(c)
+			s := &sums[cluster[i]]
+			s[0] += v[0] * w
+			s[1] += v[1] * w
+			s[2] += v[2] * w
+			s[3] += w
+		}
+		for i, s := range sums {
+			if s[3] > 0 {
+				centers[i] = [3]float64{s[0] / s[3], s[1] / s[3], s[2] / s[3]}
+			}
+		}
+	}
+
+	out := make([]color.RGBA, len(centers))
+	for i, c := range centers {
+		out[i] = color.RGBA{uint8(c[0] + 0.5), uint8(c[1] + 0.5), uint8(c[2] + 0.5), 255}
+	}
+	return out, cluster
+}
+
+func rgb(c color.RGBA) [3]float64 {
+	return [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+}
+
+func dist2(a, b [3]float64) float64 {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+// opaque undoes the premultiplied alpha, a faint stroke is still plotted
+// with the same pen as a solid one.
+func opaque(c color.RGBA) color.RGBA {
+	if c.A == 0 || c.A == 255 {
+		return color.RGBA{c.R, c.G, c.B, 255}
+	}
+	a := uint32(c.A)
+	return color.RGBA{uint8(uint32(c.R) * 255 / a), uint8(uint32(c.G) * 255 / a), uint8(uint32(c.B) * 255 / a), 255}
+}
+
+func luma(c color.RGBA) float64 {
+	return 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+}
+
+func stroked(style canvas.Style) bool {
+	return style.StrokeColor.A != 0 && style.StrokeWidth > 0
+}
+
+// layerTagger is the canvas.Renderer behind Context, it notes which layer each
+// draw call went to so the canvas can be split up again.
+type layerTagger struct {
+	*canvas.Canvas
+	cur   int // current layer
+	n     int // draw calls so far
+	spans []layerSpan
+}
+
+// layerSpan is where the layer changed
+type layerSpan struct {
+	start, layer int
+}
+
+func (lt *layerTagger) tag() {
+	if len(lt.spans) == 0 || lt.spans[len(lt.spans)-1].layer != lt.cur {
+		lt.spans = append(lt.spans, layerSpan{lt.n, lt.cur})
+	}
+	lt.n++
+}
+
+func (lt *layerTagger) reset() {
+	lt.n = 0
+	lt.spans = nil
+}
+
+func (lt *layerTagger) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	lt.tag()
+	lt.Canvas.RenderPath(path, style, m)
+}
+
+func (lt *layerTagger) RenderText(text *canvas.Text, m canvas.Matrix) {
+	lt.tag()
+	lt.Canvas.RenderText(text, m)
+}
+
+func (lt *layerTagger) RenderImage(img image.Image, m canvas.Matrix) {
+	lt.tag()
+	lt.Canvas.RenderImage(img, m)
+}
+
+// layerOf returns a func giving the layer of the i'th draw call, it has to be
+// called with i counting up from 0, as canvas.Render does.
+func (lt *layerTagger) layerOf() func(i int, _ canvas.Style) int {
+	s := 0
+	return func(i int, _ canvas.Style) int {
+		if len(lt.spans) == 0 {
+			return 0
+		}
+		for s+1 < len(lt.spans) && lt.spans[s+1].start <= i {
+			s++
+		}
+		return lt.spans[s].layer
+	}
+}
+
+// visitor is a canvas.Renderer handing each draw call to `fn` along with a
+// func to replay it on another renderer. Text and images have no style,
+// except painted paths. With `groups` the calls in groups are handed on one
+// by one, replayed in a copy of the group, so a stroke in a blend group or
+// clip still goes to its pen.
+type visitor struct {
+	width, height float64
+	groups        bool
+	fn            func(style canvas.Style, replay func(canvas.Renderer))
+}
+
+func (v *visitor) Size() (float64, float64) {
+	return v.width, v.height
+}
+
+func (v *visitor) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	v.fn(style, func(r canvas.Renderer) { r.RenderPath(path, style, m) })
+}
+
+func (v *visitor) RenderText(text *canvas.Text, m canvas.Matrix) {
+	v.fn(canvas.Style{}, func(r canvas.Renderer) { r.RenderText(text, m) })
+}
+
+func (v *visitor) RenderImage(img image.Image, m canvas.Matrix) {
+	if g, ok := img.(groupImage); ok && v.groups {
+		v.visitGroup(g.group)
+		return
+	}
+	style := canvas.Style{}
+	if pi, ok := img.(paintImage); ok {
+		style = pi.style
+	}
+	v.fn(style, func(r canvas.Renderer) { r.RenderImage(img, m) })
+}
+
+// visitGroup hands on the calls in the group `g`, those replayed on the
+// same renderer go in one copy of it.
+func (v *visitor) visitGroup(g *group) {
+	copies := map[canvas.Renderer]*group{}
+	var order []canvas.Renderer
+	g.c.Render(&visitor{v.width, v.height, true, func(style canvas.Style, replay func(canvas.Renderer)) {
+		v.fn(style, func(r canvas.Renderer) {
+			cp, ok := copies[r]
+			if !ok {
+				cp = &group{c: canvas.New(v.width, v.height), mode: g.mode, opacity: g.opacity, clip: g.clip}
+				copies[r] = cp
+				order = append(order, r)
+			}
+			replay(cp.c)
+		})
+	}})
+	for _, r := range order {
+		r.RenderImage(groupImage{copies[r]}, Identity)
+	}
+}
+
+const inkscapeNS = `xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"`
+
+// LayeredSVGWriter writes SVG files with each layer as an Inkscape layer,
+// splitting by stroke color into `pens` layers when none were set, see SplitLayers.
+// Without layers it's a plain SVG.
+func LayeredSVGWriter(pens int) WriterFunc {
+	return withProvenance(func(w io.Writer, d Drawer) error {
+		ctx, err := toContext(d)
+		if err != nil {
+			return err
+		}
+		if !ctx.layered(pens) {
+			r := svg.New(w, ctx.c.W, ctx.c.H)
+			ctx.c.Render(newSVGGroups(r, w))
+			return r.Close()
+		}
+		layers, err := SplitLayers(ctx, pens)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		r := svg.New(&buf, ctx.c.W, ctx.c.H)
+		groups := newSVGGroups(r, &buf)
+		for i, l := range layers {
+			buf.WriteString(`<g inkscape:groupmode="layer" inkscape:label="`)
+			xml.EscapeText(&buf, []byte(l.Name))
+			fmt.Fprintf(&buf, `" id="layer%d">`, i+1)
+			l.Context.c.Render(groups)
+			buf.WriteString(`</g>`)
+		}
+		if err := r.Close(); err != nil {
+			return err
+		}
+		data := bytes.Replace(buf.Bytes(), []byte("<svg "), []byte("<svg "+inkscapeNS+" "), 1)
+		_, err = w.Write(data)
+		return err
+	}, embedSVG)
+}
+
+// SafeWriteLayers noisily saves each layer of `d` in each of `exts`, with the
+// layer name added to `prefix`, so multi-color pieces can be plotted pen by pen.
+// See SplitLayers for `pens`.
+func (s Seed) SafeWriteLayers(d Drawer, prefix string, pens int, exts ...string) error {
+	// every layer records the sketch's provenance, not one named after the layer
+	p := s.Provenance(prefix)
+	layers, err := SplitLayers(stamped(d, p), pens)
+	if err != nil {
+		fmt.Printf("Problem splitting %s into layers: %v\n", prefix, err)
+		return err
+	}
+	var firstErr error
+	for _, l := range layers {
+		if err := s.writeAll(l.Context, p, prefix+l.Name+"-", exts...); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
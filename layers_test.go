@@ -0,0 +1,121 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func hline(ctx *Context, col color.Color, y float64) {
+	ctx.SetStrokeColor(col)
+	ctx.MoveTo(10, y)
+	ctx.LineTo(90, y)
+	ctx.Stroke()
+}
+
+func TestSetLayer(t *testing.T) {
+	ctx := NewContext(100, 100)
+	ctx.SetFillColor(color.White)
+	ctx.FillRect(0, 0, 100, 100)
+	ctx.SetLayer("red")
+	hline(ctx, color.RGBA{255, 0, 0, 255}, 10)
+	ctx.SetLayer("blue")
+	hline(ctx, color.RGBA{0, 0, 255, 255}, 20)
+	ctx.SetLayer("red")
+	hline(ctx, color.RGBA{255, 0, 0, 255}, 30)
+	ctx.SetLayer("empty")
+
+	layers, err := SplitLayers(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, l := range layers {
+		names = append(names, l.Name)
+	}
+	if got, want := strings.Join(names, ","), "default,red,blue"; got != want {
+		t.Fatalf("layers = %s, want %s", got, want)
+	}
+	paths, _ := PlotPaths(layers[1].Context, 0.1)
+	if len(paths) != 2 {
+		t.Errorf("red has %d paths, want 2", len(paths))
+	}
+	if want := (color.RGBA{255, 0, 0, 255}); layers[1].Color != want {
+		t.Errorf("red is %v, want %v", layers[1].Color, want)
+	}
+
+	var buf bytes.Buffer
+	if err := SVGWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, want := range []string{inkscapeNS, `inkscape:label="red" id="layer2"`, `inkscape:label="blue" id="layer3"`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG is missing %s", want)
+		}
+	}
+}
+
+func TestSplitByPen(t *testing.T) {
+	ctx := NewContext(100, 100)
+	ctx.SetFillColor(color.White)
+	ctx.FillRect(0, 0, 100, 100)
+	hline(ctx, color.RGBA{250, 0, 0, 255}, 10)
+	hline(ctx, color.RGBA{0, 0, 20, 255}, 20)
+	hline(ctx, color.RGBA{128, 0, 0, 128}, 30) // faint red, same pen as the solid one
+	hline(ctx, color.RGBA{240, 10, 0, 255}, 40)
+	ctx.BeginGroup(BlendMultiply, 1) // still goes to its pen
+	hline(ctx, color.RGBA{0, 0, 0, 255}, 50)
+	ctx.EndGroup()
+
+	layers, err := SplitLayers(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 3 {
+		t.Fatalf("got %d layers, want fill and 2 pens", len(layers))
+	}
+	if layers[0].Name != FillLayer {
+		t.Errorf("first layer is %q, want %q", layers[0].Name, FillLayer)
+	}
+	for i, want := range []int{3, 2} {
+		paths, _ := PlotPaths(layers[i+1].Context, 0.1)
+		if len(paths) != want {
+			t.Errorf("%s has %d paths, want %d", layers[i+1].Name, len(paths), want)
+		}
+	}
+	if !strings.HasPrefix(layers[1].Name, "pen1-f") {
+		t.Errorf("the lighter red pen should come first, got %s", layers[1].Name)
+	}
+}
+
+func TestSafeWriteLayersProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := NewContext(100, 100)
+	hline(ctx, color.RGBA{255, 0, 0, 255}, 10)
+
+	s, _ := Init("1f3e")
+	if err := s.SafeWriteLayers(ctx, filepath.Join(dir, "sketch-"), 1, ".svg"); err != nil {
+		t.Fatal(err)
+	}
+	names, _ := filepath.Glob(filepath.Join(dir, "sketch-pen1-*.svg"))
+	if len(names) != 1 {
+		t.Fatalf("got pen files %v, want 1", names)
+	}
+	// it has to name the sketch and seed for reproduce to work
+	p, err := ReadProvenance(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Sketch != "sketch" || p.Seed != "1f3e" {
+		t.Errorf("ReadProvenance() = sketch %q seed %q, want sketch \"sketch\" seed \"1f3e\"", p.Sketch, p.Seed)
+	}
+}
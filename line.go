@@ -5,6 +5,8 @@ import (
 )
 
 // Line is a set of two image.Points
+//
+// Deprecated: it's in whole pixels and can't be made outside gart, use Segment.
 type Line struct {
 	x1, x2 image.Point
 }
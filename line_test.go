@@ -29,7 +29,7 @@ func TestAll(t *testing.T) {
 		if tt.x1.Crosses(tt.x2) != tt.want {
 			t.Errorf("Want %v.Crosses(%v) = %v, got %v", tt.x1, tt.x2, tt.want, !tt.want)
 		}
-		if tt.x2.Crosses(tt.x1) == tt.want {
+		if tt.x2.Crosses(tt.x1) != tt.want {
 			t.Errorf("Want %v.Crosses(%v) = %v, got %v", tt.x2, tt.x1, tt.want, !tt.want)
 		}
 	}
@@ -8,22 +8,25 @@ import (
 	"fmt"
 	"image/color"
 	"math"
-	"math/rand"
 	"strings"
 
 	"github.com/scottkirkwood/gart"
 )
 
 const (
-	width            = 215.9 // mm
-	height           = 279.4 // mm
 	defaultLineWidth = 0.3
 	maxDepth         = 7
+	margin           = 10 // mm
 )
 
 var (
-	seedFlag = flag.String("seed", "", "Hex value for the seed to use")
-	lsystems = []lsystem{
+	seedFlag    = flag.String("seed", "", "Hex value for the seed to use")
+	paperFlag   = gart.PaperFlag(gart.Letter)
+	formatsFlag = flag.String("formats", "png", "Comma separated output formats, ex. png,svg,hpgl")
+	inkFlag     = flag.String("ink", "black", "Color of the branches, ex. black or #2f4f2f")
+	pngFlags    = gart.PNGFlags()
+	captionFlag = gart.CaptionFlag()
+	lsystems    = []lsystem{
 		{
 			name:       "Tree Like",
 			startAngle: 90,
@@ -125,14 +128,18 @@ type lsystem struct {
 }
 
 type fractal struct {
-	ctx                   *gart.Context
+	ctx                   gart.Drawer
 	sequence              string
 	lsys                  lsystem
-	width, height         int // pixels
 	stack                 []turtle
 	angleLeft, angleRight float64 // radians
 	minX, minY            float64
 	maxX, maxY            float64
+	xy, widths            []float64   // the branch being drawn
+	ink                   color.Color // of the branches
+	plotted               bool        // stroke the centre lines too, plotters skip fills
+	lines                 [][]float64 // the centre lines of the branches
+	thinnest              float64     // mm, the narrowest a branch gets
 }
 
 func main() {
@@ -142,29 +149,55 @@ func main() {
 		fmt.Printf("Unable to set the seed: %v\n", err)
 	}
 
-	ctx := gart.NewContext(width, height)
-	ctx.SetFillColor(color.Gray{245})
-	ctx.FillRect(0, 0, width, height)
-
-	ctx.SetStrokeColor(color.Black)
-	ctx.SetStrokeWidth(defaultLineWidth)
+	ink, err := gart.ParseColor(*inkFlag)
+	if err == nil && ink == nil {
+		err = fmt.Errorf("the branches can't be transparent")
+	}
+	if err != nil {
+		fmt.Printf("Unable to use the ink: %v\n", err)
+		return
+	}
+	exts := gart.ParseFormats(*formatsFlag)
 
-	lsystem := lsystems[rand.Intn(len(lsystems))]
+	ctx := newContext(ink)
+	lsystem := g.Choice(lsystems).(lsystem)
 	lsystem = lsystems[len(lsystems)-2]
 
-	f := initFractal(ctx, lsystem,
-		int(math.Floor(width)),
-		int(math.Floor(height)))
+	f := initFractal(ctx, lsystem, ink)
+	f.plotted = plotted(exts)
 	f.generate()
 	f.draw()
 
-	if err := g.SafeWrite(ctx, "lsystem-", ".png"); err != nil {
+	if err := g.SafeWriteAll(ctx, "lsystem-", exts...); err != nil {
 		fmt.Printf("Unable write image: %v\n", err)
 		return
 	}
 }
 
-func initFractal(ctx *gart.Context, lsys lsystem, width, height int) *fractal {
+// plotted is true if one of the formats is for a plotter
+func plotted(exts []string) bool {
+	for _, ext := range exts {
+		if ext == ".hpgl" || ext == ".gcode" {
+			return true
+		}
+	}
+	return false
+}
+
+// newContext returns a page with the background filled in
+func newContext(ink color.Color) *gart.Context {
+	page := gart.PageOptions{Paper: *paperFlag}
+	ctx := gart.NewPage(page)
+	ctx.SetFillColor(color.Gray{245})
+	w, h := page.Size()
+	ctx.FillRect(0, 0, w, h)
+
+	ctx.SetStrokeColor(ink)
+	ctx.SetStrokeWidth(defaultLineWidth)
+	return ctx
+}
+
+func initFractal(ctx gart.Drawer, lsys lsystem, ink color.Color) *fractal {
 	angleLeft := lsys.angles[0]
 	angleRight := angleLeft
 	if len(lsys.angles) == 2 {
@@ -178,7 +211,8 @@ func initFractal(ctx *gart.Context, lsys lsystem, width, height int) *fractal {
 		ctx:       ctx,
 		lsys:      lsys,
 		angleLeft: gart.Radians(angleLeft), angleRight: gart.Radians(angleRight),
-		width: width, height: height,
+		ink:      ink,
+		thinnest: math.Inf(1),
 	}
 	return f
 }
@@ -207,10 +241,30 @@ func (f *fractal) generate() string {
 }
 
 func (f *fractal) draw() {
-	f.internalGenerate(f.getLimits)
+	f.internalGenerate(f.getLimits, f.getLimits)
 	fmt.Printf("Limits %f, %f\n", f.maxX, f.maxY)
-	f.internalGenerate(f.drawTo)
+	f.ctx.Push()
+	f.ctx.FitBounds(f.minX, f.minY, f.maxX, f.maxY, margin)
+	f.internalGenerate(f.drawTo, f.moveTo)
+	f.flush()
+	// the branches are outlined, tapering as they get deeper
+	f.ctx.SetFillColor(f.ink)
+	f.ctx.Fill()
+	if !f.plotted {
+		f.ctx.Pop()
+		return
+	}
+	// plotters skip fills, so the centre lines are stroked too, thin enough
+	// for the fill to hide them
+	f.ctx.SetStrokeColor(f.ink)
+	f.ctx.SetLineCap(gart.CapButt)
+	f.ctx.SetLineJoin(gart.JoinRound)
+	f.ctx.SetStrokeWidth(f.thinnest / 2)
+	for _, xy := range f.lines {
+		f.ctx.Polyline(xy...)
+	}
 	f.ctx.Stroke()
+	f.ctx.Pop()
 }
 
 func (f *fractal) getLimits(s turtle, _ int) {
@@ -220,9 +274,10 @@ func (f *fractal) getLimits(s turtle, _ int) {
 	f.maxY = math.Max(s.y, f.maxY)
 }
 
-func (f *fractal) internalGenerate(drawTo func(turtle, int)) {
+func (f *fractal) internalGenerate(drawTo, moveTo func(turtle, int)) {
 	// Turtle starts facing up
 	s := turtle{angle: gart.Radians(f.lsys.startAngle)}
+	moveTo(s, 0)
 	for _, c := range f.sequence {
 		switch c {
 		case 'f', 'F', 'G', 'g': // move forward
@@ -239,33 +294,31 @@ func (f *fractal) internalGenerate(drawTo func(turtle, int)) {
 		case ']': // pop!
 			s = f.stack[len(f.stack)-1]
 			f.stack = f.stack[:len(f.stack)-1]
-			f.moveTo(s, len(f.stack))
+			moveTo(s, len(f.stack))
 		}
 	}
-	f.ctx.Close()
-}
-
-func boundTo(foundMin, foundMax, canvasMin, canvasMax, x float64) float64 {
-	return canvasMin + (x-foundMin)*(canvasMax-canvasMin)/(foundMax-foundMin)
-}
-
-func (f *fractal) normX(x float64) float64 {
-	return boundTo(f.minX, f.maxX, 0, float64(f.width), x)
-}
-
-func (f *fractal) normY(y float64) float64 {
-	return boundTo(f.minY, f.maxY, 0, float64(f.height), y)
 }
 
 func (f *fractal) drawTo(s turtle, depth int) {
-	f.ctx.SetStrokeWidth(f.lsys.lineWidth(depth, f.lsys.depth))
-	x, y := f.normX(s.x), f.normY(s.y)
-	f.ctx.LineTo(x, y)
-	f.ctx.Stroke()
-	f.ctx.MoveTo(x, y)
+	f.xy = append(f.xy, s.x, s.y)
+	w := f.lsys.lineWidth(depth, f.lsys.depth)
+	f.widths = append(f.widths, w)
+	f.thinnest = math.Min(f.thinnest, w)
 }
 
 func (f *fractal) moveTo(s turtle, depth int) {
-	f.ctx.Close()
-	f.ctx.MoveTo(f.normX(s.x), f.normY(s.y))
+	f.flush()
+	f.drawTo(s, depth)
+}
+
+// flush adds the outline of the branch drawn so far to the path
+func (f *fractal) flush() {
+	if len(f.widths) > 1 {
+		widths := f.widths
+		f.ctx.TaperedPolyline(func(i int) float64 { return widths[i] }, f.xy...)
+		if f.plotted {
+			f.lines = append(f.lines, append([]float64(nil), f.xy...))
+		}
+	}
+	f.xy, f.widths = f.xy[:0], f.widths[:0]
 }
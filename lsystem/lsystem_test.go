@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+	"github.com/scottkirkwood/gart/garttest"
+)
+
+func TestDrawFitsPage(t *testing.T) {
+	const eps = 1e-6
+	for _, lsys := range lsystems {
+		width, height := paperFlag.Width, paperFlag.Height
+		rec := gart.NewRecorder(width, height)
+		f := initFractal(rec, lsys, color.Black)
+		f.plotted = true
+		f.generate()
+		f.draw()
+
+		if rec.Count("TaperedPolyline") == 0 || rec.Count("Fill") != 1 {
+			t.Errorf("%s: the branches weren't outlined and filled", lsys.name)
+		}
+		if rec.Count("Polyline") != rec.Count("TaperedPolyline") || rec.Count("Stroke") != 1 {
+			t.Errorf("%s: the centre lines weren't stroked for plotters", lsys.name)
+		}
+		minX, minY, maxX, maxY := rec.Bounds()
+		if minX < margin-eps || minY < margin-eps || maxX > width-margin+eps || maxY > height-margin+eps {
+			t.Errorf("%s: bounds %.1f,%.1f %.1f,%.1f outside the margins", lsys.name, minX, minY, maxX, maxY)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	ctx := newContext(color.Black)
+	f := initFractal(ctx, lsystems[len(lsystems)-2], color.Black)
+	f.generate()
+	f.draw()
+	garttest.Golden(t, "lsystem", ctx, garttest.DefaultOptions)
+}
+
+func TestCentreLinesOnlyForPlotters(t *testing.T) {
+	if plotted([]string{".png", ".svg"}) || !plotted([]string{".png", ".hpgl"}) {
+		t.Error("plotted should be true only with a plotter format")
+	}
+	rec := gart.NewRecorder(paperFlag.Width, paperFlag.Height)
+	f := initFractal(rec, lsystems[0], color.Black)
+	f.generate()
+	f.draw()
+	if n := rec.Count("Polyline") + rec.Count("Stroke"); n != 0 {
+		t.Errorf("stroked %d centre lines without a plotter format", n)
+	}
+}
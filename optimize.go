@@ -0,0 +1,444 @@
+package gart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+)
+
+// OptimizeOptions configures Optimize.
+type OptimizeOptions struct {
+	Tolerance float64 // mm, points closer than this are treated as the same point
+	Dedupe    bool    // remove segments drawn more than once or lying on top of another
+	Reorder   bool    // draw the closest path next, reversing it if its end is closer
+	TwoOpt    int     // how many paths ahead the 2-opt pass looks for a better order, 0 to skip it
+}
+
+// DefaultOptimizeOptions are used by DefaultPlotterOptions.
+var DefaultOptimizeOptions = OptimizeOptions{
+	Tolerance: 0.01,
+	Dedupe:    true,
+	Reorder:   true,
+	TwoOpt:    50,
+}
+
+// OptimizeStats reports what Optimize did.
+type OptimizeStats struct {
+	PathsBefore, PathsAfter int
+	SegmentsRemoved         int     // including dots drawn more than once
+	Dots                    int     // paths shorter than the tolerance, kept as pen down dots
+	PenUpBefore, PenUpAfter float64 // mm travelled with the pen up
+}
+
+func (s OptimizeStats) String() string {
+	return fmt.Sprintf("%d paths into %d (%d segments removed, %d dots), pen up %.0fmm -> %.0fmm",
+		s.PathsBefore, s.PathsAfter, s.SegmentsRemoved, s.Dots, s.PenUpBefore, s.PenUpAfter)
+}
+
+// PenUpDistance returns how far the pen travels between the paths, starting at 0,0.
+func PenUpDistance(paths []PlotPath) float64 {
+	dist := 0.0
+	cur := canvas.Point{}
+	for _, pp := range paths {
+		dist += cur.Sub(pp.Start()).Length()
+		cur = pp.End()
+	}
+	return dist
+}
+
+// Optimize rearranges `paths` to cut the time a plotter spends with the pen up.
+// Segments are joined into the longest polylines they make, then, depending on
+// `opts`, duplicates are removed and the paths are reordered.
+// Paths of different colors are never joined and each color is drawn
+// in one go, in the order the colors were first used.
+// Paths shorter than the tolerance, like stipples, are kept as dots:
+// a single point the pen goes down on.
+func Optimize(paths []PlotPath, opts OptimizeOptions) ([]PlotPath, OptimizeStats) {
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 1e-6
+	}
+	stats := OptimizeStats{PathsBefore: len(paths), PenUpBefore: PenUpDistance(paths)}
+	var out []PlotPath
+	cur := canvas.Point{}
+	for _, group := range groupByColor(paths) {
+		lines, dots := splitDots(group, opts.Tolerance)
+		segs := toSegments(lines)
+		if opts.Dedupe {
+			n := len(segs) + len(dots)
+			segs = dedupe(segs, opts.Tolerance)
+			dots = dedupeDots(dots, segs, opts.Tolerance)
+			stats.SegmentsRemoved += n - len(segs) - len(dots)
+		}
+		stats.Dots += len(dots)
+		joined := append(joinSegments(segs, group[0].Color, opts.Tolerance), dots...)
+		if opts.Reorder {
+			joined = reorder(joined, cur)
+			twoOpt(joined, cur, opts.TwoOpt)
+		}
+		if len(joined) > 0 {
+			cur = joined[len(joined)-1].End()
+		}
+		out = append(out, joined...)
+	}
+	stats.PathsAfter = len(out)
+	stats.PenUpAfter = PenUpDistance(out)
+	return out, stats
+}
+
+func groupByColor(paths []PlotPath) [][]PlotPath {
+	var groups [][]PlotPath
+	index := map[color.RGBA]int{}
+	for _, pp := range paths {
+		i, ok := index[pp.Color]
+		if !ok {
+			i = len(groups)
+			index[pp.Color] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], pp)
+	}
+	return groups
+}
+
+// splitDots separates the paths that stay within `tolerance` of their start,
+// returned as dots with a single point, from the others.
+func splitDots(paths []PlotPath, tolerance float64) (lines, dots []PlotPath) {
+	for _, pp := range paths {
+		if len(pp.Points) == 0 {
+			continue
+		}
+		dot := true
+		for _, p := range pp.Points[1:] {
+			if p.Sub(pp.Start()).Length() >= tolerance {
+				dot = false
+				break
+			}
+		}
+		if dot {
+			dots = append(dots, PlotPath{Points: []canvas.Point{pp.Start()}, Color: pp.Color})
+		} else {
+			lines = append(lines, pp)
+		}
+	}
+	return lines, dots
+}
+
+// dedupeDots drops the dots drawn more than once or on the end of a segment.
+func dedupeDots(dots []PlotPath, segs []segment, tolerance float64) []PlotPath {
+	type key struct{ x, y int64 }
+	toKey := func(p canvas.Point) key {
+		return key{int64(math.Round(p.X / tolerance)), int64(math.Round(p.Y / tolerance))}
+	}
+	seen := map[key]bool{}
+	for _, s := range segs {
+		seen[toKey(s.a)] = true
+		seen[toKey(s.b)] = true
+	}
+	var out []PlotPath
+	for _, d := range dots {
+		if k := toKey(d.Start()); !seen[k] {
+			seen[k] = true
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+type segment struct {
+	a, b canvas.Point
+}
+
+func toSegments(paths []PlotPath) []segment {
+	var segs []segment
+	for _, pp := range paths {
+		for i := 1; i < len(pp.Points); i++ {
+			segs = append(segs, segment{pp.Points[i-1], pp.Points[i]})
+		}
+	}
+	return segs
+}
+
+// dedupe merges collinear segments that overlap, which also drops exact
+// duplicates and segments shorter than `tolerance`.
+func dedupe(segs []segment, tolerance float64) []segment {
+	const angleTolerance = 1e-4 // radians
+	type line struct {
+		dir    canvas.Point // unit direction, pointing right (or up)
+		offset float64      // signed distance of the line from the origin
+	}
+	type interval struct{ t0, t1 float64 }
+	type key struct{ angle, offset int64 }
+
+	lines := map[key]line{}
+	intervals := map[key][]interval{}
+	var keys []key // in the order first seen so the output is stable
+	for _, s := range segs {
+		d := s.b.Sub(s.a)
+		length := d.Length()
+		if length < tolerance {
+			continue
+		}
+		d = d.Div(length)
+		if d.X < 0 || (d.X == 0 && d.Y < 0) {
+			d = d.Neg()
+		}
+		offset := d.PerpDot(s.a)
+		k := key{int64(math.Round(math.Atan2(d.Y, d.X) / angleTolerance)), int64(math.Round(offset / tolerance))}
+		if _, ok := lines[k]; !ok {
+			lines[k] = line{d, offset}
+			keys = append(keys, k)
+		}
+		l := lines[k]
+		t0, t1 := l.dir.Dot(s.a), l.dir.Dot(s.b)
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		intervals[k] = append(intervals[k], interval{t0, t1})
+	}
+
+	var out []segment
+	for _, k := range keys {
+		l, ivs := lines[k], intervals[k]
+		sort.Slice(ivs, func(i, j int) bool { return ivs[i].t0 < ivs[j].t0 })
+		normal := canvas.Point{X: -l.dir.Y, Y: l.dir.X}
+		at := func(t float64) canvas.Point {
+			return l.dir.Mul(t).Add(normal.Mul(l.offset))
+		}
+		merged := ivs[0]
+		for _, iv := range ivs[1:] {
+			if iv.t0 <= merged.t1+tolerance {
+				merged.t1 = math.Max(merged.t1, iv.t1)
+				continue
+			}
+			out = append(out, segment{at(merged.t0), at(merged.t1)})
+			merged = iv
+		}
+		out = append(out, segment{at(merged.t0), at(merged.t1)})
+	}
+	return out
+}
+
+// joinSegments chains segments that share end points into polylines.
+func joinSegments(segs []segment, col color.RGBA, tolerance float64) []PlotPath {
+	type key struct{ x, y int64 }
+	toKey := func(p canvas.Point) key {
+		return key{int64(math.Round(p.X / tolerance)), int64(math.Round(p.Y / tolerance))}
+	}
+	ends := map[key][]int{}
+	for i, s := range segs {
+		ends[toKey(s.a)] = append(ends[toKey(s.a)], i)
+		ends[toKey(s.b)] = append(ends[toKey(s.b)], i)
+	}
+	used := make([]bool, len(segs))
+	// next returns the other end of an unused segment touching p
+	next := func(p canvas.Point) (canvas.Point, bool) {
+		k := toKey(p)
+		list := ends[k]
+		for len(list) > 0 {
+			i := list[len(list)-1]
+			list = list[:len(list)-1]
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			ends[k] = list
+			if toKey(segs[i].a) == k {
+				return segs[i].b, true
+			}
+			return segs[i].a, true
+		}
+		ends[k] = list
+		return canvas.Point{}, false
+	}
+
+	var paths []PlotPath
+	for i, s := range segs {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		forward := []canvas.Point{s.a, s.b}
+		for p, ok := next(s.b); ok; p, ok = next(p) {
+			forward = append(forward, p)
+		}
+		var backward []canvas.Point
+		for p, ok := next(s.a); ok; p, ok = next(p) {
+			backward = append(backward, p)
+		}
+		points := make([]canvas.Point, 0, len(backward)+len(forward))
+		for j := len(backward) - 1; j >= 0; j-- {
+			points = append(points, backward[j])
+		}
+		points = append(points, forward...)
+		paths = append(paths, PlotPath{Points: points, Color: col})
+	}
+	return paths
+}
+
+// reorder picks the path with the closest end to the pen each time,
+// reversing it when it's closer to start from its end.
+func reorder(paths []PlotPath, cur canvas.Point) []PlotPath {
+	grid := newEndGrid(paths)
+	out := make([]PlotPath, 0, len(paths))
+	for len(out) < len(paths) {
+		i, atEnd := grid.nearest(cur)
+		pp := paths[i]
+		if atEnd {
+			pp.reverse()
+		}
+		out = append(out, pp)
+		cur = pp.End()
+	}
+	return out
+}
+
+// twoOpt improves the order by reversing runs of up to `window` paths
+// when that shortens the pen up moves around them.
+func twoOpt(paths []PlotPath, start canvas.Point, window int) {
+	const maxPasses = 10
+	dist := func(a, b canvas.Point) float64 { return a.Sub(b).Length() }
+	for pass, improved := 0, true; improved && pass < maxPasses; pass++ {
+		improved = false
+		for i := range paths {
+			prev := start
+			if i > 0 {
+				prev = paths[i-1].End()
+			}
+			for j := i; j < len(paths) && j < i+window; j++ {
+				before := dist(prev, paths[i].Start())
+				after := dist(prev, paths[j].End())
+				if j+1 < len(paths) {
+					next := paths[j+1].Start()
+					before += dist(paths[j].End(), next)
+					after += dist(paths[i].Start(), next)
+				}
+				if after < before-1e-9 {
+					reversePaths(paths[i : j+1])
+					improved = true
+				}
+			}
+		}
+	}
+}
+
+// reversePaths reverses the order of the paths and each path, so they are drawn backwards.
+func reversePaths(paths []PlotPath) {
+	for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+		paths[i], paths[j] = paths[j], paths[i]
+	}
+	for i := range paths {
+		paths[i].reverse()
+	}
+}
+
+func (pp *PlotPath) reverse() {
+	for i, j := 0, len(pp.Points)-1; i < j; i, j = i+1, j-1 {
+		pp.Points[i], pp.Points[j] = pp.Points[j], pp.Points[i]
+	}
+}
+
+// endGrid buckets the path ends so the nearest unused one is quick to find.
+type endGrid struct {
+	paths      []PlotPath
+	used       []bool
+	cell       float64
+	minX, minY float64
+	cols, rows int
+	cells      map[[2]int][]int // path index*2, +1 for its end
+}
+
+func newEndGrid(paths []PlotPath) *endGrid {
+	g := &endGrid{paths: paths, used: make([]bool, len(paths)), cells: map[[2]int][]int{}}
+	if len(paths) == 0 {
+		return g
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, pp := range paths {
+		for _, p := range []canvas.Point{pp.Start(), pp.End()} {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	// about one path per cell on a page, and at most sqrt(n) cells along a line
+	g.cell = math.Max(math.Max(maxX-minX, maxY-minY)/math.Sqrt(float64(len(paths))), 1e-3)
+	g.minX, g.minY = minX, minY
+	g.cols, g.rows = g.cellOf(canvas.Point{X: maxX, Y: maxY})
+	for i, pp := range paths {
+		g.add(pp.Start(), 2*i)
+		g.add(pp.End(), 2*i+1)
+	}
+	return g
+}
+
+func (g *endGrid) cellOf(p canvas.Point) (int, int) {
+	return int((p.X - g.minX) / g.cell), int((p.Y - g.minY) / g.cell)
+}
+
+func (g *endGrid) add(p canvas.Point, id int) {
+	cx, cy := g.cellOf(p)
+	g.cells[[2]int{cx, cy}] = append(g.cells[[2]int{cx, cy}], id)
+}
+
+// nearest returns the unused path with an end closest to p, and marks it used.
+func (g *endGrid) nearest(p canvas.Point) (int, bool) {
+	// Start from the closest cell in the grid, for any end e in the grid
+	// |p-e| >= |clamped p - e| so the rings still bound the distance.
+	cx, cy := g.cellOf(p)
+	cx, cy = ClampInt(cx, 0, g.cols), ClampInt(cy, 0, g.rows)
+	best, bestDist := -1, math.Inf(1)
+	maxRing := g.cols + g.rows + 1
+	for r := 0; r <= maxRing; r++ {
+		// the cells in ring r are at least (r-1) cells away
+		if best >= 0 && bestDist <= float64(r-1)*g.cell {
+			break
+		}
+		for x := cx - r; x <= cx+r; x++ {
+			step := 2 * r // only the top and bottom rows, unless on the sides
+			if x == cx-r || x == cx+r || r == 0 {
+				step = 1
+			}
+			for y := cy - r; y <= cy+r; y += step {
+				if id, d := g.nearestIn([2]int{x, y}, p); d < bestDist {
+					best, bestDist = id, d
+				}
+			}
+		}
+	}
+	g.used[best/2] = true
+	return best / 2, best%2 == 1
+}
+
+// nearestIn returns the closest unused end in cell `k`, dropping the used ones.
+func (g *endGrid) nearestIn(k [2]int, p canvas.Point) (int, float64) {
+	ids, ok := g.cells[k]
+	if !ok {
+		return -1, math.Inf(1)
+	}
+	best, bestDist := -1, math.Inf(1)
+	for n := 0; n < len(ids); {
+		id := ids[n]
+		if g.used[id/2] {
+			ids[n] = ids[len(ids)-1]
+			ids = ids[:len(ids)-1]
+			continue
+		}
+		end := g.paths[id/2].Start()
+		if id%2 == 1 {
+			end = g.paths[id/2].End()
+		}
+		if d := p.Sub(end).Length(); d < bestDist {
+			best, bestDist = id, d
+		}
+		n++
+	}
+	if len(ids) == 0 {
+		delete(g.cells, k)
+	} else {
+		g.cells[k] = ids
+	}
+	return best, bestDist
+}
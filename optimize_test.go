@@ -0,0 +1,113 @@
+package gart
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+func line(col color.RGBA, xy ...float64) PlotPath {
+	pp := PlotPath{Color: col}
+	for i := 0; i+1 < len(xy); i += 2 {
+		pp.Points = append(pp.Points, canvas.Point{X: xy[i], Y: xy[i+1]})
+	}
+	return pp
+}
+
+func TestOptimize(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	tests := []struct {
+		name            string
+		paths           []PlotPath
+		wantPaths       int
+		wantRemoved     int
+		wantDots        int
+		wantPenUpBefore float64
+		wantPenUpAfter  float64
+	}{
+		{
+			name: "joins segments stroked one at a time",
+			paths: []PlotPath{
+				line(black, 0, 0, 10, 0),
+				line(black, 10, 0, 10, 10),
+				line(black, 10, 10, 0, 10),
+			},
+			wantPaths:       1,
+			wantPenUpBefore: 0,
+			wantPenUpAfter:  0,
+		},
+		{
+			name: "removes duplicates and overlaps",
+			paths: []PlotPath{
+				line(black, 0, 0, 10, 0),
+				line(black, 10, 0, 0, 0),  // backwards
+				line(black, 2, 0, 5, 0),   // on top
+				line(black, 10, 0, 10, 0), // a dot on its end
+			},
+			wantPaths:       1,
+			wantRemoved:     3,
+			wantPenUpBefore: 2 + 5,
+			wantPenUpAfter:  0,
+		},
+		{
+			name: "keeps dots",
+			paths: []PlotPath{
+				line(black, 0, 0, 10, 0),
+				line(black, 20, 0),
+				line(black, 30, 0, 30.001, 0), // shorter than the tolerance
+				line(black, 20, 0, 20, 0),     // the same dot again
+			},
+			wantPaths:       3,
+			wantRemoved:     1,
+			wantDots:        2,
+			wantPenUpBefore: 10 + 10 + 10.001,
+			wantPenUpAfter:  10 + 10,
+		},
+		{
+			name: "reorders and reverses",
+			paths: []PlotPath{
+				line(black, 0, 0, 1, 0),
+				line(black, 100, 0, 101, 0),
+				line(black, 3, 0, 2, 0),
+			},
+			wantPaths:       3,
+			wantPenUpBefore: 99 + 98,
+			wantPenUpAfter:  1 + 97,
+		},
+		{
+			name: "keeps colors apart",
+			paths: []PlotPath{
+				line(black, 0, 0, 10, 0),
+				line(red, 10, 0, 20, 0),
+				line(black, 20, 0, 30, 0),
+			},
+			wantPaths:       3,
+			wantPenUpBefore: 0,
+			wantPenUpAfter:  10 + 10, // red is reversed
+		},
+	}
+	for _, tt := range tests {
+		got, stats := Optimize(tt.paths, DefaultOptimizeOptions)
+		if len(got) != tt.wantPaths || stats.PathsAfter != tt.wantPaths {
+			t.Errorf("%s: %d paths, want %d", tt.name, len(got), tt.wantPaths)
+		}
+		if stats.SegmentsRemoved != tt.wantRemoved {
+			t.Errorf("%s: %d segments removed, want %d", tt.name, stats.SegmentsRemoved, tt.wantRemoved)
+		}
+		if stats.Dots != tt.wantDots {
+			t.Errorf("%s: %d dots, want %d", tt.name, stats.Dots, tt.wantDots)
+		}
+		for _, pp := range got {
+			if len(pp.Points) == 0 {
+				t.Errorf("%s: a path without points", tt.name)
+			}
+		}
+		if math.Abs(stats.PenUpBefore-tt.wantPenUpBefore) > 1e-6 || math.Abs(stats.PenUpAfter-tt.wantPenUpAfter) > 1e-6 {
+			t.Errorf("%s: pen up %.2f -> %.2f, want %.2f -> %.2f", tt.name,
+				stats.PenUpBefore, stats.PenUpAfter, tt.wantPenUpBefore, tt.wantPenUpAfter)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package gart
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Paper is a sheet size, the presets are portrait.
+type Paper struct {
+	Name          string
+	Width, Height float64 // mm
+}
+
+// Paper presets, see ParsePaper
+var (
+	Letter  = Paper{"letter", 215.9, 279.4}
+	Legal   = Paper{"legal", 215.9, 355.6}
+	Tabloid = Paper{"tabloid", 279.4, 431.8}
+	A0      = Paper{"a0", 841, 1189}
+	A1      = Paper{"a1", 594, 841}
+	A2      = Paper{"a2", 420, 594}
+	A3      = Paper{"a3", 297, 420}
+	A4      = Paper{"a4", 210, 297}
+	A5      = Paper{"a5", 148, 210}
+	A6      = Paper{"a6", 105, 148}
+
+	Square8in  = Paper{"square8in", 203.2, 203.2}
+	Square12in = Paper{"square12in", 304.8, 304.8}
+	Square20cm = Paper{"square20cm", 200, 200}
+	Square30cm = Paper{"square30cm", 300, 300}
+)
+
+var papers = map[string]Paper{}
+
+func init() {
+	for _, p := range []Paper{Letter, Legal, Tabloid, A0, A1, A2, A3, A4, A5, A6, Square8in, Square12in, Square20cm, Square30cm} {
+		papers[p.Name] = p
+	}
+}
+
+// PaperNames returns the sorted names of the presets.
+func PaperNames() []string {
+	names := make([]string, 0, len(papers))
+	for name := range papers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const (
+	landscapeSuffix = "-landscape"
+	portraitSuffix  = "-portrait"
+)
+
+// Landscape returns the paper turned so it's wider than it is tall.
+func (p Paper) Landscape() Paper {
+	if p.Width >= p.Height {
+		return p
+	}
+	if p.Name != "" {
+		p.Name = strings.TrimSuffix(p.Name, portraitSuffix) + landscapeSuffix
+	}
+	return Paper{p.Name, p.Height, p.Width}
+}
+
+// Portrait returns the paper turned so it's taller than it is wide.
+func (p Paper) Portrait() Paper {
+	if p.Height >= p.Width {
+		return p
+	}
+	return Paper{strings.TrimSuffix(p.Name, landscapeSuffix), p.Height, p.Width}
+}
+
+func (p Paper) String() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%sx%smm", formatNum(p.Width), formatNum(p.Height))
+}
+
+// Set parses the paper for a flag, see PaperFlag.
+func (p *Paper) Set(s string) error {
+	paper, err := ParsePaper(s)
+	if err != nil {
+		return err
+	}
+	*p = paper
+	return nil
+}
+
+// ParsePaper returns the paper for a preset name, like "a4" or "letter",
+// or a size like "300x200mm", "30x20cm" or "8.5x11in".
+// Adding "-landscape" or "-portrait" turns it, ex. "a4-landscape".
+func ParsePaper(s string) (Paper, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	turn := func(p Paper) Paper { return p }
+	switch {
+	case strings.HasSuffix(s, landscapeSuffix):
+		s, turn = strings.TrimSuffix(s, landscapeSuffix), Paper.Landscape
+	case strings.HasSuffix(s, portraitSuffix):
+		s, turn = strings.TrimSuffix(s, portraitSuffix), Paper.Portrait
+	}
+	if p, ok := papers[s]; ok {
+		return turn(p), nil
+	}
+
+	unit := MM
+	for suffix, u := range map[string]Unit{"mm": MM, "cm": CM, "in": Inch} {
+		if strings.HasSuffix(s, suffix) {
+			s, unit = strings.TrimSuffix(s, suffix), u
+			break
+		}
+	}
+	wh := strings.Split(s, "x")
+	if len(wh) != 2 {
+		return Paper{}, fmt.Errorf("unknown paper %q, want one of %s or a size like 300x200mm", s, strings.Join(PaperNames(), ", "))
+	}
+	w, err := strconv.ParseFloat(wh[0], 64)
+	if err != nil {
+		return Paper{}, fmt.Errorf("bad paper width %q: %v", wh[0], err)
+	}
+	h, err := strconv.ParseFloat(wh[1], 64)
+	if err != nil {
+		return Paper{}, fmt.Errorf("bad paper height %q: %v", wh[1], err)
+	}
+	if w <= 0 || h <= 0 {
+		return Paper{}, fmt.Errorf("paper %q has no area", s)
+	}
+	return turn(Paper{Width: w * float64(unit), Height: h * float64(unit)}), nil
+}
+
+// PaperFlag defines a -paper flag, defaulting to `def`, for sketches to pass to NewPage.
+func PaperFlag(def Paper) *Paper {
+	p := def
+	flag.Var(&p, "paper", "Paper size, one of "+strings.Join(PaperNames(), ", ")+
+		", or a size like 300x200mm or 8.5x11in, with -landscape to turn it")
+	return &p
+}
+
+// Unit is how many mm are in one unit of a sketch's coordinates.
+type Unit float64
+
+// The usual units, see also Pixels
+const (
+	MM   Unit = 1
+	CM   Unit = 10
+	Inch Unit = 25.4
+	Pt   Unit = 25.4 / 72 // typographic points
+)
+
+// Pixels returns the unit for coordinates in pixels at `dpi` dots per inch.
+func Pixels(dpi float64) Unit {
+	return Unit(25.4 / dpi)
+}
+
+// PageOptions sets up a Context with NewPage.
+type PageOptions struct {
+	Paper  Paper
+	Margin float64 // on every side, in Units
+	Units  Unit    // of the sketch's coordinates, MM when 0
+}
+
+func (opts PageOptions) unit() float64 {
+	if opts.Units <= 0 {
+		return 1
+	}
+	return float64(opts.Units)
+}
+
+// Size returns the paper size in Units.
+func (opts PageOptions) Size() (width, height float64) {
+	return opts.Paper.Width / opts.unit(), opts.Paper.Height / opts.unit()
+}
+
+// Inner returns the rectangle inside the margins in Units, which is where
+// the sketch should draw.
+func (opts PageOptions) Inner() (x, y, w, h float64) {
+	width, height := opts.Size()
+	return opts.Margin, opts.Margin, width - 2*opts.Margin, height - 2*opts.Margin
+}
+
+// NewPage returns a Context the size of the paper, drawn on in Units.
+// Size still returns mm, the Units are a scale that ResetMatrix goes back to.
+func NewPage(opts PageOptions) *Context {
+	ctx := NewContext(opts.Paper.Width, opts.Paper.Height)
+	ctx.setUnit(opts.unit())
+	return ctx
+}
+
+// NewPageRecorder is NewPage for a Recorder, the Units are saved with it.
+func NewPageRecorder(opts PageOptions) *Recorder {
+	r := NewRecorder(opts.Paper.Width, opts.Paper.Height)
+	r.setUnit(opts.unit())
+	return r
+}
+
+// NewPageDrawer is NewPage for any backend, see NewDrawer.
+func NewPageDrawer(backend string, opts PageOptions) (Drawer, error) {
+	d, err := NewDrawer(backend, opts.Paper.Width, opts.Paper.Height)
+	if err != nil {
+		return nil, err
+	}
+	switch d := d.(type) {
+	case *Context:
+		d.setUnit(opts.unit())
+	case *GGContext:
+		d.setUnit(opts.unit())
+	}
+	return d, nil
+}
@@ -0,0 +1,81 @@
+package gart
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+func TestParsePaper(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Paper
+	}{
+		{"letter", Letter},
+		{" A4 ", A4},
+		{"a4-landscape", Paper{"a4-landscape", 297, 210}},
+		{"a4-portrait", A4},
+		{"300x200mm", Paper{"", 300, 200}},
+		{"30x20cm-portrait", Paper{"", 200, 300}},
+		{"8.5x11in", Paper{"", 215.9, 279.4}},
+		{"100x100", Paper{"", 100, 100}},
+	}
+	for _, test := range tests {
+		got, err := ParsePaper(test.in)
+		if err != nil {
+			t.Errorf("ParsePaper(%q) failed: %v", test.in, err)
+			continue
+		}
+		if got.Name != test.want.Name || math.Abs(got.Width-test.want.Width) > 1e-9 || math.Abs(got.Height-test.want.Height) > 1e-9 {
+			t.Errorf("ParsePaper(%q) = %+v, want %+v", test.in, got, test.want)
+		}
+	}
+	for _, in := range []string{"b5", "10x", "0x10mm", "axbin"} {
+		if _, err := ParsePaper(in); err == nil {
+			t.Errorf("ParsePaper(%q) should fail", in)
+		}
+	}
+}
+
+func TestPageUnits(t *testing.T) {
+	page := PageOptions{Paper: Letter, Margin: 0.5, Units: Inch}
+	if w, h := page.Size(); math.Abs(w-8.5) > 1e-9 || math.Abs(h-11) > 1e-9 {
+		t.Errorf("Size() = %v x %v inches, want 8.5 x 11", w, h)
+	}
+	if x, y, w, h := page.Inner(); x != 0.5 || y != 0.5 || math.Abs(w-7.5) > 1e-9 || math.Abs(h-10) > 1e-9 {
+		t.Errorf("Inner() = %v,%v %vx%v, want 0.5,0.5 7.5x10", x, y, w, h)
+	}
+
+	ctx := NewPage(page)
+	ctx.Translate(1, 2)
+	ctx.ResetMatrix()
+	if got := ctx.Matrix().Dot(canvas.Point{X: 1, Y: 1}); math.Abs(got.X-25.4) > 1e-9 || math.Abs(got.Y-25.4) > 1e-9 {
+		t.Errorf("1,1 inch is at %v mm, want 25.4,25.4", got)
+	}
+
+	// the same for a Recorder and what it replays to
+	rec := NewPageRecorder(page)
+	rec.Translate(1, 2)
+	rec.ResetMatrix()
+	rec.FitBounds(0, 0, 1, 1, 0)
+	if got := rec.Matrix().Dot(canvas.Point{X: 1, Y: 1}); math.Abs(got.X-215.9) > 1e-9 {
+		t.Errorf("Recorder: 1,1 is at %v mm after FitBounds, want x at 215.9", got)
+	}
+	var buf bytes.Buffer
+	if err := rec.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadRecorder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := loaded.Context()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := replayed.Matrix(), rec.Matrix(); got != want {
+		t.Errorf("replayed matrix = %v, want %v", got, want)
+	}
+}
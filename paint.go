@@ -0,0 +1,587 @@
+package gart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/svg"
+	"golang.org/x/image/draw"
+)
+
+// Paint is what fills or strokes a path instead of a flat color, see
+// SetFillPaint. x,y are in the coordinates the path was drawn in, so a paint
+// moves with the transformation.
+// LinearGradient, RadialGradient and Pattern are written natively to SVG and
+// PDF, any other Paint is rasterized there, at paintResolution for PDF. EPS
+// gets a flat color standing in for each.
+type Paint interface {
+	At(x, y float64) color.Color
+}
+
+// ColorSpace is what a gradient's colors are mixed in.
+type ColorSpace int
+
+const (
+	SRGB      ColorSpace = iota // as SVG does by default, can look muddy in the middle
+	LinearRGB                   // physically mixing light, brighter in the middle
+	Lab                         // perceptually even steps
+	HCL                         // Lab going around the hue wheel, keeps the colors saturated
+)
+
+// Stop is a color at Offset, from 0 to 1, along a gradient.
+type Stop struct {
+	Offset float64
+	Color  color.Color
+}
+
+// EvenStops spreads `colors` evenly from 0 to 1.
+func EvenStops(colors ...color.Color) []Stop {
+	stops := make([]Stop, len(colors))
+	for i, col := range colors {
+		if len(colors) > 1 {
+			stops[i].Offset = float64(i) / float64(len(colors)-1)
+		}
+		stops[i].Color = col
+	}
+	return stops
+}
+
+// Gradient is the colors going from 0 to 1 of the linear and radial gradients.
+type Gradient struct {
+	Stops []Stop // in order of Offset
+	Space ColorSpace
+}
+
+// ColorAt returns the color at `t`, the ends carry on past 0 and 1.
+func (g Gradient) ColorAt(t float64) color.NRGBA {
+	if len(g.Stops) == 0 {
+		return color.NRGBA{}
+	}
+	if t <= g.Stops[0].Offset {
+		return toNRGBA(g.Stops[0].Color)
+	}
+	for i := 1; i < len(g.Stops); i++ {
+		a, b := g.Stops[i-1], g.Stops[i]
+		if t > b.Offset {
+			continue
+		}
+		if b.Offset <= a.Offset {
+			return toNRGBA(b.Color)
+		}
+		return mixColors(toNRGBA(a.Color), toNRGBA(b.Color), (t-a.Offset)/(b.Offset-a.Offset), g.Space)
+	}
+	return toNRGBA(g.Stops[len(g.Stops)-1].Color)
+}
+
+func toNRGBA(col color.Color) color.NRGBA {
+	return color.NRGBAModel.Convert(col).(color.NRGBA)
+}
+
+// mixColors goes from `a` at 0 to `b` at 1 in `space`, the alpha is always mixed linearly.
+func mixColors(a, b color.NRGBA, t float64, space ColorSpace) color.NRGBA {
+	ca := colorful.Color{R: float64(a.R) / 255, G: float64(a.G) / 255, B: float64(a.B) / 255}
+	cb := colorful.Color{R: float64(b.R) / 255, G: float64(b.G) / 255, B: float64(b.B) / 255}
+	var c colorful.Color
+	switch space {
+	case LinearRGB:
+		r0, g0, b0 := ca.LinearRgb()
+		r1, g1, b1 := cb.LinearRgb()
+		c = colorful.LinearRgb(Lerp(r0, r1, t), Lerp(g0, g1, t), Lerp(b0, b1, t))
+	case Lab:
+		c = ca.BlendLab(cb, t)
+	case HCL:
+		c = ca.BlendHcl(cb, t)
+	default:
+		c = ca.BlendRgb(cb, t)
+	}
+	r, g, bl := c.Clamped().RGB255()
+	return color.NRGBA{r, g, bl, uint8(Lerp(float64(a.A), float64(b.A), t) + 0.5)}
+}
+
+// LinearGradient goes from X0,Y0 to X1,Y1, it's the same along lines
+// perpendicular to that.
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float64
+	Gradient
+}
+
+// NewLinearGradient returns a gradient from x0,y0 to x1,y1 mixed in sRGB,
+// set its Space to change that.
+func NewLinearGradient(x0, y0, x1, y1 float64, stops ...Stop) LinearGradient {
+	return LinearGradient{x0, y0, x1, y1, Gradient{Stops: stops}}
+}
+
+func (g LinearGradient) At(x, y float64) color.Color {
+	dx, dy := g.X1-g.X0, g.Y1-g.Y0
+	t := 0.0
+	if d := dx*dx + dy*dy; d > 0 {
+		t = ((x-g.X0)*dx + (y-g.Y0)*dy) / d
+	}
+	return g.ColorAt(t)
+}
+
+// RadialGradient goes from the center CX,CY out to the circle of radius R.
+type RadialGradient struct {
+	CX, CY, R float64
+	Gradient
+}
+
+// NewRadialGradient returns a gradient out from cx,cy to `r` mixed in sRGB.
+func NewRadialGradient(cx, cy, r float64, stops ...Stop) RadialGradient {
+	return RadialGradient{cx, cy, r, Gradient{Stops: stops}}
+}
+
+func (g RadialGradient) At(x, y float64) color.Color {
+	if g.R <= 0 {
+		return g.ColorAt(1)
+	}
+	return g.ColorAt(math.Hypot(x-g.CX, y-g.CY) / g.R)
+}
+
+// Pattern repeats Image as tiles of Width x Height with one's bottom left at X,Y.
+type Pattern struct {
+	Image               image.Image
+	X, Y, Width, Height float64
+}
+
+// NewPattern returns a Pattern of `img` tiles that are width x height.
+func NewPattern(img image.Image, width, height float64) Pattern {
+	return Pattern{Image: img, Width: width, Height: height}
+}
+
+func (p Pattern) At(x, y float64) color.Color {
+	b := p.Image.Bounds()
+	if p.Width <= 0 || p.Height <= 0 || b.Empty() {
+		return color.Transparent
+	}
+	u := math.Mod(x-p.X, p.Width) / p.Width
+	v := math.Mod(y-p.Y, p.Height) / p.Height
+	if u < 0 {
+		u++
+	}
+	if v < 0 {
+		v++
+	}
+	px := ClampInt(b.Min.X+int(u*float64(b.Dx())), b.Min.X, b.Max.X-1)
+	py := ClampInt(b.Max.Y-1-int(v*float64(b.Dy())), b.Min.Y, b.Max.Y-1)
+	return p.Image.At(px, py)
+}
+
+// paintColor is a flat color standing in for `p`, so a painted path still
+// has a pen to be plotted with.
+func paintColor(p Paint) color.RGBA {
+	var col color.Color
+	switch p := p.(type) {
+	case LinearGradient:
+		col = p.ColorAt(0.5)
+	case RadialGradient:
+		col = p.ColorAt(0.5)
+	case Pattern:
+		col = p.At(p.X+p.Width/2, p.Y+p.Height/2)
+	default:
+		col = p.At(0, 0)
+	}
+	return color.RGBAModel.Convert(col).(color.RGBA)
+}
+
+// SetFillPaint fills with `p` until the next SetFillColor, nil goes back to the fill color.
+func (ctx *Context) SetFillPaint(p Paint) {
+	ctx.state.fill = p
+}
+
+// SetStrokePaint strokes with `p` until the next SetStrokeColor, nil goes
+// back to the stroke color.
+func (ctx *Context) SetStrokePaint(p Paint) {
+	ctx.state.stroke = p
+}
+
+// draw renders `path` with the current style, or as a paintImage when it has paints.
+func (ctx *Context) draw(path *canvas.Path, style canvas.Style, m canvas.Matrix, fill, stroke Paint) {
+	if fill == nil && stroke == nil {
+		ctx.ctx.RenderPath(path, style, m)
+		return
+	}
+	if fill != nil {
+		style.FillColor = paintColor(fill)
+	}
+	if stroke != nil {
+		style.StrokeColor = paintColor(stroke)
+	}
+	ctx.ctx.Renderer.RenderImage(paintImage{path.Copy(), style, m, fill, stroke}, Identity)
+}
+
+// paintImage carries a path drawn with paints through the canvas, like
+// groupImage. Its style has flat colors standing in for the paints.
+type paintImage struct {
+	path         *canvas.Path
+	style        canvas.Style
+	m            canvas.Matrix
+	fill, stroke Paint
+}
+
+func (pi paintImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (pi paintImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, 1, 1)
+}
+
+func (pi paintImage) At(x, y int) color.Color {
+	return color.RGBA64{}
+}
+
+func (pi paintImage) filled() bool {
+	return pi.fill != nil || pi.style.FillColor.A != 0
+}
+
+func (pi paintImage) stroked() bool {
+	return pi.style.StrokeWidth > 0 && (pi.stroke != nil || pi.style.StrokeColor.A != 0)
+}
+
+// rasterize draws the path onto a page of `size` pixels at `res` dots per mm,
+// returning the pixels it covers and where they go on the page, or nil.
+func (pi paintImage) rasterize(size image.Point, res float64, antialias bool) (*image.RGBA, image.Rectangle) {
+	path := pi.path.Transform(pi.m)
+	margin := 0.0
+	if pi.stroked() {
+		margin = pi.style.StrokeWidth
+	}
+	x0, y0, x1, y1 := pixelBox(path.Bounds(), margin, res, size)
+	if x1 <= x0 || y1 <= y0 {
+		return nil, image.Rectangle{}
+	}
+	w, h := x1-x0, y1-y0
+	path = path.Translate(-float64(x0)/res, -float64(y0)/res)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	source := func(p Paint, col color.RGBA) image.Image {
+		if p == nil {
+			return image.NewUniform(col)
+		}
+		return paintSource{p, pi.m.Inv(), res, x0, y1, img.Bounds()}
+	}
+	if pi.filled() {
+		mask := coverage(path, w, h, res, antialias)
+		draw.DrawMask(img, img.Bounds(), source(pi.fill, pi.style.FillColor), image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	if pi.stroked() {
+		mask := coverage(strokeOutline(path, pi.style), w, h, res, antialias)
+		draw.DrawMask(img, img.Bounds(), source(pi.stroke, pi.style.StrokeColor), image.Point{}, mask, image.Point{}, draw.Over)
+	}
+	return img, image.Rect(x0, size.Y-y1, x1, size.Y-y0)
+}
+
+// paintResolution is the dots per mm paints are rasterized at for the vector
+// formats that can't draw them.
+const paintResolution = 10
+
+// toImage returns the path rasterized, and its matrix, for a page of width x height mm.
+func (pi paintImage) toImage(width, height float64) (*image.RGBA, canvas.Matrix) {
+	res := float64(paintResolution)
+	size := image.Pt(int(width*res+0.5), int(height*res+0.5))
+	img, r := pi.rasterize(size, res, true)
+	return img, Identity.Translate(float64(r.Min.X)/res, float64(size.Y-r.Max.Y)/res).Scale(1/res, 1/res)
+}
+
+// paintSource is the image of a Paint on the pixels starting at column x0
+// and going down from row y1, counted up from the bottom of the page.
+type paintSource struct {
+	paint  Paint
+	inv    canvas.Matrix // from the page to the paint's coordinates
+	res    float64
+	x0, y1 int
+	bounds image.Rectangle
+}
+
+func (ps paintSource) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+func (ps paintSource) Bounds() image.Rectangle {
+	return ps.bounds
+}
+
+func (ps paintSource) At(x, y int) color.Color {
+	p := ps.inv.Dot(canvas.Point{X: (float64(ps.x0+x) + 0.5) / ps.res, Y: (float64(ps.y1-y) - 0.5) / ps.res})
+	return ps.paint.At(p.X, p.Y)
+}
+
+// Sentinel colors the paths are written with, replaced by the paint servers.
+var (
+	svgFillSentinel   = color.RGBA{0x01, 0x02, 0x03, 0xff}
+	svgStrokeSentinel = color.RGBA{0x04, 0x05, 0x06, 0xff}
+)
+
+// renderPaint writes the path with its paints as SVG gradients and patterns,
+// or as an image when a paint isn't one of those.
+func (s svgGroups) renderPaint(pi paintImage) {
+	w, h := s.SVG.Size()
+	view := Identity.ReflectYAbout(h / 2).Mul(pi.m)
+	var defs bytes.Buffer
+	style := pi.style
+	var urls []string
+	for _, p := range []struct {
+		paint    Paint
+		col      *color.RGBA
+		sentinel color.RGBA
+	}{{pi.fill, &style.FillColor, svgFillSentinel}, {pi.stroke, &style.StrokeColor, svgStrokeSentinel}} {
+		if p.paint == nil {
+			continue
+		}
+		*s.ids++
+		id := fmt.Sprintf("paint%d", *s.ids)
+		if !writeSVGPaint(&defs, id, p.paint, view) {
+			img, m := pi.toImage(w, h)
+			if img != nil {
+				s.SVG.RenderImage(img, m)
+			}
+			return
+		}
+		*p.col = p.sentinel
+		urls = append(urls, canvas.CSSColor(p.sentinel).String(), "url(#"+id+")")
+	}
+
+	var buf bytes.Buffer
+	svg.New(&buf, w, h).RenderPath(pi.path, svgStyle(style), pi.m)
+	out := buf.String()
+	out = out[strings.Index(out, ">")+1:] // the <svg> it starts with
+	fmt.Fprintf(s.w, "<defs>%s</defs>", defs.String())
+	io.WriteString(s.w, strings.NewReplacer(urls...).Replace(out))
+}
+
+// writeSVGPaint writes `p` as the paint server `id` with the transformation
+// `view`, or returns false if SVG has nothing for it.
+func writeSVGPaint(w io.Writer, id string, p Paint, view canvas.Matrix) bool {
+	transform := fmt.Sprintf("matrix(%s %s %s %s %s %s)", formatNum(view[0][0]), formatNum(view[1][0]),
+		formatNum(view[0][1]), formatNum(view[1][1]), formatNum(view[0][2]), formatNum(view[1][2]))
+	switch p := p.(type) {
+	case LinearGradient:
+		fmt.Fprintf(w, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s" gradientTransform="%s">`,
+			id, formatNum(p.X0), formatNum(p.Y0), formatNum(p.X1), formatNum(p.Y1), transform)
+		writeSVGStops(w, p.Gradient)
+		io.WriteString(w, `</linearGradient>`)
+	case RadialGradient:
+		fmt.Fprintf(w, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s" gradientTransform="%s">`,
+			id, formatNum(p.CX), formatNum(p.CY), formatNum(p.R), transform)
+		writeSVGStops(w, p.Gradient)
+		io.WriteString(w, `</radialGradient>`)
+	case Pattern:
+		var img bytes.Buffer
+		if p.Width <= 0 || p.Height <= 0 || png.Encode(&img, p.Image) != nil {
+			return false
+		}
+		// the image is flipped back as the transformation turns y down
+		fmt.Fprintf(w, `<pattern id="%s" patternUnits="userSpaceOnUse" x="%s" y="%s" width="%s" height="%s" patternTransform="%s">`,
+			id, formatNum(p.X), formatNum(p.Y), formatNum(p.Width), formatNum(p.Height), transform)
+		fmt.Fprintf(w, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" transform="matrix(1 0 0 -1 0 %s)" xlink:href="data:image/png;base64,%s"/>`,
+			formatNum(p.X), formatNum(p.Y), formatNum(p.Width), formatNum(p.Height),
+			formatNum(2*p.Y+p.Height), base64.StdEncoding.EncodeToString(img.Bytes()))
+		io.WriteString(w, `</pattern>`)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeSVGStops writes the stops, see sRGBStops.
+func writeSVGStops(w io.Writer, g Gradient) {
+	for _, s := range g.sRGBStops() {
+		col := s.Color.(color.NRGBA)
+		fmt.Fprintf(w, `<stop offset="%s" stop-color="#%02x%02x%02x"`, formatNum(Clamp(s.Offset, 0, 1)), col.R, col.G, col.B)
+		if col.A != 0xff {
+			fmt.Fprintf(w, ` stop-opacity="%s"`, formatNum(float64(col.A)/0xff))
+		}
+		io.WriteString(w, `/>`)
+	}
+}
+
+// sRGBStops returns the stops with NRGBA colors for SVG and PDF, which mix
+// in sRGB, so other spaces get extra stops in between.
+func (g Gradient) sRGBStops() []Stop {
+	const steps = 16
+	var stops []Stop
+	for i, s := range g.Stops {
+		if i > 0 && g.Space != SRGB {
+			prev := g.Stops[i-1].Offset
+			for j := 1; j < steps; j++ {
+				t := Lerp(prev, s.Offset, float64(j)/steps)
+				stops = append(stops, Stop{t, g.ColorAt(t)})
+			}
+		}
+		stops = append(stops, Stop{s.Offset, toNRGBA(s.Color)})
+	}
+	return stops
+}
+
+// renderPaint writes the path with its paints as PDF shadings and tiling
+// patterns, or as an image when a paint isn't one of those.
+func (c *pdfContent) renderPaint(pi paintImage) {
+	if !pdfPaint(pi.fill) || !pdfPaint(pi.stroke) {
+		if img, m := pi.toImage(c.width, c.height); img != nil {
+			c.drawImage(img, m)
+		}
+		return
+	}
+	path := pi.path.Transform(pi.m)
+	if pi.filled() {
+		if pi.fill == nil {
+			c.RenderPath(path, canvas.Style{FillColor: pi.style.FillColor, FillRule: pi.style.FillRule}, Identity)
+		} else {
+			c.paint(path, pi.style.FillRule, pi.fill, pi.m)
+		}
+	}
+	if pi.stroked() {
+		if pi.stroke == nil {
+			style := pi.style
+			style.FillColor = canvas.Transparent
+			c.RenderPath(path, style, Identity)
+		} else {
+			c.paint(strokeOutline(path, pi.style), canvas.NonZero, pi.stroke, pi.m)
+		}
+	}
+}
+
+// pdfPaint is true for the paints PDF has, and no paint
+func pdfPaint(p Paint) bool {
+	switch p := p.(type) {
+	case nil, LinearGradient, RadialGradient:
+		return true
+	case Pattern:
+		return p.Width > 0 && p.Height > 0 && !p.Image.Bounds().Empty()
+	}
+	return false
+}
+
+// paint fills `region`, on the page, with `p` drawn with `m`.
+func (c *pdfContent) paint(region *canvas.Path, rule canvas.FillRule, p Paint, m canvas.Matrix) {
+	if region.Empty() || m.Det() == 0 {
+		return
+	}
+	data := strings.TrimSpace(region.ToPDF())
+	fill, clip := "f", "W n"
+	if rule == canvas.EvenOdd {
+		fill, clip = "f*", "W* n"
+	}
+	flat := func(col color.Color) {
+		c.RenderPath(region, canvas.Style{FillColor: color.RGBAModel.Convert(col).(color.RGBA), FillRule: rule}, Identity)
+	}
+
+	var g Gradient
+	var shading pdfDict
+	switch p := p.(type) {
+	case Pattern:
+		// the tiles' alpha is in the image
+		c.setAlpha([2]float64{1, c.state.alpha[1]})
+		name := c.resource("Pattern", "P", c.f.pattern(p, c.base.Mul(m)))
+		c.set(&c.state.fill, fmt.Sprintf("/Pattern cs /%s scn", name))
+		c.op("%s %s", data, fill)
+		return
+	case LinearGradient:
+		if len(p.Stops) == 0 || p.X0 == p.X1 && p.Y0 == p.Y1 {
+			flat(p.At(p.X0, p.Y0))
+			return
+		}
+		g = p.Gradient
+		shading = pdfDict{"ShadingType": 2, "Coords": pdfArray{p.X0, p.Y0, p.X1, p.Y1}}
+	case RadialGradient:
+		if len(p.Stops) == 0 || p.R <= 0 {
+			flat(p.At(p.CX, p.CY))
+			return
+		}
+		g = p.Gradient
+		shading = pdfDict{"ShadingType": 3, "Coords": pdfArray{p.CX, p.CY, 0.0, p.CX, p.CY, p.R}}
+	}
+
+	stops := g.sRGBStops()
+	shading["ColorSpace"] = pdfName("DeviceRGB")
+	shading["Extend"] = pdfArray{true, true}
+	shading["Function"] = pdfFunction(stops, func(col color.NRGBA) pdfArray {
+		return pdfArray{float64(col.R) / 0xff, float64(col.G) / 0xff, float64(col.B) / 0xff}
+	})
+	c.save()
+	c.op("%s %s %s cm", data, clip, pdfMatrix(m))
+	c.setAlpha([2]float64{1, 1})
+	for _, s := range stops {
+		if s.Color.(color.NRGBA).A == 0xff {
+			continue
+		}
+		// the alpha is a soft mask of the same shading in gray
+		alpha := pdfDict{}
+		for key, v := range shading {
+			alpha[key] = v
+		}
+		alpha["ColorSpace"] = pdfName("DeviceGray")
+		alpha["Function"] = pdfFunction(stops, func(col color.NRGBA) pdfArray {
+			return pdfArray{float64(col.A) / 0xff}
+		})
+		b := region.Transform(m.Inv()).Bounds()
+		form := c.f.writeObject(pdfStream{pdfDict{
+			"Type":      pdfName("XObject"),
+			"Subtype":   pdfName("Form"),
+			"BBox":      pdfArray{b.X, b.Y, b.X + b.W, b.Y + b.H},
+			"Group":     pdfDict{"Type": pdfName("Group"), "S": pdfName("Transparency"), "CS": pdfName("DeviceGray")},
+			"Resources": pdfDict{"Shading": pdfDict{"Sh0": alpha}},
+		}, []byte("/Sh0 sh")})
+		c.op("/%s gs", c.resource("ExtGState", "M", pdfDict{
+			"Type":  pdfName("ExtGState"),
+			"SMask": pdfDict{"Type": pdfName("Mask"), "S": pdfName("Luminosity"), "G": form},
+		}))
+		break
+	}
+	c.op("/%s sh", c.resource("Shading", "Sh", shading))
+	c.restore()
+}
+
+// pdfFunction returns the function from 0 to 1 going through the stops, with
+// `ch` the components of their colors. It's a type 2 function, interpolating,
+// for each gap between stops, stitched together.
+func pdfFunction(stops []Stop, ch func(color.NRGBA) pdfArray) pdfDict {
+	// the ends carry on to 0 and 1
+	points := append([]Stop{{0, stops[0].Color}}, stops...)
+	points = append(points, Stop{1, stops[len(stops)-1].Color})
+	var fns, bounds, encode pdfArray
+	for i := 1; i < len(points); i++ {
+		a, b := Clamp(points[i-1].Offset, 0, 1), Clamp(points[i].Offset, 0, 1)
+		if b <= a {
+			continue // a step from one color to the next
+		}
+		if len(fns) > 0 {
+			bounds = append(bounds, a)
+		}
+		fns = append(fns, pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{0.0, 1.0},
+			"C0":           ch(points[i-1].Color.(color.NRGBA)),
+			"C1":           ch(points[i].Color.(color.NRGBA)),
+			"N":            1,
+		})
+		encode = append(encode, 0.0, 1.0)
+	}
+	return pdfDict{"FunctionType": 3, "Domain": pdfArray{0.0, 1.0}, "Functions": fns, "Bounds": bounds, "Encode": encode}
+}
+
+// pattern writes `p` as a tiling pattern, `m` takes its coordinates to those
+// of the page or form it's used in.
+func (f *pdfFile) pattern(p Pattern, m canvas.Matrix) pdfRef {
+	m = m.Translate(p.X, p.Y)
+	return f.writeObject(pdfStream{pdfDict{
+		"Type":        pdfName("Pattern"),
+		"PatternType": 1,
+		"PaintType":   1,
+		"TilingType":  1,
+		"BBox":        pdfArray{0.0, 0.0, p.Width, p.Height},
+		"XStep":       p.Width,
+		"YStep":       p.Height,
+		"Matrix":      pdfArray{m[0][0], m[1][0], m[0][1], m[1][1], m[0][2], m[1][2]},
+		"Resources":   pdfDict{"XObject": pdfDict{"Im0": f.image(p.Image)}},
+	}, []byte(fmt.Sprintf("%s 0 0 %s 0 0 cm /Im0 Do", pdfNum(p.Width), pdfNum(p.Height)))})
+}
@@ -0,0 +1,106 @@
+package gart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestGradientColorAt(t *testing.T) {
+	stops := EvenStops(color.Black, color.White)
+	tests := []struct {
+		space ColorSpace
+		t     float64
+		want  color.NRGBA
+	}{
+		{SRGB, -1, color.NRGBA{0, 0, 0, 0xff}},
+		{SRGB, 0.5, color.NRGBA{0x80, 0x80, 0x80, 0xff}},
+		{SRGB, 2, color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{LinearRGB, 0.5, color.NRGBA{0xbc, 0xbc, 0xbc, 0xff}},
+		{Lab, 0.5, color.NRGBA{0x77, 0x77, 0x77, 0xff}},
+	}
+	for _, test := range tests {
+		got := Gradient{stops, test.space}.ColorAt(test.t)
+		if colorDist(color.RGBA(got), color.RGBA(test.want)) > 1 {
+			t.Errorf("space %d at %.1f = %v, want %v", test.space, test.t, got, test.want)
+		}
+	}
+}
+
+func TestPaintRaster(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	ctx := NewContext(10, 10)
+	ctx.Push()
+	ctx.SetFillPaint(NewLinearGradient(0, 0, 10, 0, EvenStops(red, blue)...))
+	ctx.FillRect(0, 5, 10, 5)
+	ctx.Pop()
+	ctx.FillRect(0, 0, 10, 5) // the black fill is back
+
+	img, err := PNGOptions{Resolution: 1}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, color.RGBA{0xf2, 0, 0x0d, 0xff}},
+		{9, 0, color.RGBA{0x0d, 0, 0xf2, 0xff}},
+		{5, 9, color.RGBA{0, 0, 0, 0xff}},
+	}
+	for _, test := range tests {
+		r, g, b, a := img.At(test.x, test.y).RGBA()
+		got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if colorDist(got, test.want) > 2 {
+			t.Errorf("pixel %d,%d = %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestPaintFormats(t *testing.T) {
+	tile := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	tile.Set(0, 0, color.Black)
+	ctx := NewContext(10, 10)
+	ctx.SetFillPaint(NewPattern(tile, 2, 2))
+	ctx.SetStrokePaint(NewRadialGradient(5, 5, 5, EvenStops(color.White, color.Black)...))
+	ctx.SetStrokeWidth(0.5)
+	ctx.Circle(5, 5, 4)
+	ctx.FillStroke()
+	ctx.SetFillPaint(NewLinearGradient(0, 0, 10, 0, EvenStops(color.Black, color.Transparent)...))
+	ctx.SetStrokeWidth(0)
+	ctx.FillRect(0, 0, 10, 2)
+
+	var buf bytes.Buffer
+	if err := SVGWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<pattern id="paint1"`, `<radialGradient id="paint2"`, `fill:url(#paint1)`, `stroke:url(#paint2)`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("SVG = %s, want it to contain %s", buf.String(), want)
+		}
+	}
+	buf.Reset()
+	if err := PDFWriter(&buf, ctx); err != nil {
+		t.Fatalf("PDF failed: %v", err)
+	}
+	checkXRef(t, buf.Bytes())
+	pdf := buf.String()
+	for _, want := range []string{"/PatternType 1 ", "/ShadingType 2 ", "/ShadingType 3 ", "/S /Luminosity", " scn "} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("PDF doesn't contain %q", want)
+		}
+	}
+	// the tile and its alpha, nothing's rasterized
+	if n := strings.Count(pdf, "/Subtype /Image"); n != 2 {
+		t.Errorf("PDF has %d images, want 2", n)
+	}
+	if err := EPSWriter(&bytes.Buffer{}, ctx); err != nil {
+		t.Errorf("EPS failed: %v", err)
+	}
+	if paths, _ := PlotPaths(ctx, 0.1); len(paths) != 1 {
+		t.Errorf("plotted %d paths, want the painted stroke", len(paths))
+	}
+}
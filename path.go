@@ -0,0 +1,64 @@
+package gart
+
+import "github.com/tdewolff/canvas"
+
+// pathBuilder holds the path being built, it's shared by the Drawers
+// which render it on Stroke, Fill, etc.
+type pathBuilder struct {
+	path *canvas.Path
+}
+
+// MoveTo moves the path to x,y without connecting the path. It starts a new independent subpath.
+// Multiple subpaths can be useful when negating parts of a previous path by overlapping it with a
+// path in the opposite direction. The behaviour for overlapping paths depend on the FillRule.
+func (pb *pathBuilder) MoveTo(x, y float64) {
+	pb.path.MoveTo(x, y)
+}
+
+// LineTo adds a linear path to x,y.
+func (pb *pathBuilder) LineTo(x, y float64) {
+	pb.path.LineTo(x, y)
+}
+
+// QuadTo adds a quadratic Bézier path with control point cpx,cpy and end point x,y.
+func (pb *pathBuilder) QuadTo(cpx, cpy, x, y float64) {
+	pb.path.QuadTo(cpx, cpy, x, y)
+}
+
+// CubicTo adds a cubic Bézier path with control points cpx1,cpy1 and cpx2,cpy2 and end point x,y.
+func (pb *pathBuilder) CubicTo(cpx1, cpy1, cpx2, cpy2, x, y float64) {
+	pb.path.CubeTo(cpx1, cpy1, cpx2, cpy2, x, y)
+}
+
+// ArcTo adds an elliptical arc to x,y like the SVG A command, with radii rx and ry and
+// the ellipse rotated by rot radians. large and sweep pick which of the four possible arcs to draw.
+func (pb *pathBuilder) ArcTo(rx, ry, rot float64, large, sweep bool, x, y float64) {
+	pb.path.ArcTo(rx, ry, Degrees(rot), large, sweep, x, y)
+}
+
+// Arc adds a circular arc centered on x,y with radius r from angle0 to angle1 in radians,
+// counter clockwise when angle0 < angle1.
+// Like gg, the arc is connected to the current path with a line, if there is one.
+func (pb *pathBuilder) Arc(x, y, r, angle0, angle1 float64) {
+	appendArc(pb.path, x, y, r, angle0, angle1)
+}
+
+// Close closes the current path
+func (pb *pathBuilder) Close() {
+	pb.path.Close()
+}
+
+// Polylines returns the current path flattened into polylines, the curves
+// within `tolerance` of the lines, in the coordinates it's drawn with.
+// Closed subpaths end back at their first point.
+func (pb *pathBuilder) Polylines(tolerance float64) [][]Vec2 {
+	var lines [][]Vec2
+	for _, points := range flatten(pb.path, tolerance) {
+		line := make([]Vec2, len(points))
+		for i, p := range points {
+			line[i] = Vec2{p.X, p.Y}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,751 @@
+package gart
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+	canvasFont "github.com/tdewolff/canvas/font"
+)
+
+// ptPerMM is how many PDF points, 1/72 inch, there are in a mm
+const ptPerMM = 72 / 25.4
+
+// writePDF writes `c` as a one page PDF. It's gart's own writer as the
+// canvas one has no way to add a clip path, soft mask, blend mode, shading or
+// pattern to its page, which left rasterizing them as the only option.
+// Everything else is written the way the canvas writer did, in mm on a page
+// in points.
+func writePDF(w io.Writer, c *canvas.Canvas) error {
+	f := newPDFFile(w)
+	page := newPDFContent(f, c.W, c.H)
+	page.base = Identity.Scale(ptPerMM, ptPerMM)
+	page.op("%s 0 0 %s 0 0 cm", pdfNum(ptPerMM), pdfNum(ptPerMM)) // so the page is in mm
+	c.Render(page)
+	return f.close(page)
+}
+
+type (
+	pdfRef    int
+	pdfName   string
+	pdfArray  []interface{}
+	pdfDict   map[pdfName]interface{}
+	pdfStream struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+// pdfFlate is the filter compressing a stream
+const pdfFlate = pdfName("FlateDecode")
+
+// pdfFile writes the objects of a PDF as they come. The catalog, info and
+// page tree are objects 1 to 3 but written last.
+type pdfFile struct {
+	w       io.Writer
+	pos     int
+	offsets []int
+	fonts   map[*canvas.Font]pdfRef
+	images  map[image.Image]pdfRef
+	masks   map[*clipMask]pdfRef // the forms drawing the soft masks
+	err     error
+}
+
+func newPDFFile(w io.Writer) *pdfFile {
+	f := &pdfFile{
+		w:       w,
+		offsets: []int{0, 0, 0},
+		fonts:   map[*canvas.Font]pdfRef{},
+		images:  map[image.Image]pdfRef{},
+		masks:   map[*clipMask]pdfRef{},
+	}
+	f.printf("%%PDF-1.7\n")
+	return f
+}
+
+func (f *pdfFile) printf(format string, args ...interface{}) {
+	if f.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(f.w, format, args...)
+	f.pos += n
+	f.err = err
+}
+
+// fail keeps the first error, which close returns
+func (f *pdfFile) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *pdfFile) writeBytes(b []byte) {
+	if f.err != nil {
+		return
+	}
+	n, err := f.w.Write(b)
+	f.pos += n
+	f.err = err
+}
+
+func (f *pdfFile) writeVal(v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		f.printf("%t", v)
+	case int:
+		f.printf("%d", v)
+	case float64:
+		f.printf("%s", pdfNum(v))
+	case string:
+		f.printf("%s", pdfLiteral(v))
+	case pdfRef:
+		f.printf("%d 0 R", v)
+	case pdfName:
+		f.printf("/%s", string(v))
+	case pdfArray:
+		f.printf("[")
+		for i, val := range v {
+			if i > 0 {
+				f.printf(" ")
+			}
+			f.writeVal(val)
+		}
+		f.printf("]")
+	case pdfDict:
+		// Type and Subtype go first, for whoever reads it
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "Type" && key != "Subtype" {
+				keys = append(keys, string(key))
+			}
+		}
+		sort.Strings(keys)
+		keys = append([]string{"Type", "Subtype"}, keys...)
+		f.printf("<<")
+		for _, key := range keys {
+			if val, ok := v[pdfName(key)]; ok {
+				f.printf(" /%s ", key)
+				f.writeVal(val)
+			}
+		}
+		f.printf(" >>")
+	case pdfStream:
+		data := v.data
+		dict := pdfDict{}
+		for key, val := range v.dict {
+			dict[key] = val
+		}
+		if dict["Filter"] == pdfFlate {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			zw.Write(data)
+			zw.Close()
+			data = buf.Bytes()
+		}
+		dict["Length"] = len(data)
+		f.writeVal(dict)
+		f.printf(" stream\n")
+		f.writeBytes(data)
+		f.printf("\nendstream")
+	default:
+		panic(fmt.Sprintf("gart: can't write %T to a PDF", v))
+	}
+}
+
+func (f *pdfFile) writeObject(v interface{}) pdfRef {
+	f.offsets = append(f.offsets, 0)
+	ref := pdfRef(len(f.offsets))
+	f.writeAt(ref, v)
+	return ref
+}
+
+// writeAt writes the object `ref`, which was numbered already
+func (f *pdfFile) writeAt(ref pdfRef, v interface{}) {
+	f.offsets[ref-1] = f.pos
+	f.printf("%d 0 obj\n", ref)
+	f.writeVal(v)
+	f.printf("\nendobj\n")
+}
+
+// close writes the page, the objects pointing at it and the trailer.
+func (f *pdfFile) close(page *pdfContent) error {
+	contents := f.writeObject(pdfStream{data: page.bytes()})
+	pageRef := f.writeObject(pdfDict{
+		"Type":      pdfName("Page"),
+		"Parent":    pdfRef(3),
+		"MediaBox":  pdfArray{0.0, 0.0, page.width * ptPerMM, page.height * ptPerMM},
+		"Resources": page.resources,
+		"Contents":  contents,
+		"Group":     pdfGroup(),
+	})
+	f.writeAt(1, pdfDict{"Type": pdfName("Catalog"), "Pages": pdfRef(3)})
+	f.writeAt(2, pdfDict{"Producer": "gart"})
+	f.writeAt(3, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{pageRef}, "Count": 1})
+
+	xref := f.pos
+	f.printf("xref\n0 %d\n0000000000 65535 f \n", len(f.offsets)+1)
+	for _, offset := range f.offsets {
+		f.printf("%010d 00000 n \n", offset)
+	}
+	f.printf("trailer\n")
+	f.writeVal(pdfDict{"Info": pdfRef(2), "Root": pdfRef(1), "Size": len(f.offsets) + 1})
+	f.printf("\nstartxref\n%d\n%%%%EOF", xref)
+	return f.err
+}
+
+// pdfGroup is the transparency group of the page and the forms, which
+// composites what's drawn in it on its own before it goes on the page.
+func pdfGroup() pdfDict {
+	return pdfDict{"Type": pdfName("Group"), "S": pdfName("Transparency"), "I": true, "CS": pdfName("DeviceRGB")}
+}
+
+// font returns the font embedded whole, as a CID font indexed by glyph, or 0
+// when it can't be embedded, which fails the file.
+func (f *pdfFile) font(font *canvas.Font) pdfRef {
+	if ref, ok := f.fonts[font]; ok {
+		return ref
+	}
+	mediatype, b := font.Raw()
+	if mediatype != "font/truetype" && mediatype != "font/opentype" {
+		var err error
+		if b, err = canvasFont.ToSFNT(b); err == nil {
+			mediatype, err = canvasFont.MediaType(b)
+		}
+		if err != nil || mediatype != "font/truetype" && mediatype != "font/opentype" {
+			f.fail(fmt.Errorf("gart: can't embed the font %s in a PDF", font.Name()))
+			return 0
+		}
+	}
+
+	units := font.UnitsPerEm()
+	scale := 1000 / units // PDF glyphs are 1000 units to the em
+	var widths []int
+	for _, w := range font.Widths(units) {
+		widths = append(widths, int(w*scale+0.5))
+	}
+	// runs of 5 or more the same are shorter as a range
+	dw := 0
+	if len(widths) > 0 {
+		dw = widths[0]
+	}
+	w := pdfArray{}
+	i, j := 1, 1
+	for k, width := range widths {
+		if k != 0 && width != widths[j] {
+			if k-j > 4 {
+				if i < j {
+					arr := pdfArray{}
+					for _, w := range widths[i:j] {
+						arr = append(arr, w)
+					}
+					w = append(w, i, arr)
+				}
+				if widths[j] != dw {
+					w = append(w, j, k-1, widths[j])
+				}
+				i = k
+			}
+			j = k
+		}
+	}
+	if i < len(widths) {
+		arr := pdfArray{}
+		for _, w := range widths[i:] {
+			arr = append(arr, w)
+		}
+		w = append(w, i, arr)
+	}
+
+	name := pdfName(strings.Replace(font.Name(), " ", "_", -1))
+	bounds := font.Bounds(units)
+	metrics := font.Metrics(units)
+	descriptor := pdfDict{
+		"Type":        pdfName("FontDescriptor"),
+		"FontName":    name,
+		"Flags":       4,
+		"FontBBox":    pdfArray{int(scale * bounds.X), -int(scale * (bounds.Y + bounds.H)), int(scale * (bounds.X + bounds.W)), -int(scale * bounds.Y)},
+		"ItalicAngle": font.ItalicAngle(),
+		"Ascent":      int(scale * metrics.Ascent),
+		"Descent":     -int(scale * metrics.Descent),
+		"CapHeight":   -int(scale * metrics.CapHeight),
+		"StemV":       80,
+		"StemH":       80,
+	}
+	subtype := pdfName("CIDFontType2")
+	if mediatype == "font/opentype" {
+		subtype = "CIDFontType0"
+		descriptor["FontFile3"] = f.writeObject(pdfStream{pdfDict{"Subtype": pdfName("OpenType"), "Filter": pdfFlate}, b})
+	} else {
+		descriptor["FontFile2"] = f.writeObject(pdfStream{pdfDict{"Length1": len(b), "Filter": pdfFlate}, b})
+	}
+	ref := f.writeObject(pdfDict{
+		"Type":     pdfName("Font"),
+		"Subtype":  pdfName("Type0"),
+		"BaseFont": name,
+		"Encoding": pdfName("Identity-H"),
+		"DescendantFonts": pdfArray{pdfDict{
+			"Type":           pdfName("Font"),
+			"Subtype":        subtype,
+			"BaseFont":       name,
+			"CIDToGIDMap":    pdfName("Identity"),
+			"DW":             dw,
+			"W":              w,
+			"CIDSystemInfo":  pdfDict{"Registry": "Adobe", "Ordering": "Identity", "Supplement": 0},
+			"FontDescriptor": descriptor,
+		}},
+	})
+	f.fonts[font] = ref
+	return ref
+}
+
+// image embeds `img` with its alpha as a soft mask, once if it can be a map key.
+func (f *pdfFile) image(img image.Image) pdfRef {
+	key := reflect.TypeOf(img).Comparable()
+	if key {
+		if ref, ok := f.images[img]; ok {
+			return ref
+		}
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	rgb := make([]byte, 0, w*h*3)
+	alpha := make([]byte, 0, w*h)
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 0xff
+		}
+	}
+	dict := func(space string) pdfDict {
+		return pdfDict{
+			"Type":             pdfName("XObject"),
+			"Subtype":          pdfName("Image"),
+			"Width":            w,
+			"Height":           h,
+			"ColorSpace":       pdfName(space),
+			"BitsPerComponent": 8,
+			"Interpolate":      true,
+			"Filter":           pdfFlate,
+		}
+	}
+	d := dict("DeviceRGB")
+	if !opaque {
+		d["SMask"] = f.writeObject(pdfStream{dict("DeviceGray"), alpha})
+	}
+	ref := f.writeObject(pdfStream{d, rgb})
+	if key {
+		f.images[img] = ref
+	}
+	return ref
+}
+
+// pdfContent is the canvas.Renderer writing a content stream, of the page
+// or a form on it. It keeps track of the graphics state so it's only set
+// when it changes.
+type pdfContent struct {
+	f             *pdfFile
+	buf           bytes.Buffer
+	width, height float64
+	resources     pdfDict
+	alphas        map[[2]float64]pdfName
+	fontNames     map[pdfRef]pdfName
+	state         pdfState
+	saved         []pdfState    // by q, for Q
+	base          canvas.Matrix // from its coordinates to those patterns start in
+}
+
+// pdfState is the graphics state as the operators that set it, and the fill
+// and stroke alpha.
+type pdfState struct {
+	fill, stroke, width, cap, join, miter, dash string
+	font, charSpace, textMode                   string
+	alpha                                       [2]float64
+}
+
+func newPDFContent(f *pdfFile, width, height float64) *pdfContent {
+	return &pdfContent{
+		f:         f,
+		width:     width,
+		height:    height,
+		resources: pdfDict{},
+		alphas:    map[[2]float64]pdfName{},
+		fontNames: map[pdfRef]pdfName{},
+		base:      Identity,
+		state: pdfState{
+			fill:      "0 0 0 rg",
+			stroke:    "0 0 0 RG",
+			width:     "1 w",
+			cap:       "0 J",
+			join:      "0 j",
+			miter:     "10 M",
+			dash:      "[] 0 d",
+			charSpace: "0 Tc",
+			textMode:  "0 Tr",
+			alpha:     [2]float64{1, 1},
+		},
+	}
+}
+
+func (c *pdfContent) op(format string, args ...interface{}) {
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte(' ')
+	}
+	fmt.Fprintf(&c.buf, format, args...)
+}
+
+func (c *pdfContent) bytes() []byte {
+	return c.buf.Bytes()
+}
+
+// set writes `op` unless it's what `field` already is
+func (c *pdfContent) set(field *string, op string) {
+	if *field != op {
+		*field = op
+		c.op("%s", op)
+	}
+}
+
+func (c *pdfContent) setAlpha(alpha [2]float64) {
+	if alpha == c.state.alpha {
+		return
+	}
+	c.state.alpha = alpha
+	name, ok := c.alphas[alpha]
+	if !ok {
+		name = c.resource("ExtGState", "A", pdfDict{"ca": alpha[0], "CA": alpha[1]})
+		c.alphas[alpha] = name
+	}
+	c.op("/%s gs", name)
+}
+
+func (c *pdfContent) save() {
+	c.op("q")
+	c.saved = append(c.saved, c.state)
+}
+
+func (c *pdfContent) restore() {
+	c.op("Q")
+	c.state = c.saved[len(c.saved)-1]
+	c.saved = c.saved[:len(c.saved)-1]
+}
+
+// resource adds `v` to the resources of `kind` named `prefix` and a number.
+func (c *pdfContent) resource(kind, prefix string, v interface{}) pdfName {
+	d, ok := c.resources[pdfName(kind)].(pdfDict)
+	if !ok {
+		d = pdfDict{}
+		c.resources[pdfName(kind)] = d
+	}
+	name := pdfName(fmt.Sprintf("%s%d", prefix, len(d)))
+	d[name] = v
+	return name
+}
+
+func (c *pdfContent) Size() (float64, float64) {
+	return c.width, c.height
+}
+
+func (c *pdfContent) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	fill := style.FillColor.A != 0
+	stroke := stroked(style)
+	if !fill && !stroke {
+		return
+	}
+	path = path.Transform(m)
+	lineCap, capOK := pdfCap(style.StrokeCapper)
+	join, miter, joinOK := pdfJoin(style.StrokeJoiner)
+	if stroke && !(capOK && joinOK) {
+		// PDF doesn't have the cap or join, so the stroke is filled as its outline
+		if fill {
+			c.RenderPath(path, canvas.Style{FillColor: style.FillColor, FillRule: style.FillRule}, Identity)
+		}
+		c.RenderPath(strokeOutline(path, style), canvas.Style{FillColor: style.StrokeColor}, Identity)
+		return
+	}
+
+	alpha := c.state.alpha
+	if fill {
+		col, a := pdfColor(style.FillColor, "rg")
+		c.set(&c.state.fill, col)
+		alpha[0] = a
+	}
+	if stroke {
+		col, a := pdfColor(style.StrokeColor, "RG")
+		c.set(&c.state.stroke, col)
+		alpha[1] = a
+		c.set(&c.state.width, pdfNum(style.StrokeWidth)+" w")
+		c.set(&c.state.cap, lineCap)
+		c.set(&c.state.join, join)
+		if miter != "" {
+			c.set(&c.state.miter, miter)
+		}
+		c.set(&c.state.dash, pdfDash(style.DashOffset, style.Dashes))
+	}
+	c.setAlpha(alpha)
+
+	paint := "f"
+	if stroke && fill {
+		paint = "B"
+	} else if stroke {
+		paint = "S"
+	}
+	if fill && style.FillRule == canvas.EvenOdd {
+		paint += "*"
+	}
+	c.op("%s %s", strings.TrimSpace(path.ToPDF()), paint)
+}
+
+func (c *pdfContent) RenderText(text *canvas.Text, m canvas.Matrix) {
+	c.op("BT")
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		face := span.Face
+		alpha := c.state.alpha
+		col, a := pdfColor(face.Color, "rg")
+		c.set(&c.state.fill, col)
+		alpha[0] = a
+		mode := "0 Tr"
+		if face.FauxBold > 0 {
+			// filled and stroked a little wider
+			mode = "2 Tr"
+			col, _ := pdfColor(face.Color, "RG")
+			c.set(&c.state.stroke, col)
+			c.set(&c.state.width, pdfNum(face.FauxBold*2)+" w")
+			alpha[1] = alpha[0]
+		}
+		c.setAlpha(alpha)
+		c.set(&c.state.textMode, mode)
+
+		size := face.Size * face.Scale
+		ref := c.f.font(face.Font)
+		name, ok := c.fontNames[ref]
+		if !ok {
+			name = c.resource("Font", "F", ref)
+			c.fontNames[ref] = name
+		}
+		c.set(&c.state.font, fmt.Sprintf("/%s %s Tf", name, pdfNum(size)))
+		c.set(&c.state.charSpace, pdfNum(span.GlyphSpacing)+" Tc")
+		c.op("%s Tm", pdfMatrix(m.Translate(dx, y).Shear(face.FauxItalic, 0)))
+		c.op("%s TJ", pdfTJ(span, size))
+	})
+	c.op("ET")
+	text.RenderDecoration(c, m)
+}
+
+// pdfTJ returns the span's glyphs, with its kerning and word spacing, as the
+// array TJ shows.
+func pdfTJ(span canvas.TextSpan, size float64) string {
+	font := span.Face.Font
+	units := font.UnitsPerEm()
+	var parts []string
+	glyphs := func(s string) {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.BigEndian, font.IndicesOf(s))
+		parts = append(parts, pdfLiteral(buf.String()))
+	}
+	for i, word := range span.Words() {
+		if i > 0 {
+			parts = append(parts, strconv.Itoa(-int(span.WordSpacing*1000/size+0.5)))
+		}
+		start := 0
+		var prev rune
+		for j, r := range word {
+			if start < j {
+				if kern, err := font.Kerning(prev, r, units); err == nil && kern != 0 {
+					glyphs(word[start:j])
+					parts = append(parts, strconv.Itoa(-int(kern*1000/units+0.5)))
+					start = j
+				}
+			}
+			prev = r
+		}
+		glyphs(word[start:])
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func (c *pdfContent) RenderImage(img image.Image, m canvas.Matrix) {
+	switch img := img.(type) {
+	case groupImage:
+		c.renderGroup(img.group)
+	case paintImage:
+		c.renderPaint(img)
+	default:
+		c.drawImage(img, m)
+	}
+}
+
+func (c *pdfContent) drawImage(img image.Image, m canvas.Matrix) {
+	size := img.Bounds().Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return
+	}
+	name := c.resource("XObject", "Im", c.f.image(img))
+	c.setAlpha([2]float64{1, 1})
+	c.op("q %s cm /%s Do Q", pdfMatrix(m.Scale(float64(size.X), float64(size.Y))), name)
+}
+
+// pdfBlendModes are the names PDF has for the blend modes. It has no add, and
+// Screen is the closest: s+d-s*d rather than s+d.
+var pdfBlendModes = map[BlendMode]pdfName{
+	BlendNormal:     "Normal",
+	BlendMultiply:   "Multiply",
+	BlendScreen:     "Screen",
+	BlendAdd:        "Screen",
+	BlendOverlay:    "Overlay",
+	BlendDarken:     "Darken",
+	BlendLighten:    "Lighten",
+	BlendDifference: "Difference",
+}
+
+// renderGroup draws a clip group clipped to its path, or its soft mask, and
+// a blend group as a form composited with its blend mode and opacity.
+func (c *pdfContent) renderGroup(g *group) {
+	if g.c.Empty() {
+		return
+	}
+	if g.clip == nil {
+		mode, ok := pdfBlendModes[g.mode]
+		if !ok {
+			mode = "Normal" // as the raster formats do
+		}
+		gs := c.resource("ExtGState", "B", pdfDict{"Type": pdfName("ExtGState"), "BM": mode, "ca": g.opacity, "CA": g.opacity})
+		form := c.resource("XObject", "Fm", c.f.form(g.c, c.width, c.height))
+		c.save()
+		c.op("/%s gs /%s Do", gs, form)
+		c.restore()
+		return
+	}
+	if g.clip.path.Empty() {
+		return // nothing shows
+	}
+	c.save()
+	if g.clip.mask == nil {
+		c.op("%s W n", strings.TrimSpace(g.clip.path.ToPDF()))
+	} else {
+		c.op("/%s gs", c.resource("ExtGState", "M", pdfDict{
+			"Type":  pdfName("ExtGState"),
+			"SMask": pdfDict{"Type": pdfName("Mask"), "S": pdfName("Alpha"), "G": c.f.mask(g.clip)},
+		}))
+	}
+	g.c.Render(c)
+	c.restore()
+}
+
+// mask returns the form drawing the soft mask of `cm`, whose alpha is how
+// much shows through.
+func (f *pdfFile) mask(cm *clipMask) pdfRef {
+	if ref, ok := f.masks[cm]; ok {
+		return ref
+	}
+	w, h := cm.mask.Size()
+	ref := f.form(cm.mask, w, h)
+	f.masks[cm] = ref
+	return ref
+}
+
+// form writes `c` as a form XObject in the page's coordinates, a
+// transparency group so it can be composited as a whole.
+func (f *pdfFile) form(c *canvas.Canvas, width, height float64) pdfRef {
+	fc := newPDFContent(f, width, height)
+	c.Render(fc)
+	return f.writeObject(pdfStream{pdfDict{
+		"Type":      pdfName("XObject"),
+		"Subtype":   pdfName("Form"),
+		"BBox":      pdfArray{0.0, 0.0, width, height},
+		"Resources": fc.resources,
+		"Group":     pdfGroup(),
+	}, fc.bytes()})
+}
+
+// pdfColor returns the operator `op` setting the color without its alpha,
+// and the alpha.
+func pdfColor(col color.RGBA, op string) (string, float64) {
+	if col.A == 0 {
+		return "0 0 0 " + op, 0
+	}
+	a := float64(col.A) / 0xff
+	ch := func(v uint8) string {
+		return pdfNum(float64(v) / 0xff / a)
+	}
+	return ch(col.R) + " " + ch(col.G) + " " + ch(col.B) + " " + op, a
+}
+
+func pdfCap(capper canvas.Capper) (string, bool) {
+	switch capper.(type) {
+	case canvas.ButtCapper, nil:
+		return "0 J", true
+	case canvas.RoundCapper:
+		return "1 J", true
+	case canvas.SquareCapper:
+		return "2 J", true
+	}
+	return "", false
+}
+
+// pdfJoin returns the line join and, for a miter, the miter limit.
+func pdfJoin(joiner canvas.Joiner) (string, string, bool) {
+	switch j := joiner.(type) {
+	case canvas.BevelJoiner:
+		return "2 j", "", true
+	case canvas.RoundJoiner:
+		return "1 j", "", true
+	case canvas.MiterJoiner:
+		// PDF bevels past the limit
+		if _, ok := j.GapJoiner.(canvas.BevelJoiner); ok && !math.IsNaN(j.Limit) {
+			return "0 j", pdfNum(j.Limit) + " M", true
+		}
+	case nil:
+		return "0 j", "", true
+	}
+	return "", "", false
+}
+
+// pdfDash returns the dash pattern, PDF wants it even and the phase positive.
+func pdfDash(offset float64, dashes []float64) string {
+	if len(dashes) == 0 {
+		return "[] 0 d"
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes[:len(dashes):len(dashes)], dashes...)
+	}
+	total := 0.0
+	nums := make([]string, len(dashes))
+	for i, d := range dashes {
+		total += d
+		nums[i] = pdfNum(d)
+	}
+	if total > 0 {
+		offset = math.Mod(offset, total)
+		if offset < 0 {
+			offset += total
+		}
+	}
+	return "[" + strings.Join(nums, " ") + "] " + pdfNum(offset) + " d"
+}
+
+func pdfMatrix(m canvas.Matrix) string {
+	return strings.Join([]string{pdfNum(m[0][0]), pdfNum(m[1][0]), pdfNum(m[0][1]), pdfNum(m[1][1]), pdfNum(m[0][2]), pdfNum(m[1][2])}, " ")
+}
+
+// pdfNum writes v with up to 5 decimals and no trailing zeros.
+func pdfNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e5)/1e5, 'f', -1, 64)
+}
+
+// pdfLiteral returns `s` as a literal string, which can hold any bytes.
+func pdfLiteral(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`).Replace(s) + ")"
+}
@@ -0,0 +1,116 @@
+package gart
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/pdf"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+var pdfXRefRx = regexp.MustCompile(`xref\n0 (\d+)\n0000000000 65535 f \n((?:\d{10} 00000 n \n)*)trailer\n`)
+
+// checkXRef checks every object is where the cross-reference table says.
+func checkXRef(t *testing.T, pdf []byte) {
+	m := pdfXRefRx.FindSubmatch(pdf)
+	if m == nil {
+		t.Fatalf("no xref table in %q", pdf)
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	entries := bytes.Split(bytes.TrimSuffix(m[2], []byte("\n")), []byte("\n"))
+	if len(entries) != n-1 {
+		t.Fatalf("xref has %d entries, want %d", len(entries), n-1)
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[:10]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("object %d isn't at %d", i+1, offset)
+		}
+	}
+}
+
+func TestPDFWriter(t *testing.T) {
+	f, err := ParseFont("Go", goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext(50, 40)
+	ctx.SetFillColor(color.NRGBA{255, 0, 0, 128})
+	ctx.SetStrokeColor(color.Black)
+	ctx.Circle(20, 20, 10)
+	ctx.FillStroke()
+	ctx.NewRaster(1).Set(35, 10, color.NRGBA{0, 0, 255, 100})
+	ctx.SetFont(f, 5)
+	ctx.DrawText("Hello", 5, 35, 0)
+
+	var buf bytes.Buffer
+	if err := PDFWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkXRef(t, buf.Bytes())
+	for _, want := range []string{"/CA 1 /ca 0.50196", " B ", "/Subtype /Image", "/SMask ", "/FontFile2 ", " TJ ET"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("PDF doesn't contain %q", want)
+		}
+	}
+}
+
+func TestPDFStrokes(t *testing.T) {
+	ctx := NewContext(20, 10)
+	ctx.SetStrokeColor(color.Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetLineCap(CapRound)
+	ctx.SetLineJoin(JoinBevel)
+	ctx.SetDashes(0.5, 1, 2)
+	ctx.MoveTo(1, 1)
+	ctx.LineTo(10, 9)
+	ctx.LineTo(19, 1)
+	ctx.Stroke()
+
+	var buf bytes.Buffer
+	if err := PDFWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"0.5 w", "1 J", "2 j", "[1 2] 0.5 d", " S"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("PDF doesn't contain %q", want)
+		}
+	}
+}
+
+// TestPDFPage checks the page is the size and in the units the canvas
+// writer used before gart had its own.
+func TestPDFPage(t *testing.T) {
+	c := canvas.New(210, 297)
+	cc := canvas.NewContext(c)
+	cc.SetFillColor(color.Black)
+	cc.DrawPath(10, 10, canvas.Rectangle(20, 30))
+	var want, got bytes.Buffer
+	if err := pdf.Writer(&want, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePDF(&got, c); err != nil {
+		t.Fatal(err)
+	}
+	mediaBox := regexp.MustCompile(`/MediaBox \[0 0 (\S+) (\S+)\]`)
+	for _, pdf := range []*bytes.Buffer{&want, &got} {
+		m := mediaBox.FindStringSubmatch(pdf.String())
+		if m == nil {
+			t.Fatalf("no MediaBox in %q", pdf.String())
+		}
+		w, _ := strconv.ParseFloat(m[1], 64)
+		h, _ := strconv.ParseFloat(m[2], 64)
+		if math.Abs(w-595.27559) > 1e-3 || math.Abs(h-841.88976) > 1e-3 {
+			t.Errorf("MediaBox is %gx%g, want A4 in points", w, h)
+		}
+	}
+	if !bytes.Contains(got.Bytes(), []byte("2.83465 0 0 2.83465 0 0 cm")) {
+		t.Errorf("PDF isn't scaled to mm")
+	}
+}
@@ -0,0 +1,300 @@
+package gart
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/tdewolff/canvas"
+)
+
+// PlotterOptions configures the HPGL and G-code writers.
+type PlotterOptions struct {
+	Tolerance float64 // mm, how far the polylines may stray from the curves they replace
+
+	// The plotter coordinates are the page's mm shifted by OriginX,OriginY.
+	// With FlipY the top left corner of the page is the origin and y grows
+	// down, like most pen plotters with their home at the back left.
+	OriginX, OriginY float64
+	FlipY            bool
+
+	DrawSpeed   float64 // mm per minute with the pen down
+	TravelSpeed float64 // mm per minute with the pen up
+
+	PenUp, PenDown float64 // G-code Z heights in mm
+	PenDelay       float64 // G-code pause in seconds after the pen moves, as G4 P which GRBL reads as seconds
+
+	UnitsPerMM float64 // HPGL plotter units per mm, 40 on nearly every plotter
+
+	Optimize *OptimizeOptions    // how to reorder the paths, nil to plot them in the order drawn
+	Stats    func(OptimizeStats) // if set, gets what Optimize did, ex. to log it
+}
+
+// DefaultPlotterOptions are used for the .hpgl and .gcode files written by SafeWrite.
+var DefaultPlotterOptions = PlotterOptions{
+	Tolerance:   0.1,
+	DrawSpeed:   1500,
+	TravelSpeed: 3000,
+	PenUp:       5,
+	PenDown:     0,
+	PenDelay:    0.15,
+	UnitsPerMM:  40,
+	Optimize:    newOptimizeOptions(DefaultOptimizeOptions),
+}
+
+// newOptimizeOptions returns a copy of `opts`, so changing
+// DefaultOptimizeOptions later doesn't change DefaultPlotterOptions.
+func newOptimizeOptions(opts OptimizeOptions) *OptimizeOptions {
+	return &opts
+}
+
+// PlotPath is a polyline drawn with the pen down, in page mm with y up.
+type PlotPath struct {
+	Points []canvas.Point
+	Color  color.RGBA // stroke color, handy to pick the pen
+}
+
+// Start returns the first point.
+func (pp PlotPath) Start() canvas.Point {
+	return pp.Points[0]
+}
+
+// End returns the last point.
+func (pp PlotPath) End() canvas.Point {
+	return pp.Points[len(pp.Points)-1]
+}
+
+// PlotPaths returns the stroked paths drawn on `d` flattened to polylines
+// no further than `tolerance` mm from the curves, in the order they were drawn.
+// Fills aren't plotted, only strokes, so a background rectangle is skipped.
+func PlotPaths(d Drawer, tolerance float64) ([]PlotPath, error) {
+	ctx, err := toContext(d)
+	if err != nil {
+		return nil, err
+	}
+	pc := &pathCollector{width: ctx.c.W, height: ctx.c.H, tolerance: tolerance}
+	flattenGroups(ctx.c).Render(pc)
+	return pc.paths, nil
+}
+
+// pathCollector is a canvas.Renderer that keeps the stroked paths.
+type pathCollector struct {
+	width, height float64
+	tolerance     float64
+	paths         []PlotPath
+}
+
+func (pc *pathCollector) Size() (float64, float64) {
+	return pc.width, pc.height
+}
+
+func (pc *pathCollector) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if style.StrokeColor.A == 0 || style.StrokeWidth <= 0 {
+		return
+	}
+	path = path.Transform(m)
+	if len(style.Dashes) > 0 {
+		path = path.Dash(style.DashOffset, style.Dashes...)
+	}
+	for _, points := range flatten(path, pc.tolerance) {
+		pc.paths = append(pc.paths, PlotPath{Points: points, Color: style.StrokeColor})
+	}
+}
+
+func (pc *pathCollector) RenderText(text *canvas.Text, m canvas.Matrix) {}
+
+// RenderImage plots the painted paths with the colors standing in for the
+// paints, and the parts of the paths in clip groups inside the clip.
+func (pc *pathCollector) RenderImage(img image.Image, m canvas.Matrix) {
+	if pi, ok := img.(paintImage); ok {
+		pc.RenderPath(pi.path, pi.style, pi.m)
+	}
+	if g, ok := img.(groupImage); ok && g.clip != nil {
+		inner := &pathCollector{width: pc.width, height: pc.height, tolerance: pc.tolerance}
+		g.c.Render(inner)
+		rings := g.clip.clipRings(pc.tolerance)
+		for _, p := range inner.paths {
+			for _, points := range clipPolyline(p.Points, rings) {
+				pc.paths = append(pc.paths, PlotPath{Points: points, Color: p.Color})
+			}
+		}
+	}
+}
+
+// flatten turns `p` into polylines, one per subpath with at least two points.
+func flatten(p *canvas.Path, tolerance float64) [][]canvas.Point {
+	var lines [][]canvas.Point
+	var cur []canvas.Point
+	flush := func() {
+		if len(cur) > 1 {
+			lines = append(lines, cur)
+		}
+		cur = nil
+	}
+	p.ReplaceArcs().Iterate(
+		func(_, end canvas.Point) {
+			flush()
+			cur = []canvas.Point{end}
+		},
+		func(_, end canvas.Point) {
+			cur = append(cur, end)
+		},
+		func(start, cp, end canvas.Point) {
+			// as a cubic, control points are 2/3 of the way to cp
+			cp1 := start.Add(cp.Sub(start).Mul(2.0 / 3.0))
+			cp2 := end.Add(cp.Sub(end).Mul(2.0 / 3.0))
+			cur = flattenCubic(cur, start, cp1, cp2, end, tolerance, 0)
+		},
+		func(start, cp1, cp2, end canvas.Point) {
+			cur = flattenCubic(cur, start, cp1, cp2, end, tolerance, 0)
+		},
+		func(_ canvas.Point, _, _, _ float64, _, _ bool, end canvas.Point) {
+			cur = append(cur, end) // there are none after ReplaceArcs
+		},
+		func(_, end canvas.Point) {
+			cur = append(cur, end)
+		},
+	)
+	flush()
+	return lines
+}
+
+const maxFlattenDepth = 16
+
+// flattenCubic appends the points after p0 of the cubic Bézier, splitting it in
+// half until the control points are within `tolerance` of the chord.
+func flattenCubic(points []canvas.Point, p0, p1, p2, p3 canvas.Point, tolerance float64, depth int) []canvas.Point {
+	if depth >= maxFlattenDepth || (distToLine(p1, p0, p3) <= tolerance && distToLine(p2, p0, p3) <= tolerance) {
+		return append(points, p3)
+	}
+	p01, p12, p23 := p0.Interpolate(p1, 0.5), p1.Interpolate(p2, 0.5), p2.Interpolate(p3, 0.5)
+	p012, p123 := p01.Interpolate(p12, 0.5), p12.Interpolate(p23, 0.5)
+	mid := p012.Interpolate(p123, 0.5)
+	points = flattenCubic(points, p0, p01, p012, mid, tolerance, depth+1)
+	return flattenCubic(points, mid, p123, p23, p3, tolerance, depth+1)
+}
+
+// distToLine is the distance from p to the segment a,b.
+func distToLine(p, a, b canvas.Point) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return p.Sub(a).Length()
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return p.Sub(a.Add(ab.Mul(t))).Length()
+}
+
+// plotPaths returns the paths to plot, optimized if asked to.
+func (opts PlotterOptions) plotPaths(d Drawer) ([]PlotPath, error) {
+	paths, err := PlotPaths(d, opts.Tolerance)
+	if err != nil || opts.Optimize == nil {
+		return paths, err
+	}
+	paths, stats := Optimize(paths, *opts.Optimize)
+	if opts.Stats != nil {
+		opts.Stats(stats)
+	}
+	return paths, nil
+}
+
+// toPlotter converts a page point to the plotter's mm.
+func (opts PlotterOptions) toPlotter(p canvas.Point, height float64) (float64, float64) {
+	if opts.FlipY {
+		return opts.OriginX + p.X, opts.OriginY + height - p.Y
+	}
+	return opts.OriginX + p.X, opts.OriginY + p.Y
+}
+
+// HPGLWriter writes the strokes as HPGL for pen plotters.
+// HPGL has no comments so these files carry no provenance.
+func HPGLWriter(opts PlotterOptions) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		paths, err := opts.plotPaths(d)
+		if err != nil {
+			return err
+		}
+		_, height := d.Size()
+		return writeHPGL(w, paths, height, opts)
+	}
+}
+
+func writeHPGL(w io.Writer, paths []PlotPath, height float64, opts PlotterOptions) error {
+	bw := bufio.NewWriter(w)
+	units := func(p canvas.Point) string {
+		x, y := opts.toPlotter(p, height)
+		return fmt.Sprintf("%d,%d", int(math.Round(x*opts.UnitsPerMM)), int(math.Round(y*opts.UnitsPerMM)))
+	}
+	fmt.Fprintf(bw, "IN;SP1;VS%s;\n", formatNum(opts.DrawSpeed/600)) // cm per second
+	for _, pp := range paths {
+		fmt.Fprintf(bw, "PU%s;PD", units(pp.Start()))
+		for i, p := range pp.Points[1:] {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString(units(p))
+		}
+		bw.WriteString(";\n")
+	}
+	bw.WriteString("PU;SP0;\n")
+	return bw.Flush()
+}
+
+// GCodeWriter writes the strokes as G-code for pen plotters that raise
+// and lower the pen along Z, with the provenance in a comment.
+func GCodeWriter(opts PlotterOptions) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		paths, err := opts.plotPaths(d)
+		if err != nil {
+			return err
+		}
+		_, height := d.Size()
+		return writeGCode(w, paths, height, d.Provenance(), opts)
+	}
+}
+
+func writeGCode(w io.Writer, paths []PlotPath, height float64, prov *Provenance, opts PlotterOptions) error {
+	bw := bufio.NewWriter(w)
+	xy := func(p canvas.Point) string {
+		x, y := opts.toPlotter(p, height)
+		return "X" + formatNum(x) + " Y" + formatNum(y)
+	}
+	pen := func(z float64) {
+		fmt.Fprintf(bw, "G0 Z%s\n", formatNum(z))
+		if opts.PenDelay > 0 {
+			fmt.Fprintf(bw, "G4 P%s\n", formatNum(opts.PenDelay))
+		}
+	}
+	if prov != nil {
+		fmt.Fprintf(bw, "; %s %s\n", provenanceKey, prov.json())
+	}
+	bw.WriteString("G21 ; mm\nG90 ; absolute\n")
+	pen(opts.PenUp)
+	for _, pp := range paths {
+		fmt.Fprintf(bw, "G0 %s F%s\n", xy(pp.Start()), formatNum(opts.TravelSpeed))
+		pen(opts.PenDown)
+		for i, p := range pp.Points[1:] {
+			if i == 0 {
+				fmt.Fprintf(bw, "G1 %s F%s\n", xy(p), formatNum(opts.DrawSpeed))
+			} else {
+				fmt.Fprintf(bw, "G1 %s\n", xy(p))
+			}
+		}
+		pen(opts.PenUp)
+	}
+	bw.WriteString("G0 X0 Y0\n")
+	return bw.Flush()
+}
+
+func extractGCode(data []byte) (string, error) {
+	return extractComment(data, ";")
+}
+
+// formatNum writes v with up to 3 decimals and no trailing zeros.
+func formatNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,77 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPlotPaths(t *testing.T) {
+	ctx := NewContext(100, 50)
+	ctx.SetFillColor(color.White)
+	ctx.FillRect(0, 0, 100, 50) // not stroked so not plotted
+	ctx.SetStrokeColor(color.Black)
+	ctx.MoveTo(10, 10)
+	ctx.LineTo(20, 10)
+	ctx.LineTo(20, 20)
+	ctx.Stroke()
+	ctx.Circle(50, 25, 10)
+	ctx.Stroke()
+
+	paths, err := PlotPaths(ctx, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("got %d paths, want 2", len(paths))
+	}
+	if got := len(paths[0].Points); got != 3 {
+		t.Errorf("the polyline has %d points, want 3", got)
+	}
+	circle := paths[1]
+	if circle.Start() != circle.End() {
+		t.Errorf("the circle isn't closed: %v to %v", circle.Start(), circle.End())
+	}
+	for i := 1; i < len(circle.Points); i++ {
+		mid := circle.Points[i-1].Interpolate(circle.Points[i], 0.5)
+		if r := math.Hypot(mid.X-50, mid.Y-25); 10-r > 0.05+1e-9 {
+			t.Errorf("segment %d strays %.3f mm from the circle", i, 10-r)
+		}
+	}
+}
+
+func TestPlotterWriters(t *testing.T) {
+	ctx := NewContext(100, 50)
+	ctx.SetStrokeColor(color.Black)
+	ctx.MoveTo(10, 10)
+	ctx.LineTo(20, 15)
+	ctx.Stroke()
+
+	opts := DefaultPlotterOptions
+	opts.FlipY = true
+	var stats []OptimizeStats
+	opts.Stats = func(s OptimizeStats) { stats = append(stats, s) }
+	var buf bytes.Buffer
+	if err := HPGLWriter(opts)(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].PathsBefore != 1 {
+		t.Errorf("Stats got %v, want one call with 1 path before", stats)
+	}
+	if want := "PU400,1600;PD800,1400;"; !strings.Contains(buf.String(), want) {
+		t.Errorf("HPGL = %q, want it to contain %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	opts.OriginX = 5
+	if err := GCodeWriter(opts)(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"G0 X15 Y40 F3000\nG0 Z0\n", "G1 X25 Y35 F1500\nG0 Z5\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("G-code = %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package gart
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/rasterizer"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/vector"
+)
+
+// PNGOptions configures PNG files, ex. a quick low-res preview while
+// iterating or a 600 dpi file for print.
+type PNGOptions struct {
+	Resolution canvas.DPMM // dots per mm, the default when 0
+
+	// Width and Height in pixels override the Resolution, picking the one
+	// that fits the page in them. Either can be 0 to only fit the other.
+	Width, Height int
+
+	NoAntialias bool        // paint the pixels at least half covered, in full
+	Background  color.Color // painted under the drawing, nil leaves it transparent
+	Depth16     bool        // 16 bits per channel instead of 8
+}
+
+// DPI returns the resolution for `dpi` dots per inch.
+func DPI(dpi float64) canvas.DPMM {
+	return canvas.DPMM(dpi / 25.4)
+}
+
+// resolution returns the dots per mm for a page of width x height mm.
+func (opts PNGOptions) resolution(width, height float64) float64 {
+	res := math.Inf(1)
+	if opts.Width > 0 {
+		res = float64(opts.Width) / width
+	}
+	if opts.Height > 0 {
+		res = math.Min(res, float64(opts.Height)/height)
+	}
+	if !math.IsInf(res, 1) {
+		return res
+	}
+	if opts.Resolution > 0 {
+		return float64(opts.Resolution)
+	}
+	return float64(defaultResolution)
+}
+
+// Rasterize draws `d` into a new image.
+// A GGContext was rasterized when it was drawn, so it's only resampled to
+// the size asked for and anti-aliasing can't be turned off.
+func (opts PNGOptions) Rasterize(d Drawer) (image.Image, error) {
+	width, height := d.Size()
+	res := opts.resolution(width, height)
+	rect := image.Rect(0, 0, int(width*res+0.5), int(height*res+0.5))
+	var img draw.Image = image.NewRGBA(rect)
+	if opts.Depth16 {
+		img = image.NewRGBA64(rect)
+	}
+	if opts.Background != nil {
+		draw.Draw(img, rect, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	if g, ok := d.(*GGContext); ok {
+		src := g.Image()
+		if src.Bounds().Size() == rect.Size() {
+			draw.Draw(img, rect, src, src.Bounds().Min, draw.Over)
+		} else if opts.NoAntialias {
+			draw.NearestNeighbor.Scale(img, rect, src, src.Bounds(), draw.Over, nil)
+		} else {
+			draw.CatmullRom.Scale(img, rect, src, src.Bounds(), draw.Over, nil)
+		}
+		return img, nil
+	}
+	ctx, err := toContext(d)
+	if err != nil {
+		return nil, err
+	}
+	drawCanvas(img, ctx.c, res, !opts.NoAntialias)
+	return img, nil
+}
+
+// Writer returns the WriterFunc for PNG files with these options.
+func (opts PNGOptions) Writer() WriterFunc {
+	return withProvenance(func(w io.Writer, d Drawer) error {
+		img, err := opts.Rasterize(d)
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	}, embedPNG)
+}
+
+// PNGFlags defines the -dpi, -px, -noaa, -bg and -png16 flags and registers
+// a .png writer that uses them, see PNGOptions.
+func PNGFlags() *PNGOptions {
+	opts := &PNGOptions{}
+	flag.Var(dpiValue{&opts.Resolution}, "dpi", "PNG resolution in dots per inch")
+	flag.Var(pxValue{opts}, "px", "PNG size in pixels to fit the page in, ex. 1200x1200, 1200x or x800, overrides -dpi")
+	flag.BoolVar(&opts.NoAntialias, "noaa", false, "Turn off anti-aliasing in PNGs")
+	flag.Var(colorValue{&opts.Background}, "bg", "PNG background, ex. white or #f5f5f5, transparent by default")
+	flag.BoolVar(&opts.Depth16, "png16", false, "Write 16 bit PNGs")
+	RegisterFormat(".png", func(w io.Writer, d Drawer) error {
+		return opts.Writer()(w, d)
+	})
+	return opts
+}
+
+type dpiValue struct{ res *canvas.DPMM }
+
+func (v dpiValue) String() string {
+	if v.res == nil || *v.res == 0 {
+		return ""
+	}
+	return formatNum(float64(*v.res) * 25.4)
+}
+
+func (v dpiValue) Set(s string) error {
+	dpi, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if dpi <= 0 {
+		return errors.New("dpi must be positive")
+	}
+	*v.res = DPI(dpi)
+	return nil
+}
+
+type pxValue struct{ opts *PNGOptions }
+
+func (v pxValue) String() string {
+	if v.opts == nil || (v.opts.Width == 0 && v.opts.Height == 0) {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", v.opts.Width, v.opts.Height)
+}
+
+func (v pxValue) Set(s string) error {
+	wh := strings.Split(s, "x")
+	if len(wh) != 2 || (wh[0] == "" && wh[1] == "") {
+		return fmt.Errorf("want a size like 1200x800, got %q", s)
+	}
+	var px [2]int
+	for i, n := range wh {
+		if n == "" {
+			continue
+		}
+		var err error
+		if px[i], err = strconv.Atoi(n); err != nil || px[i] < 0 {
+			return fmt.Errorf("bad size %q", n)
+		}
+	}
+	v.opts.Width, v.opts.Height = px[0], px[1]
+	return nil
+}
+
+type colorValue struct{ col *color.Color }
+
+func (v colorValue) String() string {
+	if v.col == nil || *v.col == nil {
+		return ""
+	}
+	r, g, b, a := (*v.col).RGBA()
+	return fmt.Sprintf("#%02x%02x%02x%02x", r>>8, g>>8, b>>8, a>>8)
+}
+
+func (v colorValue) Set(s string) error {
+	col, err := ParseColor(s)
+	if err != nil {
+		return err
+	}
+	*v.col = col
+	return nil
+}
+
+// ParseColor reads "#rgb", "#rrggbb", "#rrggbbaa", "white", "black" or
+// "transparent", which is nil.
+func ParseColor(s string) (color.Color, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", "transparent", "none":
+		return nil, nil
+	case "white":
+		return color.White, nil
+	case "black":
+		return color.Black, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("bad color %q", s)
+	}
+	return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// aliased is a canvas.Renderer that rasterizes paths without anti-aliasing,
+// the rest is left to the canvas rasterizer.
+type aliased struct {
+	*rasterizer.Renderer
+	img        draw.Image
+	resolution float64
+}
+
+func (a *aliased) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	path = path.Transform(m)
+	strokeWidth := 0.0
+	if stroked(style) {
+		strokeWidth = style.StrokeWidth
+	}
+	x0, y0, x1, y1 := pixelBox(path.Bounds(), strokeWidth, a.resolution, a.img.Bounds().Size())
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+	path = path.Translate(-float64(x0)/a.resolution, -float64(y0)/a.resolution)
+	if style.FillColor.A != 0 {
+		a.fill(path, style.FillColor, x0, y0, x1, y1)
+	}
+	if stroked(style) {
+		a.fill(strokeOutline(path, style), style.StrokeColor, x0, y0, x1, y1)
+	}
+}
+
+func (a *aliased) RenderText(text *canvas.Text, m canvas.Matrix) {
+	canvas.RenderTextAsPath(a, text, m)
+}
+
+// fill paints `path`, which starts at pixel x0,y0, in the box up to x1,y1.
+func (a *aliased) fill(path *canvas.Path, col color.RGBA, x0, y0, x1, y1 int) {
+	mask := coverage(path, x1-x0, y1-y0, a.resolution, false)
+	height := a.img.Bounds().Dy()
+	draw.DrawMask(a.img, image.Rect(x0, height-y1, x1, height-y0), image.NewUniform(col), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// pixelBox returns the pixels covered by `b` plus `margin` mm, with y going
+// up, on a page of `size` pixels.
+func pixelBox(b canvas.Rect, margin, res float64, size image.Point) (x0, y0, x1, y1 int) {
+	x0 = int(math.Max(0, math.Floor((b.X-margin)*res)))
+	y0 = int(math.Max(0, math.Floor((b.Y-margin)*res)))
+	x1 = int(math.Min(float64(size.X), math.Ceil((b.X+b.W+margin)*res)))
+	y1 = int(math.Min(float64(size.Y), math.Ceil((b.Y+b.H+margin)*res)))
+	return x0, y0, x1, y1
+}
+
+// coverage returns the mask of `path`, which starts at pixel 0,0, in a w x h
+// box. Without antialias the pixels at least half covered are in full.
+func coverage(path *canvas.Path, w, h int, res float64, antialias bool) *image.Alpha {
+	ras := vector.NewRasterizer(w, h)
+	path.ToRasterizer(ras, res)
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	if !antialias {
+		for i, v := range mask.Pix {
+			if v >= 0x80 {
+				mask.Pix[i] = 0xff
+			} else {
+				mask.Pix[i] = 0
+			}
+		}
+	}
+	return mask
+}
@@ -0,0 +1,75 @@
+package gart
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPNGOptions(t *testing.T) {
+	ctx := NewContext(100, 50)
+	ctx.SetFillColor(color.Black)
+	ctx.Circle(50, 25, 20)
+	ctx.Fill()
+
+	tests := []struct {
+		name          string
+		opts          PNGOptions
+		width, height int
+	}{
+		{"default", PNGOptions{}, 320, 160},
+		{"dpi", PNGOptions{Resolution: DPI(254)}, 1000, 500},
+		{"width", PNGOptions{Width: 200}, 200, 100},
+		{"fit", PNGOptions{Width: 200, Height: 50}, 100, 50},
+	}
+	for _, test := range tests {
+		img, err := test.opts.Rasterize(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := img.Bounds().Size(); size.X != test.width || size.Y != test.height {
+			t.Errorf("%s: got %v, want %dx%d", test.name, size, test.width, test.height)
+		}
+	}
+
+	img, err := PNGOptions{NoAntialias: true, Background: color.White, Depth16: true}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.RGBA64); !ok {
+		t.Errorf("got a %T, want 16 bits", img)
+	}
+	if r, _, _, a := img.At(0, 0).RGBA(); r != 0xffff || a != 0xffff {
+		t.Errorf("the corner isn't the white background")
+	}
+	if r, _, _, _ := img.At(160, 80).RGBA(); r != 0 {
+		t.Errorf("the circle wasn't drawn")
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r != 0 && r != 0xffff {
+				t.Fatalf("pixel %d,%d is anti-aliased", x, y)
+			}
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := map[string]color.Color{
+		"":          nil,
+		"white":     color.White,
+		"#f5f5f5":   color.NRGBA{0xf5, 0xf5, 0xf5, 0xff},
+		"#abc":      color.NRGBA{0xaa, 0xbb, 0xcc, 0xff},
+		"#01020380": color.NRGBA{1, 2, 3, 0x80},
+	}
+	for in, want := range tests {
+		got, err := ParseColor(in)
+		if err != nil || got != want {
+			t.Errorf("ParseColor(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseColor("#12345"); err == nil {
+		t.Errorf("a 5 digit color should fail")
+	}
+}
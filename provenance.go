@@ -0,0 +1,384 @@
+package gart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Version of gart, recorded in the provenance of every file written.
+const Version = "0.2.0"
+
+const (
+	// provenanceKey is the PNG keyword, JPEG, EPS and G-code comment prefix for the record.
+	provenanceKey = "gart:provenance"
+	// provenanceID is the id of the SVG <metadata> element (ids can't hold a colon).
+	provenanceID = "gart-provenance"
+)
+
+// ErrNoProvenance is returned by ReadProvenance when the file has no record.
+var ErrNoProvenance = errors.New("no gart provenance found")
+
+// Provenance records what is needed to reproduce a render.
+type Provenance struct {
+	Sketch    string            `json:"sketch"`
+	Seed      string            `json:"seed"` // hex, as passed to -seed
+	GitHash   string            `json:"git_hash"`
+	Flags     map[string]string `json:"flags,omitempty"` // flags set on the command line
+	Timestamp time.Time         `json:"timestamp"`
+	Version   string            `json:"version"`
+}
+
+// Provenance returns the record for a file written with `prefix`.
+func (s Seed) Provenance(prefix string) *Provenance {
+	p := &Provenance{
+		Sketch:    sketchName(prefix),
+		Seed:      fmt.Sprintf("%x", s.intSeed),
+		GitHash:   getGitHash(),
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+		Version:   Version,
+	}
+	if flag.Parsed() {
+		flag.Visit(func(f *flag.Flag) {
+			if p.Flags == nil {
+				p.Flags = map[string]string{}
+			}
+			p.Flags[f.Name] = f.Value.String()
+		})
+	}
+	return p
+}
+
+// sketchName turns a prefix like "samples/substrate-" into "substrate".
+func sketchName(prefix string) string {
+	return strings.Trim(path.Base(prefix), "-_.")
+}
+
+// ReadProvenance reads back the record embedded by SafeWrite.
+// PNG, JPEG, SVG, PDF, EPS and G-code files carry one.
+func ReadProvenance(fname string) (*Provenance, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var raw string
+	switch strings.ToLower(path.Ext(fname)) {
+	case ".png":
+		raw, err = extractPNG(data)
+	case ".jpg", ".jpeg":
+		raw, err = extractJPEG(data)
+	case ".svg":
+		raw, err = extractSVG(data)
+	case ".pdf":
+		raw, err = extractPDF(data)
+	case ".eps":
+		raw, err = extractEPS(data)
+	case ".gcode":
+		raw, err = extractGCode(data)
+	default:
+		return nil, fmt.Errorf("can't read provenance from %s files", path.Ext(fname))
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &Provenance{}
+	if err := json.Unmarshal([]byte(raw), p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// embedFunc adds the provenance to an encoded file.
+type embedFunc func(data []byte, p *Provenance) ([]byte, error)
+
+// withProvenance wraps `fn` so the files it writes carry the context's provenance.
+func withProvenance(fn WriterFunc, embed embedFunc) WriterFunc {
+	return func(w io.Writer, d Drawer) error {
+		p := d.Provenance()
+		if p == nil {
+			return fn(w, d)
+		}
+		var buf bytes.Buffer
+		if err := fn(&buf, d); err != nil {
+			return err
+		}
+		data, err := embed(buf.Bytes(), p)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+}
+
+func (p *Provenance) json() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// embedPNG adds tEXt chunks for the common keywords and an iTXt chunk
+// with the full record right after the IHDR chunk.
+func embedPNG(data []byte, p *Provenance) ([]byte, error) {
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < ihdrEnd || !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("png: bad header")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:ihdrEnd])
+	writePNGChunk(&buf, "tEXt", []byte("Software\x00gart "+p.Version))
+	writePNGChunk(&buf, "tEXt", []byte("Creation Time\x00"+p.Timestamp.Format(time.RFC1123Z)))
+	writePNGChunk(&buf, "tEXt", []byte("Comment\x00"+p.Sketch+" seed "+p.Seed+" git "+p.GitHash))
+	writePNGChunk(&buf, "iTXt", []byte(provenanceKey+"\x00\x00\x00\x00\x00"+p.json()))
+	buf.Write(data[ihdrEnd:])
+	return buf.Bytes(), nil
+}
+
+func writePNGChunk(buf *bytes.Buffer, kind string, data []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	buf.Write(n[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	buf.WriteString(kind)
+	buf.Write(data)
+	binary.BigEndian.PutUint32(n[:], crc.Sum32())
+	buf.Write(n[:])
+}
+
+func extractPNG(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return "", errors.New("png: bad header")
+	}
+	for pos := len(pngSignature); pos+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		if pos+12+n > len(data) {
+			break
+		}
+		chunk := data[pos+8 : pos+8+n]
+		pos += 12 + n
+		if kind != "iTXt" && kind != "tEXt" {
+			continue
+		}
+		fields := bytes.SplitN(chunk, []byte{0}, 2)
+		if len(fields) != 2 || string(fields[0]) != provenanceKey {
+			continue
+		}
+		text := fields[1]
+		if kind == "iTXt" {
+			// compression flag, method, language and translated keyword
+			if len(text) < 2 || text[0] != 0 {
+				return "", errors.New("png: compressed provenance isn't supported")
+			}
+			parts := bytes.SplitN(text[2:], []byte{0}, 3)
+			if len(parts) != 3 {
+				return "", errors.New("png: bad iTXt chunk")
+			}
+			text = parts[2]
+		}
+		return string(text), nil
+	}
+	return "", ErrNoProvenance
+}
+
+// embedJPEG adds a COM segment after the SOI marker.
+func embedJPEG(data []byte, p *Provenance) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errors.New("jpeg: bad header")
+	}
+	comment := provenanceKey + " " + p.json()
+	if len(comment)+2 > 0xffff {
+		return nil, errors.New("jpeg: provenance too long")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:2])
+	buf.Write([]byte{0xff, 0xfe, byte((len(comment) + 2) >> 8), byte(len(comment) + 2)})
+	buf.WriteString(comment)
+	buf.Write(data[2:])
+	return buf.Bytes(), nil
+}
+
+func extractJPEG(data []byte) (string, error) {
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xff; {
+		marker := data[pos+1]
+		if marker == 0xda { // start of scan, no more headers
+			break
+		}
+		n := int(data[pos+2])<<8 | int(data[pos+3])
+		if pos+2+n > len(data) {
+			break
+		}
+		seg := data[pos+4 : pos+2+n]
+		pos += 2 + n
+		if marker == 0xfe && bytes.HasPrefix(seg, []byte(provenanceKey+" ")) {
+			return string(seg[len(provenanceKey)+1:]), nil
+		}
+	}
+	return "", ErrNoProvenance
+}
+
+// embedSVG adds a <metadata> element as the first child of <svg>.
+func embedSVG(data []byte, p *Provenance) ([]byte, error) {
+	start := bytes.Index(data, []byte("<svg"))
+	if start < 0 {
+		return nil, errors.New("svg: no <svg> element")
+	}
+	end := bytes.IndexByte(data[start:], '>')
+	if end < 0 {
+		return nil, errors.New("svg: no <svg> element")
+	}
+	end += start + 1
+	var buf bytes.Buffer
+	buf.Write(data[:end])
+	buf.WriteString(`<metadata id="` + provenanceID + `">`)
+	xml.EscapeText(&buf, []byte(p.json()))
+	buf.WriteString(`</metadata>`)
+	buf.Write(data[end:])
+	return buf.Bytes(), nil
+}
+
+var svgMetadataRx = regexp.MustCompile(`<metadata id="` + provenanceID + `">([^<]*)</metadata>`)
+
+func extractSVG(data []byte) (string, error) {
+	m := svgMetadataRx.FindSubmatch(data)
+	if m == nil {
+		return "", ErrNoProvenance
+	}
+	return html.UnescapeString(string(m[1])), nil
+}
+
+var (
+	pdfStartXRefRx = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	pdfSizeRx      = regexp.MustCompile(`/Size (\d+)`)
+	pdfRootRx      = regexp.MustCompile(`/Root (\d+ \d+ R)`)
+	pdfInfoRx      = regexp.MustCompile(`/Info (\d+) 0 R`)
+	pdfGartRx      = regexp.MustCompile(`/GartProvenance <([0-9A-Fa-f]*)>`)
+)
+
+// embedPDF appends an incremental update that replaces the document info
+// dictionary with one holding the record.
+func embedPDF(data []byte, p *Provenance) ([]byte, error) {
+	errNoTrailer := errors.New("pdf: can't find the trailer")
+	i := bytes.LastIndex(data, []byte("trailer"))
+	if i < 0 { // ex. a cross-reference stream
+		return nil, errNoTrailer
+	}
+	xref := pdfStartXRefRx.FindSubmatch(data)
+	trailer := data[i:]
+	size := pdfSizeRx.FindSubmatch(trailer)
+	root := pdfRootRx.FindSubmatch(trailer)
+	info := pdfInfoRx.FindSubmatch(trailer)
+	if xref == nil || size == nil || root == nil || info == nil {
+		return nil, errNoTrailer
+	}
+	infoObj, _ := strconv.Atoi(string(info[1]))
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	buf.WriteString("\n")
+	offset := buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Producer %s /Creator %s /Title %s /Subject %s /Keywords %s /CreationDate %s /GartProvenance %s >>\nendobj\n",
+		infoObj,
+		pdfString("gart "+p.Version),
+		pdfString(p.Sketch),
+		pdfString(p.Sketch+" "+p.Seed),
+		pdfString("seed "+p.Seed+" git "+p.GitHash),
+		pdfString(strings.Join(flagList(p.Flags), " ")),
+		pdfString(p.Timestamp.Format("D:20060102150405Z")),
+		pdfString(p.json()))
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n%d 1\n%010d 00000 n \n", infoObj, offset)
+	fmt.Fprintf(&buf, "trailer\n<< /Info %d 0 R /Prev %s /Root %s /Size %s >>\nstartxref\n%d\n%%%%EOF", infoObj, xref[1], root[1], size[1], xrefOffset)
+	return buf.Bytes(), nil
+}
+
+// pdfString encodes s as a UTF-16BE hex string, which survives any content.
+func pdfString(s string) string {
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+func extractPDF(data []byte) (string, error) {
+	all := pdfGartRx.FindAllSubmatch(data, -1)
+	if all == nil {
+		return "", ErrNoProvenance
+	}
+	raw, err := hex.DecodeString(string(all[len(all)-1][1]))
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < 2 || raw[0] != 0xfe || raw[1] != 0xff || len(raw)%2 != 0 {
+		return "", errors.New("pdf: bad provenance string")
+	}
+	units := make([]uint16, 0, len(raw)/2-1)
+	for i := 2; i < len(raw); i += 2 {
+		units = append(units, binary.BigEndian.Uint16(raw[i:]))
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// embedEPS adds a comment line after the %!PS header.
+func embedEPS(data []byte, p *Provenance) ([]byte, error) {
+	eol := bytes.IndexByte(data, '\n')
+	if eol < 0 {
+		return nil, errors.New("eps: bad header")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:eol+1])
+	buf.WriteString("%" + provenanceKey + " " + p.json() + "\n")
+	buf.Write(data[eol+1:])
+	return buf.Bytes(), nil
+}
+
+func extractEPS(data []byte) (string, error) {
+	return extractComment(data, "%")
+}
+
+// extractComment looks for the record in the comment lines, starting with
+// `comment`, at the top of the file.
+func extractComment(data []byte, comment string) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, comment) {
+			break
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, comment))
+		if strings.HasPrefix(line, provenanceKey+" ") {
+			return strings.TrimPrefix(line, provenanceKey+" "), nil
+		}
+	}
+	return "", ErrNoProvenance
+}
+
+// flagList returns the flags in -name=value form, sorted by name.
+func flagList(flags map[string]string) []string {
+	list := make([]string, 0, len(flags))
+	for name, val := range flags {
+		list = append(list, "-"+name+"="+val)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,69 @@
+package gart
+
+import (
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewContext(20, 10)
+	ctx.SetFillColor(color.Gray{200})
+	ctx.FillRect(0, 0, 20, 10)
+	want := &Provenance{
+		Sketch:    "test",
+		Seed:      "1f3e",
+		GitHash:   "abc1234",
+		Flags:     map[string]string{"formats": "png,svg", "title": "(ü) <&>"},
+		Timestamp: time.Date(2020, 10, 21, 15, 32, 14, 0, time.UTC),
+		Version:   Version,
+	}
+	ctx.SetProvenance(want)
+
+	for _, ext := range []string{".png", ".jpg", ".svg", ".pdf", ".eps", ".gcode"} {
+		fname := filepath.Join(dir, "test"+ext)
+		writer, _ := LookupFormat(ext)
+		if err := ctx.WriteFile(fname, writer); err != nil {
+			t.Fatalf("%s: WriteFile: %v", ext, err)
+		}
+		if ext == ".png" || ext == ".jpg" {
+			f, err := os.Open(fname)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = image.Decode(f)
+			f.Close()
+			if err != nil {
+				t.Errorf("%s: Decode: %v", ext, err)
+			}
+		}
+		got, err := ReadProvenance(fname)
+		if err != nil {
+			t.Fatalf("%s: ReadProvenance: %v", ext, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", ext, got, want)
+		}
+	}
+}
+
+func TestEmbedPDFNoTrailer(t *testing.T) {
+	// a cross-reference stream PDF has no trailer keyword
+	data := []byte("%PDF-1.5\n1 0 obj\n<< /Type /XRef >>\nstream\nendstream\nendobj\nstartxref\n9\n%%EOF")
+	if _, err := embedPDF(data, &Provenance{}); err == nil {
+		t.Error("embedPDF without a trailer didn't fail")
+	}
+}
@@ -0,0 +1,91 @@
+package gart
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"reflect"
+)
+
+// Float64 returns a pseudo-random number in [0.0,1.0)
+func (s Seed) Float64() float64 {
+	return s.rng.Float64()
+}
+
+// Intn returns a pseudo-random number in [0,n)
+func (s Seed) Intn(n int) int {
+	return s.rng.Intn(n)
+}
+
+// NormFloat64 returns a normally distributed number with mean 0 and std 1
+func (s Seed) NormFloat64() float64 {
+	return s.rng.NormFloat64()
+}
+
+// Range returns a pseudo-random number in [low,high)
+func (s Seed) Range(low, high float64) float64 {
+	if high < low {
+		low, high = high, low
+	}
+	return s.rng.Float64()*(high-low) + low
+}
+
+// Choice returns a random element of `slice`, which must be a non empty slice.
+//   lsys := g.Choice(lsystems).(lsystem)
+func (s Seed) Choice(slice interface{}) interface{} {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		panic("gart: Choice needs a non empty slice")
+	}
+	return v.Index(s.rng.Intn(v.Len())).Interface()
+}
+
+// Shuffle randomizes the order of n elements using `swap`, see rand.Shuffle
+func (s Seed) Shuffle(n int, swap func(i, j int)) {
+	s.rng.Shuffle(n, swap)
+}
+
+// Weighted returns a random index into `weights` with a probability
+// proportional to its weight. Negative weights count as zero, and like
+// Choice it panics when there's nothing to pick, no weight above zero.
+func (s Seed) Weighted(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	if total <= 0 {
+		panic("gart: Weighted needs a weight above zero")
+	}
+	r := s.rng.Float64() * total
+	last := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			return i
+		}
+		r -= w
+		last = i
+	}
+	return last
+}
+
+// Stream returns a new random source derived from this seed and `name`.
+// The sequence only depends on the seed and the name, so adding a random
+// call in one part of a sketch doesn't reshuffle the others.
+func (s Seed) Stream(name string) Seed {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	sub := int64(splitMix64(uint64(s.intSeed) ^ h.Sum64()))
+	return Seed{intSeed: sub, rng: rand.New(rand.NewSource(sub))}
+}
+
+// splitMix64 scrambles x so nearby seeds give unrelated sequences.
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
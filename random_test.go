@@ -0,0 +1,126 @@
+package gart
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// draws makes one call to each helper and returns the results.
+func draws(s Seed) []interface{} {
+	perm := []int{0, 1, 2, 3, 4}
+	s.Shuffle(len(perm), func(i, j int) { perm[i], perm[j] = perm[j], perm[i] })
+	return []interface{}{
+		s.Float64(),
+		s.Intn(1000),
+		s.NormFloat64(),
+		s.Range(-5, 5),
+		s.Choice([]string{"a", "b", "c", "d"}),
+		perm,
+		s.Weighted([]float64{1, 2, 3}),
+	}
+}
+
+func TestSeedReproducible(t *testing.T) {
+	a, _ := Init("1f3e")
+	b, _ := Init("1f3e")
+	for i := 0; i < 10; i++ {
+		if da, db := draws(a), draws(b); !reflect.DeepEqual(da, db) {
+			t.Fatalf("round %d: %v and %v from the same seed", i, da, db)
+		}
+	}
+	// and the streams from it
+	sa, sb := a.Stream("x"), b.Stream("x")
+	if da, db := draws(sa), draws(sb); !reflect.DeepEqual(da, db) {
+		t.Errorf("Stream(x) gave %v and %v from the same seed", da, db)
+	}
+	c, _ := Init("1f3f")
+	if da, dc := draws(a), draws(c); reflect.DeepEqual(da, dc) {
+		t.Errorf("seeds 1f3e and 1f3f both gave %v", da)
+	}
+}
+
+func TestRange(t *testing.T) {
+	s, _ := Init("1f3e")
+	for _, tt := range [][2]float64{{2, 3}, {3, 2}, {-1, 1}, {5, 5}} {
+		low, high := math.Min(tt[0], tt[1]), math.Max(tt[0], tt[1])
+		for i := 0; i < 100; i++ {
+			if v := s.Range(tt[0], tt[1]); v < low || v > high || v == high && low != high {
+				t.Fatalf("Range(%v, %v) = %v", tt[0], tt[1], v)
+			}
+		}
+	}
+}
+
+func TestChoice(t *testing.T) {
+	s, _ := Init("1f3e")
+	seen := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		seen[s.Choice([]int{0, 1, 2}).(int)]++
+	}
+	for v := 0; v < 3; v++ {
+		if seen[v] < 250 {
+			t.Errorf("Choice picked %d %d times out of 1000, want about a third", v, seen[v])
+		}
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	s, _ := Init("1f3e")
+	perm := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	s.Shuffle(len(perm), func(i, j int) { perm[i], perm[j] = perm[j], perm[i] })
+	seen := map[int]bool{}
+	for _, v := range perm {
+		seen[v] = true
+	}
+	if len(seen) != len(perm) {
+		t.Errorf("Shuffle gave %v, want each element once", perm)
+	}
+	if reflect.DeepEqual(perm, []int{0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("Shuffle left the order as it was")
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	s, _ := Init("1f3e")
+	weights := []float64{1, 0, 3, -2}
+	counts := make([]int, len(weights))
+	const n = 10000
+	for i := 0; i < n; i++ {
+		counts[s.Weighted(weights)]++
+	}
+	if counts[1] != 0 || counts[3] != 0 {
+		t.Errorf("Weighted picked weights of 0 or less: %v", counts)
+	}
+	if got := float64(counts[0]) / n; math.Abs(got-0.25) > 0.02 {
+		t.Errorf("Weighted picked a weight of 1 in 4 %.3f of the time, want 0.25", got)
+	}
+}
+
+func TestWeightedPanics(t *testing.T) {
+	s, _ := Init("1f3e")
+	for _, weights := range [][]float64{nil, {0, 0}, {-1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Weighted(%v) didn't panic", weights)
+				}
+			}()
+			s.Weighted(weights)
+		}()
+	}
+}
+
+func TestChoicePanics(t *testing.T) {
+	s, _ := Init("1f3e")
+	for _, slice := range []interface{}{[]int{}, "abc", nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Choice(%#v) didn't panic", slice)
+				}
+			}()
+			s.Choice(slice)
+		}()
+	}
+}
@@ -0,0 +1,288 @@
+package gart
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Raster is a layer of pixels on a Context, for effects that put down
+// millions of translucent dots like sand grains, which would be far too slow
+// drawn one path at a time.
+// It sits above what was drawn before it was made and under what's drawn
+// after, and is composited into every format on export. EPS has no alpha so
+// the pixels less than half opaque are left out there.
+// Coordinates are mm on the page with y up, the transformation isn't used.
+// Only Context has them, not the Drawer interface, as a Recorder can't save
+// the pixels and GGContext has no layers to slot them between, so sketches
+// that can run on either type assert for it, see substrate.
+type Raster struct {
+	width, height int
+	resolution    float64 // pixels per mm
+	pageHeight    float64 // mm
+
+	pix []float32 // premultiplied RGBA from the top left
+	acc []float32 // summed premultiplied RGBA, nil until Accumulate
+
+	// ToneMap turns the accumulated alpha, which can be any density, into 0 to 1.
+	ToneMap func(density float64) float64
+}
+
+// NewRaster adds a Raster at `resolution` dots per mm on top of what's been
+// drawn so far.
+func (ctx *Context) NewRaster(resolution canvas.DPMM) *Raster {
+	res := float64(resolution)
+	r := &Raster{
+		width:      int(ctx.c.W*res + 0.5),
+		height:     int(ctx.c.H*res + 0.5),
+		resolution: res,
+		pageHeight: ctx.c.H,
+		ToneMap:    Exposure(1),
+	}
+	r.pix = make([]float32, 4*r.width*r.height)
+	ctx.ctx.Renderer.RenderImage(rasterImage{r}, Identity.Scale(1/res, 1/res))
+	return r
+}
+
+// Size returns the width and height in pixels.
+func (r *Raster) Size() (int, int) {
+	return r.width, r.height
+}
+
+// offset returns where the pixel at x,y mm is in pix, or -1 if it's off the page.
+func (r *Raster) offset(x, y float64) int {
+	px := int(math.Floor(x * r.resolution))
+	py := int(math.Floor((r.pageHeight - y) * r.resolution))
+	if px < 0 || py < 0 || px >= r.width || py >= r.height {
+		return -1
+	}
+	return 4 * (py*r.width + px)
+}
+
+func premultiplied(col color.Color) (float32, float32, float32, float32) {
+	cr, cg, cb, ca := col.RGBA()
+	return float32(cr) / 0xffff, float32(cg) / 0xffff, float32(cb) / 0xffff, float32(ca) / 0xffff
+}
+
+// Set replaces the pixel at x,y.
+func (r *Raster) Set(x, y float64, col color.Color) {
+	i := r.offset(x, y)
+	if i < 0 {
+		return
+	}
+	p := r.pix[i : i+4 : i+4]
+	p[0], p[1], p[2], p[3] = premultiplied(col)
+}
+
+// Blend paints `col` over the pixel at x,y using its alpha.
+func (r *Raster) Blend(x, y float64, col color.Color) {
+	i := r.offset(x, y)
+	if i < 0 {
+		return
+	}
+	cr, cg, cb, ca := premultiplied(col)
+	p := r.pix[i : i+4 : i+4]
+	p[0] = cr + p[0]*(1-ca)
+	p[1] = cg + p[1]*(1-ca)
+	p[2] = cb + p[2]*(1-ca)
+	p[3] = ca + p[3]*(1-ca)
+}
+
+// Accumulate adds `col` to the density at x,y, which doesn't saturate like
+// Blend does. On export the summed alpha goes through ToneMap and the color
+// is the alpha weighted average, under what was Set or Blended.
+func (r *Raster) Accumulate(x, y float64, col color.Color) {
+	i := r.offset(x, y)
+	if i < 0 {
+		return
+	}
+	if r.acc == nil {
+		r.acc = make([]float32, len(r.pix))
+	}
+	cr, cg, cb, ca := premultiplied(col)
+	a := r.acc[i : i+4 : i+4]
+	a[0] += cr
+	a[1] += cg
+	a[2] += cb
+	a[3] += ca
+}
+
+// MaxDensity returns the largest accumulated alpha, handy to pick the exposure.
+func (r *Raster) MaxDensity() float64 {
+	max := float32(0)
+	for i := 3; i < len(r.acc); i += 4 {
+		if r.acc[i] > max {
+			max = r.acc[i]
+		}
+	}
+	return float64(max)
+}
+
+// Exposure returns a tone map that's 1-exp(-exposure*density), so dense
+// areas get close to solid without ever clipping.
+func Exposure(exposure float64) func(float64) float64 {
+	return func(d float64) float64 {
+		return 1 - math.Exp(-exposure*d)
+	}
+}
+
+// Reinhard is the density/(1+density) tone map.
+func Reinhard(d float64) float64 {
+	return d / (1 + d)
+}
+
+// Linear returns a tone map that scales densities up to `max` to 0 to 1,
+// clipping the rest, ex. Linear(r.MaxDensity()).
+func Linear(max float64) func(float64) float64 {
+	return func(d float64) float64 {
+		if max <= 0 {
+			return 0
+		}
+		return math.Min(1, d/max)
+	}
+}
+
+// at returns the premultiplied color of pixel px,py
+func (r *Raster) at(px, py int) (float64, float64, float64, float64) {
+	i := 4 * (py*r.width + px)
+	p := r.pix[i : i+4 : i+4]
+	cr, cg, cb, ca := float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])
+	if r.acc == nil || r.acc[i+3] <= 0 {
+		return cr, cg, cb, ca
+	}
+	a := r.acc[i : i+4 : i+4]
+	density := float64(a[3])
+	alpha := Clamp(r.ToneMap(density), 0, 1)
+	scale := alpha / density
+	return cr + float64(a[0])*scale*(1-ca),
+		cg + float64(a[1])*scale*(1-ca),
+		cb + float64(a[2])*scale*(1-ca),
+		ca + alpha*(1-ca)
+}
+
+// snapshot returns the pixels as they'd be exported
+func (r *Raster) snapshot() *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, r.width, r.height))
+	ri := rasterImage{r}
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			img.SetRGBA64(x, y, ri.rgba64(x, y))
+		}
+	}
+	return img
+}
+
+// rasterLayers is the canvas.Renderer for raster formats, it composites the
+// Rasters, scaling them to the image, the groups, clips and the paints itself.
+type rasterLayers struct {
+	canvas.Renderer
+	img        draw.Image
+	resolution float64
+	antialias  bool
+}
+
+// RenderPath strokes the path itself, see strokeOutline.
+func (rl rasterLayers) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if !stroked(style) {
+		rl.Renderer.RenderPath(path, style, m)
+		return
+	}
+	path = path.Transform(m)
+	if style.FillColor.A != 0 {
+		fill := style
+		fill.StrokeColor = canvas.Transparent
+		rl.Renderer.RenderPath(path, fill, Identity)
+	}
+	rl.Renderer.RenderPath(strokeOutline(path, style), canvas.Style{FillColor: style.StrokeColor}, Identity)
+}
+
+// strokeOutline returns the outline of the dashed and stroked `path`. It's
+// flattened first as canvas strokes arcs a little thin and cubics a little
+// wide.
+func strokeOutline(path *canvas.Path, style canvas.Style) *canvas.Path {
+	if len(style.Dashes) > 0 {
+		path = path.Dash(style.DashOffset, style.Dashes...)
+	}
+	return path.Flatten().Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+}
+
+func (rl rasterLayers) RenderImage(img image.Image, m canvas.Matrix) {
+	if pi, ok := img.(paintImage); ok {
+		if src, r := pi.rasterize(rl.img.Bounds().Size(), rl.resolution, rl.antialias); src != nil {
+			draw.Draw(rl.img, r, src, image.Point{}, draw.Over)
+		}
+		return
+	}
+	if g, ok := img.(groupImage); ok {
+		if !g.c.Empty() {
+			blendImage(rl.img, g.rasterize(rl.img.Bounds().Size(), rl.resolution, rl.antialias), g.mode, g.opacity)
+		}
+		return
+	}
+	ri, ok := img.(rasterImage)
+	if !ok {
+		drawImage(rl.img, img, m, rl.resolution)
+		return
+	}
+	src := ri.r.snapshot()
+	if src.Bounds().Size() == rl.img.Bounds().Size() {
+		draw.Draw(rl.img, rl.img.Bounds(), src, image.Point{}, draw.Over)
+	} else {
+		draw.BiLinear.Scale(rl.img, rl.img.Bounds(), src, src.Bounds(), draw.Over, nil)
+	}
+}
+
+// drawImage draws `img` onto the page `dst` at `res` dots per mm, `m` maps
+// it from pixels with y up like canvas does.
+// The canvas rasterizer stretches small images by its margin so it isn't used.
+func drawImage(dst draw.Image, img image.Image, m canvas.Matrix, res float64) {
+	b := img.Bounds()
+	toPage := Identity.Translate(0, float64(dst.Bounds().Dy())).Scale(res, -res).Mul(m)
+	t := toPage.Mul(Identity.Translate(0, float64(b.Dy())).Scale(1, -1).Translate(float64(-b.Min.X), float64(-b.Min.Y)))
+	draw.BiLinear.Transform(dst, f64.Aff3{t[0][0], t[0][1], t[0][2], t[1][0], t[1][1], t[1][2]}, img, b, draw.Over, nil)
+}
+
+// rasterImage is the image.Image the canvas renders, it's read when the
+// Context is written so it has everything drawn up to then.
+type rasterImage struct {
+	r *Raster
+}
+
+func (ri rasterImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (ri rasterImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, ri.r.width, ri.r.height)
+}
+
+func (ri rasterImage) At(x, y int) color.Color {
+	if x < 0 || y < 0 || x >= ri.r.width || y >= ri.r.height {
+		return color.RGBA64{}
+	}
+	return ri.rgba64(x, y)
+}
+
+func (ri rasterImage) rgba64(x, y int) color.RGBA64 {
+	cr, cg, cb, ca := ri.r.at(x, y)
+	to16 := func(v float64) uint16 {
+		return uint16(Clamp(v, 0, 1)*0xffff + 0.5)
+	}
+	a := to16(ca)
+	c := color.RGBA64{to16(cr), to16(cg), to16(cb), a}
+	// keep it a valid premultiplied color after rounding
+	if c.R > a {
+		c.R = a
+	}
+	if c.G > a {
+		c.G = a
+	}
+	if c.B > a {
+		c.B = a
+	}
+	return c
+}
@@ -0,0 +1,55 @@
+package gart
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestRaster(t *testing.T) {
+	ctx := NewContext(10, 10)
+	ctx.SetFillColor(color.White)
+	ctx.FillRect(0, 0, 10, 10)
+	r := ctx.NewRaster(1)
+	if w, h := r.Size(); w != 10 || h != 10 {
+		t.Fatalf("raster is %dx%d, want 10x10", w, h)
+	}
+	r.Set(0.5, 9.5, color.Black)                  // top left pixel
+	r.Blend(2.5, 9.5, color.NRGBA{0, 0, 0, 0x80}) // half grey
+	r.Blend(20, 20, color.Black)                  // off the page
+	for i := 0; i < 1000; i++ {
+		r.Accumulate(4.5, 9.5, color.NRGBA{0, 0, 0xff, 10}) // dense blue
+	}
+	r.Accumulate(6.5, 9.5, color.NRGBA{0, 0, 0xff, 10}) // a single faint grain
+	ctx.SetFillColor(color.RGBA{0xff, 0, 0, 0xff})
+	ctx.FillRect(8, 9, 1, 1) // drawn after so it's on top
+	r.Set(8.5, 9.5, color.Black)
+
+	img, err := PNGOptions{Resolution: 1}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x    int
+		want color.RGBA
+	}{
+		{0, color.RGBA{0, 0, 0, 0xff}},
+		{2, color.RGBA{0x7f, 0x7f, 0x7f, 0xff}},
+		{4, color.RGBA{0, 0, 0xff, 0xff}},
+		{6, color.RGBA{0xf5, 0xf5, 0xff, 0xff}},
+		{8, color.RGBA{0xff, 0, 0, 0xff}},
+		{9, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, test := range tests {
+		r, g, b, a := img.At(test.x, 0).RGBA()
+		got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if d := colorDist(got, test.want); d > 2 {
+			t.Errorf("pixel %d = %v, want %v", test.x, got, test.want)
+		}
+	}
+}
+
+func colorDist(a, b color.RGBA) float64 {
+	return math.Max(math.Max(math.Abs(float64(a.R)-float64(b.R)), math.Abs(float64(a.G)-float64(b.G))),
+		math.Max(math.Abs(float64(a.B)-float64(b.B)), math.Abs(float64(a.A)-float64(b.A))))
+}
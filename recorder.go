@@ -0,0 +1,430 @@
+package gart
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Recorder is a Drawer that keeps a display list of the draw calls instead
+// of drawing them. Tests can look at the Ops, the list can be saved as JSON
+// with WriteJSON and it can be replayed onto any other Drawer, for example
+// to render it again at another resolution without re-running the sketch.
+//
+// Writing a Recorder to a file replays it onto a Context first.
+type Recorder struct {
+	Width  float64 `json:"width"`          // mm
+	Height float64 `json:"height"`         // mm
+	Unit   float64 `json:"unit,omitempty"` // mm per unit, see NewPage, 1 when 0
+	Ops    []Op    `json:"ops"`
+
+	view       Matrix   // the transformation the ops add up to, for Matrix
+	views      []Matrix // saved by Push
+	provenance *Provenance
+}
+
+// Op is one recorded call, Name is the Drawer method and Args its arguments.
+// Colors are stored as their premultiplied 16 bit r, g, b, a values and bools as 0 or 1.
+type Op struct {
+	Name string    `json:"op"`
+	Args []float64 `json:"args,omitempty"`
+}
+
+// NewRecorder returns an empty display list of width x height mm.
+func NewRecorder(width, height float64) *Recorder {
+	return &Recorder{Width: width, Height: height, view: Identity}
+}
+
+// ReadRecorder reads a display list saved by WriteJSON.
+func ReadRecorder(r io.Reader) (*Recorder, error) {
+	rec := &Recorder{}
+	if err := json.NewDecoder(r).Decode(rec); err != nil {
+		return nil, err
+	}
+	rec.view = rec.unitMatrix()
+	return rec, nil
+}
+
+// WriteJSON saves the display list.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// Replay makes all the recorded calls on `d`.
+func (r *Recorder) Replay(d Drawer) error {
+	for i, op := range r.Ops {
+		if err := op.apply(d); err != nil {
+			return fmt.Errorf("op %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Context replays the display list onto a new Context.
+func (r *Recorder) Context() (*Context, error) {
+	ctx := NewContext(r.Width, r.Height)
+	ctx.setUnit(r.unit())
+	ctx.SetProvenance(r.provenance)
+	if err := r.Replay(ctx); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// Count returns how many times the method `name` was called.
+func (r *Recorder) Count(name string) int {
+	n := 0
+	for _, op := range r.Ops {
+		if op.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// Bounds returns the box around the end points of the path ops (MoveTo,
+// LineTo, etc.) and the points of polylines and polygons after their
+// transformation, ie. where they land on the page.
+// Control points and the other shapes aren't included, nor are ops with
+// the wrong number of args, ex. from a hand edited JSON file.
+func (r *Recorder) Bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	views := NewRecorder(r.Width, r.Height)
+	views.setUnit(r.Unit)
+	for _, op := range r.Ops {
+		if op.check() != nil {
+			continue
+		}
+		n := len(op.Args)
+		add := func(x, y float64) {
+			p := views.view.Dot(canvas.Point{X: x, Y: y})
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+		switch op.Name {
+		case "MoveTo", "LineTo", "QuadTo", "CubicTo", "ArcTo":
+			add(op.Args[n-2], op.Args[n-1])
+		case "Polyline", "Polygon":
+			for i := 0; i+1 < n; i += 2 {
+				add(op.Args[i], op.Args[i+1])
+			}
+		case "TaperedPolyline":
+			for i := 0; i+2 < n; i += 3 {
+				add(op.Args[i], op.Args[i+1])
+			}
+		default:
+			op.apply(views)
+			views.Ops = views.Ops[:0]
+		}
+	}
+	return minX, minY, maxX, maxY
+}
+
+// argCount is the number of Args each op takes, -1 for any number.
+var argCount = map[string]int{
+	"Push": 0, "Pop": 0, "Reset": 0,
+	"SetFillColor": 4, "SetStrokeColor": 4, "SetStrokeWidth": 1,
+	"SetLineCap": 1, "SetLineJoin": 1, "SetMiterLimit": 1, "SetDashes": -1,
+	"MoveTo": 2, "LineTo": 2, "QuadTo": 4, "CubicTo": 6, "ArcTo": 7, "Arc": 5, "Close": 0,
+	"Circle": 3, "Ellipse": 4, "Rect": 4, "RoundedRect": 5, "RegularPolygon": 5,
+	"Polyline": -1, "Polygon": -1, "TaperedPolyline": -1,
+	"Point": 2, "FillRect": 4, "Stroke": 0, "Fill": 0, "FillStroke": 0,
+	"Clip": 0, "ClipRect": 4, "ResetClip": 0,
+	"Translate": 2, "Rotate": 1, "RotateAbout": 3, "Scale": 2, "Skew": 2,
+	"SetMatrix": 6, "ResetMatrix": 0, "FitBounds": 5,
+}
+
+// check returns an error if the op is unknown or has the wrong number of args.
+func (op Op) check() error {
+	want, ok := argCount[op.Name]
+	if !ok {
+		return fmt.Errorf("unknown op %q", op.Name)
+	}
+	if want >= 0 && len(op.Args) != want {
+		return fmt.Errorf("%s has %d args, want %d", op.Name, len(op.Args), want)
+	}
+	return nil
+}
+
+func (op Op) apply(d Drawer) error {
+	if err := op.check(); err != nil {
+		return err
+	}
+	a := op.Args
+	switch op.Name {
+	case "Push":
+		d.Push()
+	case "Pop":
+		d.Pop()
+	case "Reset":
+		d.Reset()
+	case "SetFillColor":
+		d.SetFillColor(argsColor(a))
+	case "SetStrokeColor":
+		d.SetStrokeColor(argsColor(a))
+	case "SetStrokeWidth":
+		d.SetStrokeWidth(a[0])
+	case "SetLineCap":
+		d.SetLineCap(LineCap(a[0]))
+	case "SetLineJoin":
+		d.SetLineJoin(LineJoin(a[0]))
+	case "SetMiterLimit":
+		d.SetMiterLimit(a[0])
+	case "SetDashes":
+		if len(a) == 0 {
+			return fmt.Errorf("SetDashes has no offset")
+		}
+		d.SetDashes(a[0], a[1:]...)
+	case "MoveTo":
+		d.MoveTo(a[0], a[1])
+	case "LineTo":
+		d.LineTo(a[0], a[1])
+	case "QuadTo":
+		d.QuadTo(a[0], a[1], a[2], a[3])
+	case "CubicTo":
+		d.CubicTo(a[0], a[1], a[2], a[3], a[4], a[5])
+	case "ArcTo":
+		d.ArcTo(a[0], a[1], a[2], a[3] != 0, a[4] != 0, a[5], a[6])
+	case "Arc":
+		d.Arc(a[0], a[1], a[2], a[3], a[4])
+	case "Close":
+		d.Close()
+	case "Circle":
+		d.Circle(a[0], a[1], a[2])
+	case "Ellipse":
+		d.Ellipse(a[0], a[1], a[2], a[3])
+	case "Rect":
+		d.Rect(a[0], a[1], a[2], a[3])
+	case "RoundedRect":
+		d.RoundedRect(a[0], a[1], a[2], a[3], a[4])
+	case "RegularPolygon":
+		d.RegularPolygon(int(a[0]), a[1], a[2], a[3], a[4])
+	case "Polyline":
+		d.Polyline(a...)
+	case "Polygon":
+		d.Polygon(a...)
+	case "TaperedPolyline":
+		if len(a)%3 != 0 {
+			return fmt.Errorf("TaperedPolyline has %d args, want x,y,width triples", len(a))
+		}
+		xy := make([]float64, 0, len(a)*2/3)
+		for i := 0; i < len(a); i += 3 {
+			xy = append(xy, a[i], a[i+1])
+		}
+		d.TaperedPolyline(func(i int) float64 { return a[3*i+2] }, xy...)
+	case "Point":
+		d.Point(a[0], a[1])
+	case "FillRect":
+		d.FillRect(a[0], a[1], a[2], a[3])
+	case "Stroke":
+		d.Stroke()
+	case "Fill":
+		d.Fill()
+	case "FillStroke":
+		d.FillStroke()
+	case "Clip":
+		d.Clip()
+	case "ClipRect":
+		d.ClipRect(a[0], a[1], a[2], a[3])
+	case "ResetClip":
+		d.ResetClip()
+	case "Translate":
+		d.Translate(a[0], a[1])
+	case "Rotate":
+		d.Rotate(a[0])
+	case "RotateAbout":
+		d.RotateAbout(a[0], a[1], a[2])
+	case "Scale":
+		d.Scale(a[0], a[1])
+	case "Skew":
+		d.Skew(a[0], a[1])
+	case "SetMatrix":
+		d.SetMatrix(Matrix{{a[0], a[1], a[2]}, {a[3], a[4], a[5]}})
+	case "ResetMatrix":
+		d.ResetMatrix()
+	case "FitBounds":
+		d.FitBounds(a[0], a[1], a[2], a[3], a[4])
+	}
+	return nil
+}
+
+func (r *Recorder) add(name string, args ...float64) {
+	r.Ops = append(r.Ops, Op{Name: name, Args: args})
+}
+
+func colorArgs(col color.Color) []float64 {
+	cr, cg, cb, ca := col.RGBA()
+	return []float64{float64(cr), float64(cg), float64(cb), float64(ca)}
+}
+
+func argsColor(a []float64) color.Color {
+	return color.RGBA64{uint16(a[0]), uint16(a[1]), uint16(a[2]), uint16(a[3])}
+}
+
+func boolArg(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Size returns the width and height in mm
+func (r *Recorder) Size() (float64, float64) {
+	return r.Width, r.Height
+}
+
+// SetProvenance sets the record that writers embed in the files they write.
+func (r *Recorder) SetProvenance(p *Provenance) {
+	r.provenance = p
+}
+
+// Provenance returns the record to embed, or nil if there is none.
+func (r *Recorder) Provenance() *Provenance {
+	return r.provenance
+}
+
+// WriteFile writes to fname using the writer `fn`
+func (r *Recorder) WriteFile(fname string, fn WriterFunc) error {
+	return writeFile(fname, r, fn)
+}
+
+// The Drawer methods below only record an Op.
+
+func (r *Recorder) Push() {
+	r.add("Push")
+	r.views = append(r.views, r.view)
+}
+
+func (r *Recorder) Pop() {
+	r.add("Pop")
+	if len(r.views) > 0 {
+		r.view, r.views = r.views[len(r.views)-1], r.views[:len(r.views)-1]
+	}
+}
+
+func (r *Recorder) Reset() { r.add("Reset") }
+
+func (r *Recorder) SetFillColor(col color.Color)   { r.add("SetFillColor", colorArgs(col)...) }
+func (r *Recorder) SetStrokeColor(col color.Color) { r.add("SetStrokeColor", colorArgs(col)...) }
+func (r *Recorder) SetStrokeWidth(width float64)   { r.add("SetStrokeWidth", width) }
+func (r *Recorder) SetLineCap(c LineCap)           { r.add("SetLineCap", float64(c)) }
+func (r *Recorder) SetLineJoin(j LineJoin)         { r.add("SetLineJoin", float64(j)) }
+func (r *Recorder) SetMiterLimit(limit float64)    { r.add("SetMiterLimit", limit) }
+func (r *Recorder) SetDashes(offset float64, dashes ...float64) {
+	r.add("SetDashes", append([]float64{offset}, dashes...)...)
+}
+
+func (r *Recorder) MoveTo(x, y float64)           { r.add("MoveTo", x, y) }
+func (r *Recorder) LineTo(x, y float64)           { r.add("LineTo", x, y) }
+func (r *Recorder) QuadTo(cpx, cpy, x, y float64) { r.add("QuadTo", cpx, cpy, x, y) }
+func (r *Recorder) CubicTo(cpx1, cpy1, cpx2, cpy2, x, y float64) {
+	r.add("CubicTo", cpx1, cpy1, cpx2, cpy2, x, y)
+}
+func (r *Recorder) ArcTo(rx, ry, rot float64, large, sweep bool, x, y float64) {
+	r.add("ArcTo", rx, ry, rot, boolArg(large), boolArg(sweep), x, y)
+}
+func (r *Recorder) Arc(x, y, radius, angle0, angle1 float64) {
+	r.add("Arc", x, y, radius, angle0, angle1)
+}
+func (r *Recorder) Close() { r.add("Close") }
+
+func (r *Recorder) Circle(x, y, radius float64)  { r.add("Circle", x, y, radius) }
+func (r *Recorder) Ellipse(x, y, rx, ry float64) { r.add("Ellipse", x, y, rx, ry) }
+func (r *Recorder) Rect(x, y, w, h float64)      { r.add("Rect", x, y, w, h) }
+func (r *Recorder) RoundedRect(x, y, w, h, radius float64) {
+	r.add("RoundedRect", x, y, w, h, radius)
+}
+func (r *Recorder) RegularPolygon(n int, x, y, radius, rotation float64) {
+	r.add("RegularPolygon", float64(n), x, y, radius, rotation)
+}
+func (r *Recorder) Polyline(xy ...float64) { r.add("Polyline", append([]float64(nil), xy...)...) }
+func (r *Recorder) Polygon(xy ...float64)  { r.add("Polygon", append([]float64(nil), xy...)...) }
+
+// TaperedPolyline records the points as x,y,width triples.
+func (r *Recorder) TaperedPolyline(width func(i int) float64, xy ...float64) {
+	args := make([]float64, 0, len(xy)/2*3)
+	for i := 0; i+1 < len(xy); i += 2 {
+		args = append(args, xy[i], xy[i+1], width(i/2))
+	}
+	r.add("TaperedPolyline", args...)
+}
+
+func (r *Recorder) Point(x, y float64)          { r.add("Point", x, y) }
+func (r *Recorder) FillRect(x, y, w, h float64) { r.add("FillRect", x, y, w, h) }
+func (r *Recorder) Stroke()                     { r.add("Stroke") }
+func (r *Recorder) Fill()                       { r.add("Fill") }
+func (r *Recorder) FillStroke()                 { r.add("FillStroke") }
+
+func (r *Recorder) Clip()                       { r.add("Clip") }
+func (r *Recorder) ClipRect(x, y, w, h float64) { r.add("ClipRect", x, y, w, h) }
+func (r *Recorder) ResetClip()                  { r.add("ResetClip") }
+
+// The transformations are recorded and also tracked, so Matrix works like it does for Context.
+
+func (r *Recorder) Translate(x, y float64) {
+	r.add("Translate", x, y)
+	r.view = r.view.Translate(x, y)
+}
+
+func (r *Recorder) Rotate(angle float64) {
+	r.add("Rotate", angle)
+	r.view = r.view.Rotate(Degrees(angle))
+}
+
+func (r *Recorder) RotateAbout(angle, x, y float64) {
+	r.add("RotateAbout", angle, x, y)
+	r.view = r.view.RotateAbout(Degrees(angle), x, y)
+}
+
+func (r *Recorder) Scale(sx, sy float64) {
+	r.add("Scale", sx, sy)
+	r.view = r.view.Scale(sx, sy)
+}
+
+func (r *Recorder) Skew(ax, ay float64) {
+	r.add("Skew", ax, ay)
+	r.view = r.view.Shear(math.Tan(ax), math.Tan(ay))
+}
+
+func (r *Recorder) Matrix() Matrix {
+	return r.view
+}
+
+func (r *Recorder) SetMatrix(m Matrix) {
+	r.add("SetMatrix", m[0][0], m[0][1], m[0][2], m[1][0], m[1][1], m[1][2])
+	r.view = m
+}
+
+func (r *Recorder) ResetMatrix() {
+	r.add("ResetMatrix")
+	r.view = r.unitMatrix()
+}
+
+// setUnit makes the coordinates `unit` mm, it's saved with the ops rather
+// than being one.
+func (r *Recorder) setUnit(unit float64) {
+	r.Unit = unit
+	r.view = r.unitMatrix()
+}
+
+func (r *Recorder) unit() float64 {
+	if r.Unit <= 0 {
+		return 1
+	}
+	return r.Unit
+}
+
+// unitMatrix is what ResetMatrix goes back to
+func (r *Recorder) unitMatrix() Matrix {
+	return Identity.Scale(r.unit(), r.unit())
+}
+
+func (r *Recorder) FitBounds(minX, minY, maxX, maxY, margin float64) {
+	r.add("FitBounds", minX, minY, maxX, maxY, margin)
+	r.view = r.view.Mul(fitBounds(r.Width/r.unit(), r.Height/r.unit(), minX, minY, maxX, maxY, margin))
+}
@@ -0,0 +1,76 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/tdewolff/canvas/rasterizer"
+)
+
+func drawSample(d Drawer) {
+	d.SetFillColor(color.Gray{245})
+	d.FillRect(0, 0, 40, 30)
+	d.SetStrokeColor(color.RGBA{200, 20, 20, 255})
+	d.SetStrokeWidth(0.5)
+	d.Push()
+	d.RotateAbout(math.Pi/6, 20, 15)
+	d.MoveTo(5, 5)
+	d.QuadTo(20, 25, 35, 5)
+	d.ArcTo(5, 5, 0, false, true, 35, 15)
+	d.Stroke()
+	d.Pop()
+	d.Circle(20, 15, 4)
+	d.Polygon(1, 1, 5, 1, 3, 4)
+	d.FillStroke()
+}
+
+func TestRecorderReplay(t *testing.T) {
+	rec := NewRecorder(40, 30)
+	drawSample(rec)
+	if got := rec.Count("Stroke"); got != 1 {
+		t.Errorf("Count(Stroke) = %d, want 1", got)
+	}
+	if got := rec.Matrix(); got != Identity {
+		t.Errorf("Matrix() = %v after Pop, want Identity", got)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadRecorder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Ops, rec.Ops) {
+		t.Errorf("ReadRecorder() ops differ from the written ones")
+	}
+
+	replayed, err := loaded.Context()
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct := NewContext(40, 30)
+	drawSample(direct)
+	got := rasterizer.Draw(replayed.c, defaultResolution)
+	want := rasterizer.Draw(direct.c, defaultResolution)
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("replayed image differs from drawing directly")
+	}
+}
+
+func TestRecorderBadOp(t *testing.T) {
+	rec := NewRecorder(10, 10)
+	rec.Ops = append(rec.Ops, Op{Name: "LineTo", Args: []float64{1}})
+	if err := rec.Replay(NewRecorder(10, 10)); err == nil {
+		t.Errorf("Replay() with a short LineTo should fail")
+	}
+	// skipped by Bounds rather than panicking
+	rec.Ops = append(rec.Ops, Op{Name: "MoveTo", Args: []float64{2, 3}}, Op{Name: "CubicTo"})
+	if x0, y0, x1, y1 := rec.Bounds(); x0 != 2 || y0 != 3 || x1 != 2 || y1 != 3 {
+		t.Errorf("Bounds() = %v, %v, %v, %v, want 2, 3, 2, 3", x0, y0, x1, y1)
+	}
+}
@@ -5,14 +5,20 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
+	"strings"
 )
 
 const tmpFolder = "./"
 
 // SafeWrite noisily saves to tmp file and then moves for gg
-func (s Seed) SafeWrite(ctx *Context, prefix, ext string) error {
+// The seed, git hash and flags are embedded in the file, see ReadProvenance.
+func (s Seed) SafeWrite(d Drawer, prefix, ext string) error {
+	p := s.Provenance(prefix)
+	d = stamped(d, p)
+	defer setProvenance(d, p)()
 	fname := s.GetFilename(prefix, ext)
-	if err := safeWrite(ctx, fname); err != nil {
+	if err := safeWrite(d, fname); err != nil {
 		fmt.Printf("Problem saving %s: %v\n", fname, err)
 		return err
 	}
@@ -20,38 +26,147 @@ func (s Seed) SafeWrite(ctx *Context, prefix, ext string) error {
 	return nil
 }
 
-// safeWrite writes to a temp file then renames atomically
-func safeWrite(ctx *Context, fname string) error {
-	if err := MaybeCreateDir(path.Dir(fname)); err != nil {
+// SafeWriteAll noisily saves the same render in each of `exts` (ex. ".png", ".svg"),
+// all sharing the same seed based basename.
+// Every format is encoded to a tmp file before any of them is moved into place,
+// formats that fail are returned as FormatErrors and the others are still saved.
+func (s Seed) SafeWriteAll(d Drawer, prefix string, exts ...string) error {
+	p := s.Provenance(prefix)
+	return s.writeAll(stamped(d, p), p, prefix, exts...)
+}
+
+// writeAll is SafeWriteAll without the caption.
+func (s Seed) writeAll(d Drawer, p *Provenance, prefix string, exts ...string) error {
+	defer setProvenance(d, p)()
+	base := s.GetFilename(prefix, "")
+	errs := FormatErrors{}
+	tmpNames := make(map[string]string, len(exts))
+	exts = uniqueStrings(exts) // writing one twice would leak the first temp file
+	for _, ext := range exts {
+		tmpName, err := writeTemp(d, base+ext)
+		if err != nil {
+			errs[ext] = err
+			continue
+		}
+		tmpNames[ext] = tmpName
+	}
+	for _, ext := range exts {
+		tmpName, ok := tmpNames[ext]
+		if !ok {
+			continue
+		}
+		fname := base + ext
+		if err := moveInPlace(tmpName, fname); err != nil {
+			errs[ext] = err
+			continue
+		}
+		fmt.Printf("Saved to %s\n", fname)
+	}
+	if len(errs) > 0 {
+		fmt.Printf("Problem saving %s: %v\n", base, errs)
+		return errs
+	}
+	return nil
+}
+
+// setProvenance sets `p` on `d` for the writers, the func returned puts back
+// what `d` had so writing leaves it as it was.
+func setProvenance(d Drawer, p *Provenance) func() {
+	old := d.Provenance()
+	d.SetProvenance(p)
+	return func() {
+		d.SetProvenance(old)
+	}
+}
+
+// FormatErrors holds the errors from SafeWriteAll keyed by extension.
+type FormatErrors map[string]error
+
+func (fe FormatErrors) Error() string {
+	exts := make([]string, 0, len(fe))
+	for ext := range fe {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	msgs := make([]string, len(exts))
+	for i, ext := range exts {
+		msgs[i] = fmt.Sprintf("%s: %v", ext, fe[ext])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseFormats splits a comma separated list of formats, like the
+// value of a -formats=png,svg,pdf flag, into extensions for SafeWriteAll.
+func ParseFormats(list string) []string {
+	var exts []string
+	for _, f := range strings.Split(list, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		if !strings.HasPrefix(f, ".") {
+			f = "." + f
+		}
+		exts = append(exts, f)
+	}
+	return exts
+}
+
+// uniqueStrings returns `strs` without repeats, in the same order.
+func uniqueStrings(strs []string) []string {
+	seen := make(map[string]bool, len(strs))
+	var out []string
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// safeWrite writes to a temp file then renames atomically.
+func safeWrite(d Drawer, fname string) error {
+	tmpName, err := writeTemp(d, fname)
+	if err != nil {
 		return err
 	}
+	return moveInPlace(tmpName, fname)
+}
 
+// writeTemp writes to a temp file, returning its name.
+// The writer is picked by the file extension of fname, see RegisterFormat.
+func writeTemp(d Drawer, fname string) (string, error) {
 	ext := path.Ext(fname)
+	writer, ok := LookupFormat(ext)
+	if !ok {
+		return "", fmt.Errorf("unsupported file format %s", ext)
+	}
+	if err := MaybeCreateDir(path.Dir(fname)); err != nil {
+		return "", err
+	}
+
 	tmpfile, err := ioutil.TempFile(tmpFolder, "gart.*"+ext)
 	if err != nil {
-		return err
+		return "", err
 	}
-	// todo: Change depending on extension
-	if ext == ".png" {
-		if err := ctx.WritePNG(tmpfile.Name()); err != nil {
-			os.Remove(tmpfile.Name())
-			return err
-		}
-	} else if ext == ".svg" {
-		if err := ctx.WriteSVG(tmpfile.Name()); err != nil {
-			os.Remove(tmpfile.Name())
-			return err
-		}
-	} else if ext == ".pdf" {
-		if err := ctx.WritePDF(tmpfile.Name()); err != nil {
-			os.Remove(tmpfile.Name())
-			return err
-		}
-	} else {
-		return fmt.Errorf("unsupported file format %s", ext)
+	if err := writer(tmpfile, d); err != nil {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name())
+		return "", err
+	}
+	if err := tmpfile.Close(); err != nil {
+		os.Remove(tmpfile.Name())
+		return "", err
 	}
+	return tmpfile.Name(), nil
+}
+
+// moveInPlace renames the temp file to fname.
+func moveInPlace(tmpName, fname string) error {
 	// Note: the folders here need to be on the same drive
-	if err:= os.Rename(tmpfile.Name(), fname); err != nil {
+	if err := os.Rename(tmpName, fname); err != nil {
+		os.Remove(tmpName)
 		return err
 	}
 
@@ -0,0 +1,116 @@
+package gart
+
+import (
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"png", []string{".png"}},
+		{"png, .SVG,,pdf ", []string{".png", ".svg", ".pdf"}},
+		{"png,png", []string{".png", ".png"}},
+	}
+	for _, tt := range tests {
+		if got := ParseFormats(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFormats(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	fe := FormatErrors{".svg": errors.New("b"), ".png": errors.New("a")}
+	if got, want := fe.Error(), ".png: a; .svg: b"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// tmpFiles returns the temp files writeTemp left behind
+func tmpFiles(t *testing.T) []string {
+	names, err := filepath.Glob(filepath.Join(tmpFolder, "gart.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestSafeWriteAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	RegisterFormat(".broken", func(w io.Writer, d Drawer) error {
+		return errors.New("broken")
+	})
+	defer func() {
+		formatsMux.Lock()
+		delete(formats, ".broken")
+		formatsMux.Unlock()
+	}()
+
+	s, _ := Init("1f3e")
+	ctx := NewContext(20, 10)
+	ctx.SetFillColor(color.Black)
+	ctx.FillRect(0, 0, 10, 10)
+	before := len(tmpFiles(t))
+	prefix := filepath.Join(dir, "test-")
+	err = s.SafeWriteAll(ctx, prefix, ".png", ".broken", ".svg", ".png", ".nope")
+
+	fe, ok := err.(FormatErrors)
+	if !ok {
+		t.Fatalf("SafeWriteAll = %v, want FormatErrors", err)
+	}
+	if len(fe) != 2 || fe[".broken"] == nil || fe[".nope"] == nil {
+		t.Errorf("SafeWriteAll = %v, want errors for .broken and .nope", fe)
+	}
+	// the others are still saved
+	for _, ext := range []string{".png", ".svg"} {
+		if _, err := os.Stat(s.GetFilename(prefix, ext)); err != nil {
+			t.Errorf("%s: %v", ext, err)
+		}
+	}
+	for _, ext := range []string{".broken", ".nope"} {
+		if _, err := os.Stat(s.GetFilename(prefix, ext)); err == nil {
+			t.Errorf("%s was saved", ext)
+		}
+	}
+	if after := len(tmpFiles(t)); after != before {
+		t.Errorf("left %d temp files behind", after-before)
+	}
+}
+
+func TestSafeWriteKeepsProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _ := Init("1f3e")
+	prefix := filepath.Join(dir, "test-")
+	ctx := NewContext(10, 10)
+	if err := s.SafeWrite(ctx, prefix, ".svg"); err != nil {
+		t.Fatal(err)
+	}
+	if p := ctx.Provenance(); p != nil {
+		t.Errorf("SafeWrite left the provenance %v", p)
+	}
+	mine := &Provenance{Sketch: "mine"}
+	ctx.SetProvenance(mine)
+	if err := s.SafeWriteAll(ctx, prefix, ".svg"); err != nil {
+		t.Fatal(err)
+	}
+	if p := ctx.Provenance(); p != mine {
+		t.Errorf("SafeWriteAll replaced the provenance with %v", p)
+	}
+}
@@ -0,0 +1,228 @@
+// Reproduce re-renders an image made by Seed.SafeWrite.
+// It reads the git hash and seed from the file's embedded provenance (or
+// failing that its name), checks out that commit into a temporary worktree
+// and re-runs the sketch with the same -seed and flags.
+//
+//	go run ./scripts/reproduce substrate/samples/substrate-abc1234-1f3e.png
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/scottkirkwood/gart"
+)
+
+var (
+	sketchFlag = flag.String("sketch", "", "Folder of the sketch, defaults to the name in the file")
+	outFlag    = flag.String("out", ".", "Folder to copy the reproduced file to")
+	keepFlag   = flag.Bool("keep", false, "Keep the temporary worktree")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Printf("Usage: reproduce [flags] <file>\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if err := reproduce(flag.Arg(0)); err != nil {
+		fmt.Printf("Unable to reproduce: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// recipe is what's needed to run the sketch again.
+type recipe struct {
+	sketch  string
+	gitHash string
+	hexSeed string
+	flags   map[string]string
+	ext     string
+}
+
+func reproduce(fname string) error {
+	r, err := readRecipe(fname)
+	if err != nil {
+		return err
+	}
+	if *sketchFlag != "" {
+		r.sketch = *sketchFlag
+	}
+	if r.gitHash == "" {
+		return fmt.Errorf("%q has no git hash", fname)
+	}
+
+	repo, err := git("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	tmpDir, err := ioutil.TempDir("", "gart-reproduce-")
+	if err != nil {
+		return err
+	}
+	worktree := path.Join(tmpDir, r.gitHash)
+	fmt.Printf("Checking out %s into %s\n", r.gitHash, worktree)
+	if _, err := git(repo, "worktree", "add", "--detach", worktree, r.gitHash); err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	if !*keepFlag {
+		defer func() {
+			git(repo, "worktree", "remove", "--force", worktree)
+			os.RemoveAll(tmpDir)
+		}()
+	}
+
+	sketchDir := path.Join(worktree, r.sketch)
+	args := append([]string{"run", "."}, r.args()...)
+	fmt.Printf("Running go %s in %s\n", strings.Join(args, " "), sketchDir)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = sketchDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	// Match on the seed since the original may have been renamed
+	found, err := findFile(sketchDir, r.gitHash+"-"+r.hexSeed+r.ext)
+	if err != nil {
+		return err
+	}
+	if err := gart.MaybeCreateDir(*outFlag); err != nil {
+		return err
+	}
+	base := path.Base(found)
+	dest := path.Join(*outFlag, strings.TrimSuffix(base, r.ext)+"-reproduced"+r.ext)
+	input, err := ioutil.ReadFile(found)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(dest, input, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Reproduced %s\n", dest)
+	same, err := sameContent(fname, dest)
+	if err != nil {
+		fmt.Printf("Unable to compare: %v\n", err)
+	} else if same {
+		fmt.Printf("Identical to %s\n", fname)
+	} else {
+		fmt.Printf("Differs from %s\n", fname)
+	}
+	return nil
+}
+
+// readRecipe prefers the embedded provenance and falls back to the filename.
+func readRecipe(fname string) (recipe, error) {
+	info, nameErr := gart.ParseFilename(fname)
+	if p, err := gart.ReadProvenance(fname); err == nil {
+		return recipe{
+			sketch:  p.Sketch,
+			gitHash: p.GitHash,
+			hexSeed: p.Seed,
+			flags:   p.Flags,
+			ext:     path.Ext(fname),
+		}, nil
+	}
+	if nameErr != nil {
+		return recipe{}, nameErr
+	}
+	return recipe{
+		sketch:  strings.Trim(info.Prefix, "-_."),
+		gitHash: info.GitHash,
+		hexSeed: info.HexSeed,
+		ext:     info.Ext,
+	}, nil
+}
+
+// args returns the command line flags, with -seed always set.
+func (r recipe) args() []string {
+	names := make([]string, 0, len(r.flags))
+	for name := range r.flags {
+		if name != "seed" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	args := []string{"-seed=" + r.hexSeed}
+	for _, name := range names {
+		args = append(args, "-"+name+"="+r.flags[name])
+	}
+	return args
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// findFile looks for a file whose name ends with `suffix` under dir.
+func findFile(dir, suffix string) (string, error) {
+	found := ""
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && found == "" && !info.IsDir() && strings.HasSuffix(info.Name(), suffix) {
+			found = p
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", fmt.Errorf("the sketch didn't write a file ending in %q", suffix)
+	}
+	return found, nil
+}
+
+// sameContent compares the pixels of raster images, since the embedded
+// timestamp differs, and the raw bytes of anything else.
+func sameContent(a, b string) (bool, error) {
+	imgA, errA := decode(a)
+	imgB, errB := decode(b)
+	if errA == nil && errB == nil {
+		return bytes.Equal(imgA.Pix, imgB.Pix) && imgA.Rect == imgB.Rect, nil
+	}
+	dataA, err := ioutil.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := ioutil.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+func decode(fname string) (*image.NRGBA, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Rect, img, img.Bounds().Min, draw.Src)
+	return nrgba, nil
+}
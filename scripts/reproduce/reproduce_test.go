@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadRecipe(t *testing.T) {
+	tests := []struct {
+		fname string
+		want  recipe
+	}{
+		// no such file, so it's all from the name
+		{"samples/substrate-abc1234-1f3e.png", recipe{sketch: "substrate", gitHash: "abc1234", hexSeed: "1f3e", ext: ".png"}},
+		{"lsystem_c610baa-ff.svg", recipe{sketch: "lsystem", gitHash: "c610baa", hexSeed: "ff", ext: ".svg"}},
+		{"horzlines--ff.pdf", recipe{sketch: "horzlines", hexSeed: "ff", ext: ".pdf"}},
+	}
+	for _, tt := range tests {
+		got, err := readRecipe(tt.fname)
+		if err != nil {
+			t.Errorf("readRecipe(%q): %v", tt.fname, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readRecipe(%q) = %+v, want %+v", tt.fname, got, tt.want)
+		}
+	}
+	if _, err := readRecipe("favorite.png"); err == nil {
+		t.Errorf("readRecipe(favorite.png) should fail")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	r := recipe{hexSeed: "1f3e", flags: map[string]string{"seed": "old", "width": "20", "formats": "png,svg"}}
+	want := []string{"-seed=1f3e", "-formats=png,svg", "-width=20"}
+	if got := r.args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
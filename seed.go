@@ -4,29 +4,38 @@ import (
 	"fmt"
 	"math/rand"
 	"os/exec"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// Seed hold the primary seed used for random numbers
+// Seed hold the primary seed used for random numbers.
+// Sketches should draw their random numbers from it (see random.go) rather
+// than the global math/rand, so nothing else can perturb the sequence.
 type Seed struct {
 	intSeed int64
+	rng     *rand.Rand
 }
 
 // Jan 1, 2020 (to make filenames a little smaller)
 const epoch2020 = 1577836800
 
 // Init initializes the seed
-// `hexSeed` is either the empty string or a hex value
+// `hexSeed` is either the empty string or a hex value.
+// If `hexSeed` can't be parsed the error is returned along with a time based
+// seed, so the sketch can still run.
 func Init(hexSeed string) (Seed, error) {
-	intSeed := time.Now().UnixNano() - epoch2020
-	s := Seed{intSeed: intSeed}
+	s := Seed{}
 	if hexSeed != "" {
-		err := s.SetSeed(hexSeed)
-		return s, err
+		if err := s.SetSeed(hexSeed); err != nil {
+			s.setSeed(time.Now().UnixNano() - epoch2020)
+			return s, err
+		}
+		return s, nil
 	}
-	rand.Seed(s.intSeed)
+	s.setSeed(time.Now().UnixNano() - epoch2020)
 	return s, nil
 }
 
@@ -36,20 +45,45 @@ func (s Seed) GetSeed() int64 {
 }
 
 // SetSeed sets the seed given the file seed part of filename
-func (s Seed) SetSeed(hexSeed string) (err error) {
-	s.intSeed, err = strconv.ParseInt(hexSeed, 16, 64)
+func (s *Seed) SetSeed(hexSeed string) error {
+	intSeed, err := strconv.ParseInt(hexSeed, 16, 64)
 	if err != nil {
 		return err
 	}
-	rand.Seed(s.intSeed)
+	s.setSeed(intSeed)
 	return nil
 }
 
+// setSeed keeps the seed and the generator it drives in step.
+func (s *Seed) setSeed(intSeed int64) {
+	s.intSeed = intSeed
+	s.rng = rand.New(rand.NewSource(intSeed))
+}
+
 // GetFilename returns a string to use for this file
 func (s Seed) GetFilename(prefix, ext string) string {
 	return fmt.Sprintf("%s%s-%x%s", prefix, getGitHash(), s.intSeed, ext)
 }
 
+// FileInfo holds the parts of a filename made by GetFilename.
+type FileInfo struct {
+	Prefix  string // ex. "substrate-", without any folder
+	GitHash string // short hash or "" when git wasn't available
+	HexSeed string // the seed to pass to Init
+	Ext     string // ex. ".png"
+}
+
+var filenameRx = regexp.MustCompile(`^(.*?)([0-9a-f]{7}|)-([0-9a-f]+)(\.[^.]+)$`)
+
+// ParseFilename splits a filename made by GetFilename back into its parts.
+func ParseFilename(fname string) (FileInfo, error) {
+	m := filenameRx.FindStringSubmatch(path.Base(fname))
+	if m == nil {
+		return FileInfo{}, fmt.Errorf("%q doesn't look like a gart filename", fname)
+	}
+	return FileInfo{Prefix: m[1], GitHash: m[2], HexSeed: m[3], Ext: m[4]}, nil
+}
+
 func getGitHash() string {
 	var (
 		cmdOut []byte
@@ -0,0 +1,106 @@
+package gart
+
+import (
+	"strings"
+	"testing"
+)
+
+func sequence(s Seed) []float64 {
+	seq := make([]float64, 20)
+	for i := range seq {
+		seq[i] = s.Float64()
+	}
+	return seq
+}
+
+func TestFilenameRoundTrip(t *testing.T) {
+	tests := []string{"", "1f3e", "18df7e9d6790d79e", "0"}
+	for _, hexSeed := range tests {
+		s, err := Init(hexSeed)
+		if err != nil {
+			t.Fatalf("Init(%q): %v", hexSeed, err)
+		}
+		fname := s.GetFilename("samples/test-", ".png")
+		want := sequence(s)
+
+		info, err := ParseFilename(fname)
+		if err != nil {
+			t.Fatalf("ParseFilename(%q): %v", fname, err)
+		}
+		if info.Prefix != "test-" || info.Ext != ".png" {
+			t.Errorf("ParseFilename(%q) = %+v", fname, info)
+		}
+		if hexSeed != "" && info.HexSeed != hexSeed {
+			t.Errorf("Init(%q) wrote seed %q to %q", hexSeed, info.HexSeed, fname)
+		}
+
+		again, err := Init(info.HexSeed)
+		if err != nil {
+			t.Fatalf("Init(%q): %v", info.HexSeed, err)
+		}
+		if again.GetSeed() != s.GetSeed() {
+			t.Errorf("Init(%q).GetSeed() = %x, want %x", info.HexSeed, again.GetSeed(), s.GetSeed())
+		}
+		got := sequence(again)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%q: random %d = %v, want %v", fname, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestSetSeed(t *testing.T) {
+	s, _ := Init("")
+	if err := s.SetSeed("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(s.GetFilename("x-", ".svg"), "-abc.svg") {
+		t.Errorf("GetFilename = %q, want the seed abc", s.GetFilename("x-", ".svg"))
+	}
+	if err := s.SetSeed("xyz"); err == nil {
+		t.Errorf("SetSeed(xyz) should fail")
+	}
+	if _, err := Init("xyz"); err == nil {
+		t.Errorf("Init(xyz) should fail")
+	}
+}
+
+func TestParseFilename(t *testing.T) {
+	tests := []struct {
+		fname string
+		want  FileInfo
+	}{
+		{"samples/substrate-abc1234-1f3e.png", FileInfo{"substrate-", "abc1234", "1f3e", ".png"}},
+		{"lsystem-c610baa-18df7e9d6790d79e.svg", FileInfo{"lsystem-", "c610baa", "18df7e9d6790d79e", ".svg"}},
+		{"horzlines--ff.pdf", FileInfo{"horzlines-", "", "ff", ".pdf"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilename(tt.fname)
+		if err != nil {
+			t.Errorf("ParseFilename(%q): %v", tt.fname, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFilename(%q) = %+v, want %+v", tt.fname, got, tt.want)
+		}
+	}
+	if _, err := ParseFilename("favorite.png"); err == nil {
+		t.Errorf("ParseFilename(favorite.png) should fail")
+	}
+}
+
+func TestStream(t *testing.T) {
+	a, _ := Init("1f3e")
+	b, _ := Init("1f3e")
+	b.Float64() // drawing from the main stream mustn't change the others
+	sa, sb := sequence(a.Stream("sand")), sequence(b.Stream("sand"))
+	for i := range sa {
+		if sa[i] != sb[i] {
+			t.Fatalf("Stream(sand) random %d = %v, want %v", i, sb[i], sa[i])
+		}
+	}
+	if other := sequence(a.Stream("cracks")); other[0] == sa[0] {
+		t.Errorf("Stream(cracks) should differ from Stream(sand)")
+	}
+}
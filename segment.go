@@ -0,0 +1,76 @@
+package gart
+
+import "math"
+
+// Segment is the straight line from A to B, unlike Line it's in the float
+// coordinates sketches draw with.
+// Points less than Epsilon away from a segment count as on it, so segments
+// that only touch at an end cross.
+type Segment struct {
+	A, B Vec2
+}
+
+// Len returns the length of the segment
+func (s Segment) Len() float64 {
+	return s.A.Dist(s.B)
+}
+
+// At returns the point a fraction t of the way from A to B
+func (s Segment) At(t float64) Vec2 {
+	return s.A.Lerp(s.B, t)
+}
+
+// Crosses is true when the segments cross or touch.
+func (s Segment) Crosses(o Segment) bool {
+	_, ok := s.Intersection(o)
+	return ok
+}
+
+// Intersection returns where the segments cross or touch. When they overlap
+// along the same line it's the overlap's point nearest to s.A.
+func (s Segment) Intersection(o Segment) (Vec2, bool) {
+	r, q := s.B.Sub(s.A), o.B.Sub(o.A)
+	rl, ql := r.Len(), q.Len()
+	if rl < Epsilon || ql < Epsilon { // a point
+		if rl < Epsilon {
+			return s.A, o.Distance(s.A) < Epsilon
+		}
+		return o.A, s.Distance(o.A) < Epsilon
+	}
+	ao := o.A.Sub(s.A)
+	denom := r.Cross(q)
+	if math.Abs(denom) <= Epsilon*rl*ql { // parallel
+		if math.Abs(r.Cross(ao))/rl >= Epsilon {
+			return Vec2{}, false
+		}
+		// how far along s the ends of o are
+		t0 := ao.Dot(r) / (rl * rl)
+		t1 := o.B.Sub(s.A).Dot(r) / (rl * rl)
+		lo, hi := math.Max(0, math.Min(t0, t1)), math.Min(1, math.Max(t0, t1))
+		if lo > hi+Epsilon/rl {
+			return Vec2{}, false
+		}
+		return s.At(math.Min(lo, 1)), true
+	}
+	t := ao.Cross(q) / denom
+	u := ao.Cross(r) / denom
+	if t < -Epsilon/rl || t > 1+Epsilon/rl || u < -Epsilon/ql || u > 1+Epsilon/ql {
+		return Vec2{}, false
+	}
+	return s.At(Clamp(t, 0, 1)), true
+}
+
+// ClosestPoint returns the point on the segment nearest to p
+func (s Segment) ClosestPoint(p Vec2) Vec2 {
+	r := s.B.Sub(s.A)
+	l2 := r.Dot(r)
+	if l2 < Epsilon*Epsilon {
+		return s.A
+	}
+	return s.At(Clamp(p.Sub(s.A).Dot(r)/l2, 0, 1))
+}
+
+// Distance returns how far p is from the nearest point on the segment
+func (s Segment) Distance(p Vec2) float64 {
+	return p.Dist(s.ClosestPoint(p))
+}
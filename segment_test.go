@@ -0,0 +1,73 @@
+package gart
+
+import (
+	"math"
+	"testing"
+)
+
+func seg(x1, y1, x2, y2 float64) Segment {
+	return Segment{Vec2{x1, y1}, Vec2{x2, y2}}
+}
+
+func TestSegmentIntersection(t *testing.T) {
+	tests := []struct {
+		name    string
+		s, o    Segment
+		want    Vec2
+		crosses bool
+	}{
+		{"crossing", seg(0, 0, 10, 10), seg(10, 0, 0, 10), Vec2{5, 5}, true},
+		{"parallel", seg(1, 1, 10, 1), seg(1, 2, 10, 2), Vec2{}, false},
+		{"would cross if longer", seg(-5, -5, 0, 0), seg(1, 0, 0, 1), Vec2{}, false},
+		{"touching ends", seg(0, 0, 1, 1), seg(1, 1, 2, 0), Vec2{1, 1}, true},
+		{"T", seg(0, 0, 2, 0), seg(1, 0, 1, 5), Vec2{1, 0}, true},
+		{"T a rounding error short", seg(0, 0, 2, 0), seg(1, 1e-12, 1, 5), Vec2{1, 0}, true},
+		{"T a gap short", seg(0, 0, 2, 0), seg(1, 1e-6, 1, 5), Vec2{}, false},
+		{"0.1 + 0.2", seg(0, 0.3, 1, 0.3), seg(0.5, 0.1+0.2, 0.5, 1), Vec2{0.5, 0.3}, true},
+		{"overlapping", seg(0, 0, 4, 0), seg(6, 0, 2, 0), Vec2{2, 0}, true},
+		{"overlapping backwards", seg(4, 0, 0, 0), seg(2, 0, 6, 0), Vec2{4, 0}, true},
+		{"in line apart", seg(0, 0, 1, 0), seg(2, 0, 3, 0), Vec2{}, false},
+		{"in line touching", seg(0, 0, 1, 0), seg(1, 0, 3, 0), Vec2{1, 0}, true},
+		{"point on", seg(0, 0, 2, 2), seg(1, 1, 1, 1), Vec2{1, 1}, true},
+		{"point off", seg(0, 0, 2, 2), seg(1, 0, 1, 0), Vec2{}, false},
+	}
+	for _, tt := range tests {
+		for _, swap := range []bool{false, true} {
+			s, o := tt.s, tt.o
+			if swap {
+				s, o = o, s
+			}
+			got, ok := s.Intersection(o)
+			if ok != tt.crosses || s.Crosses(o) != tt.crosses {
+				t.Errorf("%s: %v.Intersection(%v) crosses = %v, want %v", tt.name, s, o, ok, tt.crosses)
+			}
+			// the overlaps start from whichever is s
+			if ok && !swap && !got.Equals(tt.want) {
+				t.Errorf("%s: %v.Intersection(%v) = %v, want %v", tt.name, s, o, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSegmentClosestPoint(t *testing.T) {
+	tests := []struct {
+		s    Segment
+		p    Vec2
+		want Vec2
+		dist float64
+	}{
+		{seg(0, 0, 10, 0), Vec2{3, 4}, Vec2{3, 0}, 4},
+		{seg(0, 0, 10, 0), Vec2{-3, 4}, Vec2{0, 0}, 5},
+		{seg(0, 0, 10, 0), Vec2{13, -4}, Vec2{10, 0}, 5},
+		{seg(0, 0, 2, 2), Vec2{0, 2}, Vec2{1, 1}, math.Sqrt2},
+		{seg(1, 1, 1, 1), Vec2{4, 5}, Vec2{1, 1}, 5},
+	}
+	for _, tt := range tests {
+		if got := tt.s.ClosestPoint(tt.p); !got.Equals(tt.want) {
+			t.Errorf("%v.ClosestPoint(%v) = %v, want %v", tt.s, tt.p, got, tt.want)
+		}
+		if got := tt.s.Distance(tt.p); math.Abs(got-tt.dist) > 1e-12 {
+			t.Errorf("%v.Distance(%v) = %v, want %v", tt.s, tt.p, got, tt.dist)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gart
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// The shapes are added to the current path as closed subpaths,
+// call Stroke, Fill or FillStroke to draw them.
+
+// Circle adds a circle centered on x,y with radius r.
+func (pb *pathBuilder) Circle(x, y, r float64) {
+	pb.path = pb.path.Append(canvas.Circle(r).Translate(x, y))
+}
+
+// Ellipse adds an ellipse centered on x,y with radii rx,ry.
+func (pb *pathBuilder) Ellipse(x, y, rx, ry float64) {
+	pb.path = pb.path.Append(canvas.Ellipse(rx, ry).Translate(x, y))
+}
+
+// Rect adds a rectangle with its lower left corner at x,y.
+func (pb *pathBuilder) Rect(x, y, w, h float64) {
+	pb.path = pb.path.Append(canvas.Rectangle(w, h).Translate(x, y))
+}
+
+// RoundedRect adds a rectangle with its lower left corner at x,y and corners of radius r.
+func (pb *pathBuilder) RoundedRect(x, y, w, h, r float64) {
+	pb.path = pb.path.Append(canvas.RoundedRectangle(w, h, r).Translate(x, y))
+}
+
+// RegularPolygon adds a polygon with n sides centered on x,y with radius r.
+// The first vertex is at angle `rotation` radians, 0 points along the X axis.
+func (pb *pathBuilder) RegularPolygon(n int, x, y, r, rotation float64) {
+	pb.path = pb.path.Append(regularPolygon(n, x, y, r, rotation))
+}
+
+// Polyline adds an open path through the points given as x,y pairs.
+//
+//	ctx.Polyline(0, 0, 10, 5, 20, 0)
+func (pb *pathBuilder) Polyline(xy ...float64) {
+	pb.path = pb.path.Append(polyline(xy, false))
+}
+
+// Polygon adds a closed path through the points given as x,y pairs.
+func (pb *pathBuilder) Polygon(xy ...float64) {
+	pb.path = pb.path.Append(polyline(xy, true))
+}
+
+func regularPolygon(n int, x, y, r, rotation float64) *canvas.Path {
+	p := &canvas.Path{}
+	if n < 3 {
+		return p
+	}
+	for i := 0; i < n; i++ {
+		sin, cos := math.Sincos(rotation + 2*math.Pi*float64(i)/float64(n))
+		if i == 0 {
+			p.MoveTo(x+r*cos, y+r*sin)
+		} else {
+			p.LineTo(x+r*cos, y+r*sin)
+		}
+	}
+	p.Close()
+	return p
+}
+
+func polyline(xy []float64, closed bool) *canvas.Path {
+	p := &canvas.Path{}
+	for i := 0; i+1 < len(xy); i += 2 {
+		if i == 0 {
+			p.MoveTo(xy[i], xy[i+1])
+		} else {
+			p.LineTo(xy[i], xy[i+1])
+		}
+	}
+	if closed && len(xy) >= 6 {
+		p.Close()
+	}
+	return p
+}
+
+// appendArc adds the circular arc to `p`, joined by a line to any current point.
+func appendArc(p *canvas.Path, x, y, r, angle0, angle1 float64) {
+	sin, cos := math.Sincos(angle0)
+	if p.Empty() {
+		p.MoveTo(x+r*cos, y+r*sin)
+	} else {
+		p.LineTo(x+r*cos, y+r*sin)
+	}
+	p.Arc(r, r, 0, Degrees(angle0), Degrees(angle1))
+}
@@ -0,0 +1,204 @@
+package gart
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+)
+
+// drawnPath is a path read back from a vector file, in mm with y up
+type drawnPath struct {
+	path         *canvas.Path
+	fill, stroke bool
+	strokeWidth  float64
+}
+
+// mask rasterizes the paths like the PNG writer, with round caps and joins.
+// They're flattened first as canvas strokes cubic curves a little wide.
+func mask(paths []drawnPath, size image.Point, res float64) *image.Alpha {
+	out := image.NewAlpha(image.Rectangle{Max: size})
+	add := func(p *canvas.Path) {
+		m := coverage(p, size.X, size.Y, res, true)
+		for i, v := range m.Pix {
+			if v > out.Pix[i] {
+				out.Pix[i] = v
+			}
+		}
+	}
+	for _, p := range paths {
+		if p.fill {
+			add(p.path)
+		}
+		if p.stroke {
+			add(p.path.Flatten().Stroke(p.strokeWidth, canvas.RoundCap, canvas.RoundJoin))
+		}
+	}
+	return out
+}
+
+var (
+	svgPathRx  = regexp.MustCompile(`<path d="([^"]*)"(?: style="([^"]*)")?`)
+	svgWidthRx = regexp.MustCompile(`stroke-width:([0-9.]+)`)
+)
+
+func svgPaths(t *testing.T, svg string, height float64) []drawnPath {
+	var paths []drawnPath
+	for _, m := range svgPathRx.FindAllStringSubmatch(svg, -1) {
+		p, err := canvas.ParseSVG(m[1])
+		if err != nil {
+			t.Fatalf("ParseSVG(%q): %v", m[1], err)
+		}
+		dp := drawnPath{path: p.Transform(Identity.ReflectYAbout(height / 2)), strokeWidth: 1}
+		dp.fill = !strings.Contains(m[2], "fill:none")
+		dp.stroke = strings.Contains(m[2], "stroke:") && !strings.Contains(m[2], "stroke:none")
+		if w := svgWidthRx.FindStringSubmatch(m[2]); w != nil {
+			dp.strokeWidth, _ = strconv.ParseFloat(w[1], 64)
+		}
+		paths = append(paths, dp)
+	}
+	return paths
+}
+
+var pdfStreamRx = regexp.MustCompile(`(?s)<<([^>]*)>> stream\n(.*?)\nendstream`)
+
+// pdfPaths reads the paths from the content streams, which only hold paths
+func pdfPaths(t *testing.T, pdf []byte) []drawnPath {
+	var paths []drawnPath
+	for _, m := range pdfStreamRx.FindAllSubmatch(pdf, -1) {
+		content := m[2]
+		if bytes.Contains(m[1], []byte("FlateDecode")) {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content, err = ioutil.ReadAll(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var nums []float64
+		p, width := &canvas.Path{}, 1.0
+		for _, tok := range strings.Fields(string(content)) {
+			if v, err := strconv.ParseFloat(tok, 64); err == nil {
+				nums = append(nums, v)
+				continue
+			}
+			switch tok {
+			case "m":
+				p.MoveTo(nums[0], nums[1])
+			case "l":
+				p.LineTo(nums[0], nums[1])
+			case "c":
+				p.CubeTo(nums[0], nums[1], nums[2], nums[3], nums[4], nums[5])
+			case "re":
+				p = p.Append(canvas.Rectangle(nums[2], nums[3]).Translate(nums[0], nums[1]))
+			case "h":
+				p.Close()
+			case "w":
+				width = nums[0]
+			case "f", "f*", "S", "s", "B", "B*", "b", "b*":
+				if strings.ContainsAny(tok, "sb") {
+					p.Close()
+				}
+				paths = append(paths, drawnPath{p, strings.ContainsAny(tok, "fBb"), strings.ContainsAny(tok, "SsBb"), width})
+				p = &canvas.Path{}
+			case "n":
+				p = &canvas.Path{}
+			}
+			nums = nums[:0]
+		}
+	}
+	return paths
+}
+
+func TestShapeFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(ctx *Context)
+	}{
+		{"CubicTo", func(ctx *Context) {
+			ctx.MoveTo(2, 2)
+			ctx.CubicTo(2, 16, 18, -4, 18, 12)
+		}},
+		{"ArcTo", func(ctx *Context) {
+			ctx.MoveTo(3, 6)
+			ctx.ArcTo(8, 5, Radians(30), true, false, 15, 8)
+		}},
+		{"Arc", func(ctx *Context) {
+			ctx.MoveTo(2, 2)
+			ctx.Arc(10, 10, 6, 0, 1.5*math.Pi)
+		}},
+		{"Circle", func(ctx *Context) { ctx.Circle(10, 10, 6) }},
+		{"Ellipse", func(ctx *Context) { ctx.Ellipse(10, 10, 7, 3) }},
+		{"Rect", func(ctx *Context) { ctx.Rect(3, 4, 12, 8) }},
+		{"RoundedRect", func(ctx *Context) { ctx.RoundedRect(3, 4, 12, 8, 3) }},
+		{"RegularPolygon", func(ctx *Context) { ctx.RegularPolygon(5, 10, 10, 7, 0.3) }},
+		{"Polyline", func(ctx *Context) { ctx.Polyline(2, 2, 10, 17, 12, 5, 18, 9) }},
+		{"Polygon", func(ctx *Context) { ctx.Polygon(2, 2, 10, 17, 12, 5, 18, 9) }},
+	}
+	renders := []struct {
+		name   string
+		render func(ctx *Context)
+	}{
+		{"Fill", (*Context).Fill},
+		{"Stroke", (*Context).Stroke},
+		{"FillStroke", (*Context).FillStroke},
+	}
+	const res = 10.0
+	for _, tt := range tests {
+		for _, r := range renders {
+			ctx := NewContext(20, 20)
+			ctx.SetFillColor(color.RGBA{255, 0, 0, 255})
+			ctx.SetStrokeColor(color.Black)
+			ctx.SetStrokeWidth(1.5)
+			ctx.SetLineCap(CapRound)
+			ctx.SetLineJoin(JoinRound)
+			tt.draw(ctx)
+			r.render(ctx)
+
+			img, err := PNGOptions{Resolution: res}.Rasterize(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := img.Bounds().Size()
+			png := image.NewAlpha(img.Bounds())
+			for i := range png.Pix {
+				png.Pix[i] = img.(*image.RGBA).Pix[4*i+3]
+			}
+			var svg, pdf bytes.Buffer
+			if err := SVGWriter(&svg, ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := PDFWriter(&pdf, ctx); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []struct {
+				name  string
+				paths []drawnPath
+			}{{"SVG", svgPaths(t, svg.String(), 20)}, {"PDF", pdfPaths(t, pdf.Bytes())}} {
+				if len(f.paths) != 1 {
+					t.Errorf("%s %s: %s has %d paths, want 1", tt.name, r.name, f.name, len(f.paths))
+					continue
+				}
+				// the vector file drawn like the PNG should be the PNG
+				got, differ := mask(f.paths, size, res), 0
+				for i, v := range got.Pix {
+					if math.Abs(float64(v)-float64(png.Pix[i])) > 0x80 {
+						differ++
+					}
+				}
+				if differ > 0 {
+					t.Errorf("%s %s: %d pixels differ between the %s and PNG", tt.name, r.name, differ, f.name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package spatial
+
+import (
+	"math"
+
+	"github.com/scottkirkwood/gart"
+)
+
+type cell struct {
+	x, y int
+}
+
+// Grid is a uniform hash grid, each segment is kept in every cell its box
+// overlaps. Cells about the size of the items or the query radius work best,
+// much bigger and every query looks at too many, much smaller and long
+// segments fill too many cells.
+type Grid struct {
+	size   float64
+	cells  map[cell][]gart.Segment
+	n      int
+	lo, hi cell // the cells anything's been in
+}
+
+// NewGrid returns an empty grid with square cells `size` across, which must
+// be positive.
+func NewGrid(size float64) *Grid {
+	if !(size > 0) || math.IsInf(size, 1) {
+		panic("spatial: NewGrid needs a positive size")
+	}
+	return &Grid{size: size, cells: map[cell][]gart.Segment{}}
+}
+
+func (g *Grid) cellOf(p gart.Vec2) cell {
+	return cell{int(math.Floor(p.X / g.size)), int(math.Floor(p.Y / g.size))}
+}
+
+// cellsOf returns the first and last cells of the box
+func (g *Grid) cellsOf(min, max gart.Vec2) (cell, cell) {
+	return g.cellOf(min), g.cellOf(max)
+}
+
+// Insert adds the segment to every cell its box overlaps.
+func (g *Grid) Insert(s gart.Segment) {
+	lo, hi := g.cellsOf(bounds(s))
+	if g.n == 0 {
+		g.lo, g.hi = lo, hi
+	}
+	g.lo = cell{minInt(g.lo.x, lo.x), minInt(g.lo.y, lo.y)}
+	g.hi = cell{maxInt(g.hi.x, hi.x), maxInt(g.hi.y, hi.y)}
+	for y := lo.y; y <= hi.y; y++ {
+		for x := lo.x; x <= hi.x; x++ {
+			c := cell{x, y}
+			g.cells[c] = append(g.cells[c], s)
+		}
+	}
+	g.n++
+}
+
+// Remove removes one segment equal to `s`.
+func (g *Grid) Remove(s gart.Segment) bool {
+	lo, hi := g.cellsOf(bounds(s))
+	found := false
+	for y := lo.y; y <= hi.y; y++ {
+		for x := lo.x; x <= hi.x; x++ {
+			c := cell{x, y}
+			items := g.cells[c]
+			for i, it := range items {
+				if it == s {
+					items = append(items[:i], items[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if len(items) == 0 {
+				delete(g.cells, c)
+			} else {
+				g.cells[c] = items
+			}
+		}
+	}
+	if found {
+		g.n--
+	}
+	return found
+}
+
+// Len returns how many segments there are.
+func (g *Grid) Len() int {
+	return g.n
+}
+
+// query calls fn with every segment whose cells overlap the box once. A
+// segment in several cells is only passed on from the first of them that's
+// in the box too.
+func (g *Grid) query(min, max gart.Vec2, fn func(s gart.Segment)) {
+	lo, hi := g.cellsOf(min, max)
+	// no need to look past where anything's been
+	lo = cell{maxInt(lo.x, g.lo.x), maxInt(lo.y, g.lo.y)}
+	hi = cell{minInt(hi.x, g.hi.x), minInt(hi.y, g.hi.y)}
+	for y := lo.y; y <= hi.y; y++ {
+		for x := lo.x; x <= hi.x; x++ {
+			for _, s := range g.cells[cell{x, y}] {
+				first, _ := g.cellsOf(bounds(s))
+				if maxInt(first.x, lo.x) == x && maxInt(first.y, lo.y) == y {
+					fn(s)
+				}
+			}
+		}
+	}
+}
+
+// Within returns the segments `r` or closer to `p`.
+func (g *Grid) Within(p gart.Vec2, r float64) []gart.Segment {
+	var found []gart.Segment
+	d := gart.Vec2{X: r, Y: r}
+	g.query(p.Sub(d), p.Add(d), func(s gart.Segment) {
+		if s.Distance(p) <= r {
+			found = append(found, s)
+		}
+	})
+	return found
+}
+
+// Crossing returns the segments that cross or touch `s`.
+func (g *Grid) Crossing(s gart.Segment) []gart.Segment {
+	var found []gart.Segment
+	min, max := bounds(s)
+	e := gart.Vec2{X: gart.Epsilon, Y: gart.Epsilon}
+	g.query(min.Sub(e), max.Add(e), func(o gart.Segment) {
+		if s.Crosses(o) {
+			found = append(found, o)
+		}
+	})
+	return found
+}
+
+// Nearest returns the segment closest to `p`. It looks in rings of cells
+// further and further out, until anything in the next ring would be further
+// away than the closest found so far.
+func (g *Grid) Nearest(p gart.Vec2) (gart.Segment, bool) {
+	if g.n == 0 {
+		return gart.Segment{}, false
+	}
+	c := g.cellOf(p)
+	// the rings from the nearest to the farthest that overlap the cells in use
+	first := maxInt(maxInt(g.lo.x-c.x, c.x-g.hi.x), maxInt(g.lo.y-c.y, c.y-g.hi.y))
+	last := maxInt(maxInt(c.x-g.lo.x, g.hi.x-c.x), maxInt(c.y-g.lo.y, g.hi.y-c.y))
+	var best gart.Segment
+	bestDist := math.Inf(1)
+	look := func(x, y int) {
+		if x < g.lo.x || x > g.hi.x {
+			return
+		}
+		for _, s := range g.cells[cell{x, y}] {
+			if d := s.Distance(p); d < bestDist {
+				best, bestDist = s, d
+			}
+		}
+	}
+	for k := maxInt(first, 0); k <= last; k++ {
+		// anything in ring k is at least k-1 cells away
+		if bestDist <= float64(k-1)*g.size {
+			break
+		}
+		for y := maxInt(c.y-k, g.lo.y); y <= minInt(c.y+k, g.hi.y); y++ {
+			if y == c.y-k || y == c.y+k {
+				for x := maxInt(c.x-k, g.lo.x); x <= minInt(c.x+k, g.hi.x); x++ {
+					look(x, y)
+				}
+			} else {
+				look(c.x-k, y)
+				look(c.x+k, y)
+			}
+		}
+	}
+	return best, !math.IsInf(bestDist, 1)
+}
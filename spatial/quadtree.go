@@ -0,0 +1,197 @@
+package spatial
+
+import (
+	"math"
+
+	"github.com/scottkirkwood/gart"
+)
+
+const (
+	// maxItems is how many segments a quad holds before it's split in four
+	maxItems = 8
+	// maxDepth stops the splitting where lots of segments are on top of each other
+	maxDepth = 20
+)
+
+// Quadtree is a loose quadtree, each segment is kept in the smallest quad
+// it fits in, where the quads overlap their neighbours by half their size so
+// short segments across a boundary can still go deep. Quads with too many
+// are split in four. Segments outside the area it was made with still work,
+// they're just always looked at.
+type Quadtree struct {
+	root quad
+	n    int
+}
+
+type quad struct {
+	min, max gart.Vec2 // with the overlap
+	mid      gart.Vec2
+	items    []gart.Segment
+	kids     *[4]quad // nil until it's split
+}
+
+// NewQuadtree returns an empty quadtree over the box from `min` to `max`.
+func NewQuadtree(min, max gart.Vec2) *Quadtree {
+	return &Quadtree{root: newQuad(min, max)}
+}
+
+// newQuad returns the quad for the box, loosened
+func newQuad(min, max gart.Vec2) quad {
+	d := max.Sub(min).Scale(0.5)
+	return quad{min: min.Sub(d), max: max.Add(d), mid: min.Lerp(max, 0.5)}
+}
+
+// kid returns the kid for the quarter the middle of the box is in, or
+// nil if the box doesn't fit in it
+func (q *quad) kid(min, max gart.Vec2) *quad {
+	if q.kids == nil {
+		return nil
+	}
+	c, i := min.Lerp(max, 0.5), 0
+	if c.X >= q.mid.X {
+		i++
+	}
+	if c.Y >= q.mid.Y {
+		i += 2
+	}
+	k := &q.kids[i]
+	if min.X < k.min.X || min.Y < k.min.Y || max.X > k.max.X || max.Y > k.max.Y {
+		return nil
+	}
+	return k
+}
+
+func (q *quad) split() {
+	// the box without the overlap
+	d := q.mid.Sub(q.min).Scale(0.5)
+	min, max, mid := q.mid.Sub(d), q.mid.Add(d), q.mid
+	q.kids = &[4]quad{
+		newQuad(min, mid),
+		newQuad(gart.Vec2{X: mid.X, Y: min.Y}, gart.Vec2{X: max.X, Y: mid.Y}),
+		newQuad(gart.Vec2{X: min.X, Y: mid.Y}, gart.Vec2{X: mid.X, Y: max.Y}),
+		newQuad(mid, max),
+	}
+	items := q.items
+	q.items = nil
+	for _, s := range items {
+		if k := q.kid(bounds(s)); k != nil {
+			k.items = append(k.items, s)
+		} else {
+			q.items = append(q.items, s)
+		}
+	}
+}
+
+// Insert adds the segment.
+func (t *Quadtree) Insert(s gart.Segment) {
+	min, max := bounds(s)
+	q := &t.root
+	for depth := 0; ; depth++ {
+		k := q.kid(min, max)
+		if k == nil {
+			q.items = append(q.items, s)
+			if q.kids == nil && len(q.items) > maxItems && depth < maxDepth {
+				q.split()
+			}
+			break
+		}
+		q = k
+	}
+	t.n++
+}
+
+// Remove removes one segment equal to `s`.
+func (t *Quadtree) Remove(s gart.Segment) bool {
+	min, max := bounds(s)
+	for q := &t.root; q != nil; q = q.kid(min, max) {
+		for i, it := range q.items {
+			if it == s {
+				q.items = append(q.items[:i], q.items[i+1:]...)
+				t.n--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Len returns how many segments there are.
+func (t *Quadtree) Len() int {
+	return t.n
+}
+
+// query calls fn with the segments in the quads overlapping the box.
+func (q *quad) query(min, max gart.Vec2, fn func(s gart.Segment)) {
+	for _, s := range q.items {
+		fn(s)
+	}
+	if q.kids == nil {
+		return
+	}
+	for i := range q.kids {
+		k := &q.kids[i]
+		if k.min.X <= max.X && k.max.X >= min.X && k.min.Y <= max.Y && k.max.Y >= min.Y {
+			k.query(min, max, fn)
+		}
+	}
+}
+
+// Within returns the segments `r` or closer to `p`.
+func (t *Quadtree) Within(p gart.Vec2, r float64) []gart.Segment {
+	var found []gart.Segment
+	d := gart.Vec2{X: r, Y: r}
+	t.root.query(p.Sub(d), p.Add(d), func(s gart.Segment) {
+		if s.Distance(p) <= r {
+			found = append(found, s)
+		}
+	})
+	return found
+}
+
+// Crossing returns the segments that cross or touch `s`.
+func (t *Quadtree) Crossing(s gart.Segment) []gart.Segment {
+	var found []gart.Segment
+	min, max := bounds(s)
+	e := gart.Vec2{X: gart.Epsilon, Y: gart.Epsilon}
+	t.root.query(min.Sub(e), max.Add(e), func(o gart.Segment) {
+		if s.Crosses(o) {
+			found = append(found, o)
+		}
+	})
+	return found
+}
+
+// Nearest returns the segment closest to `p`, looking in the nearer kids
+// first and skipping quads further away than the closest found so far.
+func (t *Quadtree) Nearest(p gart.Vec2) (gart.Segment, bool) {
+	var best gart.Segment
+	bestDist := math.Inf(1)
+	var visit func(q *quad)
+	visit = func(q *quad) {
+		for _, s := range q.items {
+			if d := s.Distance(p); d < bestDist {
+				best, bestDist = s, d
+			}
+		}
+		if q.kids == nil {
+			return
+		}
+		var dist [4]float64
+		order := [4]int{0, 1, 2, 3}
+		for i := range q.kids {
+			dist[i] = boxDist(p, q.kids[i].min, q.kids[i].max)
+		}
+		for i := 1; i < 4; i++ { // insertion sort by distance
+			for j := i; j > 0 && dist[order[j]] < dist[order[j-1]]; j-- {
+				order[j], order[j-1] = order[j-1], order[j]
+			}
+		}
+		for _, i := range order {
+			if dist[i] < bestDist {
+				visit(&q.kids[i])
+			}
+		}
+	}
+	visit(&t.root)
+	return best, t.n > 0
+}
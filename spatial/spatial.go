@@ -0,0 +1,76 @@
+// Package spatial has indexes for finding the points and segments near a
+// spot or crossing a line without checking every one of them, for packing,
+// collision and growth sketches.
+//
+// Grid is a uniform hash grid, best when the items are about the same size
+// and spread evenly. Quadtree adapts to items bunched up in places, but
+// needs to know roughly the area they'll be in.
+//
+//	idx := spatial.NewGrid(5)
+//	for _, c := range circles {
+//		if len(idx.Within(c.Center, 2*c.R)) == 0 {
+//			idx.Insert(spatial.Point(c.Center))
+//		}
+//	}
+package spatial
+
+import (
+	"math"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// Index is what both indexes can do. Points are kept as segments with both
+// ends the same, see Point.
+type Index interface {
+	// Insert adds the segment, adding it twice keeps it twice.
+	Insert(s gart.Segment)
+	// Remove removes one segment equal to `s`, false when there isn't one.
+	Remove(s gart.Segment) bool
+	// Len returns how many segments there are.
+	Len() int
+	// Within returns the segments `r` or closer to `p`.
+	Within(p gart.Vec2, r float64) []gart.Segment
+	// Nearest returns the segment closest to `p`, false when there are none.
+	Nearest(p gart.Vec2) (gart.Segment, bool)
+	// Crossing returns the segments that cross or touch `s`, and points on it.
+	Crossing(s gart.Segment) []gart.Segment
+}
+
+var (
+	_ Index = (*Grid)(nil)
+	_ Index = (*Quadtree)(nil)
+)
+
+// Point returns `p` as a segment to keep in an index.
+func Point(p gart.Vec2) gart.Segment {
+	return gart.Segment{A: p, B: p}
+}
+
+// bounds returns the corners of the box around the segment
+func bounds(s gart.Segment) (min, max gart.Vec2) {
+	min = gart.Vec2{X: math.Min(s.A.X, s.B.X), Y: math.Min(s.A.Y, s.B.Y)}
+	max = gart.Vec2{X: math.Max(s.A.X, s.B.X), Y: math.Max(s.A.Y, s.B.Y)}
+	return min, max
+}
+
+// boxDist returns how far `p` is from the box, 0 inside it
+func boxDist(p, min, max gart.Vec2) float64 {
+	dx := math.Max(0, math.Max(min.X-p.X, p.X-max.X))
+	dy := math.Max(0, math.Max(min.Y-p.Y, p.Y-max.Y))
+	return math.Hypot(dx, dy)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,232 @@
+package spatial
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/scottkirkwood/gart"
+)
+
+// brute checks every segment, what the indexes are tested and timed against
+type brute []gart.Segment
+
+func (b *brute) Insert(s gart.Segment) { *b = append(*b, s) }
+
+func (b *brute) Remove(s gart.Segment) bool {
+	for i, it := range *b {
+		if it == s {
+			*b = append((*b)[:i], (*b)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *brute) Len() int { return len(*b) }
+
+func (b *brute) Within(p gart.Vec2, r float64) []gart.Segment {
+	var found []gart.Segment
+	for _, s := range *b {
+		if s.Distance(p) <= r {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func (b *brute) Nearest(p gart.Vec2) (gart.Segment, bool) {
+	var best gart.Segment
+	bestDist := math.Inf(1)
+	for _, s := range *b {
+		if d := s.Distance(p); d < bestDist {
+			best, bestDist = s, d
+		}
+	}
+	return best, len(*b) > 0
+}
+
+func (b *brute) Crossing(s gart.Segment) []gart.Segment {
+	var found []gart.Segment
+	for _, o := range *b {
+		if s.Crosses(o) {
+			found = append(found, o)
+		}
+	}
+	return found
+}
+
+func sorted(segs []gart.Segment) []gart.Segment {
+	sort.Slice(segs, func(i, j int) bool {
+		a, b := segs[i], segs[j]
+		if a.A != b.A {
+			return a.A.X < b.A.X || a.A.X == b.A.X && a.A.Y < b.A.Y
+		}
+		return a.B.X < b.B.X || a.B.X == b.B.X && a.B.Y < b.B.Y
+	})
+	return segs
+}
+
+func randVec(r *rand.Rand, size float64) gart.Vec2 {
+	return gart.Vec2{X: r.Float64() * size, Y: r.Float64() * size}
+}
+
+// randItems returns points and short segments spread over a square `size`
+// across, some a little outside it.
+func randItems(r *rand.Rand, n int, size float64) []gart.Segment {
+	items := make([]gart.Segment, n)
+	for i := range items {
+		a := randVec(r, size*1.1).Sub(gart.Vec2{X: size * 0.05, Y: size * 0.05})
+		if i%2 == 0 {
+			items[i] = Point(a)
+		} else {
+			items[i] = gart.Segment{A: a, B: a.Add(randVec(r, size/10).Sub(gart.Vec2{X: size / 20, Y: size / 20}))}
+		}
+	}
+	return items
+}
+
+func newIndexes(size float64) map[string]Index {
+	return map[string]Index{
+		"grid":     NewGrid(size / 20),
+		"quadtree": NewQuadtree(gart.Vec2{}, gart.Vec2{X: size, Y: size}),
+	}
+}
+
+func TestIndex(t *testing.T) {
+	const size = 100
+	r := rand.New(rand.NewSource(1))
+	items := randItems(r, 500, size)
+	// some that share ends and lie on top of each other
+	items = append(items,
+		gart.Segment{A: gart.Vec2{X: 10, Y: 10}, B: gart.Vec2{X: 20, Y: 10}},
+		gart.Segment{A: gart.Vec2{X: 20, Y: 10}, B: gart.Vec2{X: 20, Y: 30}},
+		gart.Segment{A: gart.Vec2{X: 10, Y: 10}, B: gart.Vec2{X: 20, Y: 10}},
+		gart.Segment{A: gart.Vec2{X: 0, Y: 0}, B: gart.Vec2{X: size, Y: size}},
+	)
+	for name, idx := range newIndexes(size) {
+		if _, ok := idx.Nearest(gart.Vec2{}); ok {
+			t.Errorf("%s: Nearest found something when empty", name)
+		}
+		want := &brute{}
+		for _, s := range items {
+			idx.Insert(s)
+			want.Insert(s)
+		}
+		// take some out again
+		for i := 0; i < len(items); i += 3 {
+			if !idx.Remove(items[i]) {
+				t.Errorf("%s: Remove(%v) = false", name, items[i])
+			}
+			want.Remove(items[i])
+		}
+		if idx.Remove(gart.Segment{A: gart.Vec2{X: -1, Y: -1}}) {
+			t.Errorf("%s: Remove of a missing segment = true", name)
+		}
+		if idx.Len() != want.Len() {
+			t.Errorf("%s: Len = %d, want %d", name, idx.Len(), want.Len())
+		}
+
+		for i := 0; i < 200; i++ {
+			p := randVec(r, size*1.4).Sub(gart.Vec2{X: size * 0.2, Y: size * 0.2})
+			rad := r.Float64() * size / 5
+			if got, want := sorted(idx.Within(p, rad)), sorted(want.Within(p, rad)); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Within(%v, %v) = %d segments, want %d", name, p, rad, len(got), len(want))
+			}
+			got, _ := idx.Nearest(p)
+			near, _ := want.Nearest(p)
+			if got.Distance(p) != near.Distance(p) {
+				t.Errorf("%s: Nearest(%v) = %v, want %v", name, p, got, near)
+			}
+			s := gart.Segment{A: p, B: randVec(r, size)}
+			if got, want := sorted(idx.Crossing(s)), sorted(want.Crossing(s)); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Crossing(%v) = %d segments, want %d", name, s, len(got), len(want))
+			}
+		}
+		// touching at an end counts
+		s := gart.Segment{A: gart.Vec2{X: 20, Y: 10}, B: gart.Vec2{X: 25, Y: 0}}
+		touched := gart.Segment{A: gart.Vec2{X: 10, Y: 10}, B: gart.Vec2{X: 20, Y: 10}}
+		found := false
+		for _, o := range idx.Crossing(s) {
+			found = found || o == touched
+		}
+		if !found {
+			t.Errorf("%s: Crossing(%v) missed %v", name, s, touched)
+		}
+	}
+}
+
+func TestNewGridBadSize(t *testing.T) {
+	for _, size := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewGrid(%v) didn't panic", size)
+				}
+			}()
+			NewGrid(size)
+		}()
+	}
+}
+
+func benchIndexes(size float64) map[string]Index {
+	idx := newIndexes(size)
+	idx["brute"] = &brute{}
+	return idx
+}
+
+func benchmark(b *testing.B, query func(idx Index, r *rand.Rand)) {
+	const size = 1000
+	for _, n := range []int{100, 10000} {
+		items := randItems(rand.New(rand.NewSource(1)), n, size)
+		for _, name := range []string{"brute", "grid", "quadtree"} {
+			idx := benchIndexes(size)[name]
+			for _, s := range items {
+				idx.Insert(s)
+			}
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				r := rand.New(rand.NewSource(2))
+				for i := 0; i < b.N; i++ {
+					query(idx, r)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	const size = 1000
+	items := randItems(rand.New(rand.NewSource(1)), 10000, size)
+	for _, name := range []string{"brute", "grid", "quadtree"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx := benchIndexes(size)[name]
+				for _, s := range items {
+					idx.Insert(s)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWithin(b *testing.B) {
+	benchmark(b, func(idx Index, r *rand.Rand) {
+		idx.Within(randVec(r, 1000), 20)
+	})
+}
+
+func BenchmarkNearest(b *testing.B) {
+	benchmark(b, func(idx Index, r *rand.Rand) {
+		idx.Nearest(randVec(r, 1000))
+	})
+}
+
+func BenchmarkCrossing(b *testing.B) {
+	benchmark(b, func(idx Index, r *rand.Rand) {
+		p := randVec(r, 1000)
+		idx.Crossing(gart.Segment{A: p, B: p.Add(randVec(r, 100))})
+	})
+}
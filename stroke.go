@@ -0,0 +1,179 @@
+package gart
+
+import (
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/tdewolff/canvas"
+)
+
+// LineCap is how the ends of open strokes are drawn.
+type LineCap int
+
+const (
+	CapButt   LineCap = iota // cut square at the end point
+	CapRound                 // a half circle past the end point
+	CapSquare                // a half square past the end point
+)
+
+// LineJoin is how the corners of strokes are drawn.
+type LineJoin int
+
+const (
+	JoinMiter LineJoin = iota // pointed, beveled past the miter limit
+	JoinRound
+	JoinBevel
+)
+
+// defaultMiterLimit is canvas' default, joins sharper than 60 degrees get beveled
+const defaultMiterLimit = 2
+
+// The stroke style is saved by Push and restored by Pop like the colors.
+// Like the stroke width, dash lengths are in mm and aren't transformed.
+
+// SetLineCap sets how the ends of open strokes are drawn, CapButt by default.
+func (ctx *Context) SetLineCap(c LineCap) {
+	ctx.ctx.SetStrokeCapper(c.capper())
+}
+
+// SetLineJoin sets how the corners of strokes are drawn, JoinMiter by default.
+func (ctx *Context) SetLineJoin(j LineJoin) {
+	ctx.state.join = j
+	ctx.ctx.SetStrokeJoiner(j.joiner(ctx.state.miterLimit))
+}
+
+// SetMiterLimit sets how long a miter can get, as a ratio of the stroke
+// width like SVG's stroke-miterlimit, before it's beveled. 0 is the default of 2.
+func (ctx *Context) SetMiterLimit(limit float64) {
+	ctx.state.miterLimit = limit
+	ctx.ctx.SetStrokeJoiner(ctx.state.join.joiner(limit))
+}
+
+// SetDashes dashes the strokes, alternating the lengths of the dashes and
+// gaps in `dashes` starting `offset` into the pattern. No dashes gives solid strokes.
+//
+//	ctx.SetDashes(0, 2, 1) // 2mm dashes with 1mm gaps
+func (ctx *Context) SetDashes(offset float64, dashes ...float64) {
+	ctx.ctx.SetDashes(offset, append([]float64(nil), dashes...)...)
+}
+
+// TaperedPolyline adds the outline of a stroke along the points given as x,y
+// pairs to the current path, width(i) mm wide at the i'th point and changing
+// smoothly in between, as one closed subpath to Fill. A width of 0 or less
+// pinches it to nothing. The corners are mitered up to the miter limit and
+// beveled past it, the ends are cut square. Plotters skip it like other fills.
+func (ctx *Context) TaperedPolyline(width func(i int) float64, xy ...float64) {
+	ctx.path = ctx.path.Append(taperedOutline(ctx.ctx.View(), width, xy, ctx.state.miterLimit))
+}
+
+func (c LineCap) capper() canvas.Capper {
+	switch c {
+	case CapRound:
+		return canvas.RoundCap
+	case CapSquare:
+		return canvas.SquareCap
+	}
+	return canvas.ButtCap
+}
+
+func (j LineJoin) joiner(limit float64) canvas.Joiner {
+	switch j {
+	case JoinRound:
+		return canvas.RoundJoin
+	case JoinBevel:
+		return canvas.BevelJoin
+	}
+	if limit <= 0 {
+		limit = defaultMiterLimit
+	}
+	return canvas.MiterClipJoin(canvas.BevelJoin, limit)
+}
+
+func (c LineCap) gg() gg.LineCap {
+	switch c {
+	case CapRound:
+		return gg.LineCapRound
+	case CapSquare:
+		return gg.LineCapSquare
+	}
+	return gg.LineCapButt
+}
+
+// svgStyle returns `style` with the miter limit the way canvas' SVG renderer
+// expects it, in mm rather than as a ratio of the stroke width.
+func svgStyle(style canvas.Style) canvas.Style {
+	if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok && !math.IsNaN(miter.Limit) {
+		miter.Limit *= style.StrokeWidth / 2
+		style.StrokeJoiner = miter
+	}
+	return style
+}
+
+// taperedOutline returns the outline for TaperedPolyline in the coordinates
+// of the path, drawn with the transformation `view`.
+func taperedOutline(view Matrix, width func(i int) float64, xy []float64, limit float64) *canvas.Path {
+	outline := &canvas.Path{}
+	if canvas.Equal(view.Det(), 0) {
+		return outline
+	}
+	if limit <= 0 {
+		limit = defaultMiterLimit
+	}
+	// the stroke is worked out in mm on the page, where the widths are
+	var pts []canvas.Point
+	var half []float64
+	for i := 0; i+1 < len(xy); i += 2 {
+		p := view.Dot(canvas.Point{X: xy[i], Y: xy[i+1]})
+		if n := len(pts); n > 0 && p.Equals(pts[n-1]) {
+			continue
+		}
+		pts = append(pts, p)
+		half = append(half, math.Max(width(i/2), 0)/2)
+	}
+	if len(pts) < 2 {
+		return outline
+	}
+
+	var left, right []canvas.Point
+	normal := func(k int) canvas.Point { return pts[k+1].Sub(pts[k]).Rot90CCW().Norm(1) }
+	for i, p := range pts {
+		h := half[i]
+		var n0, n1 canvas.Point
+		switch i {
+		case 0:
+			n0 = normal(0)
+			n1 = n0
+		case len(pts) - 1:
+			n0 = normal(i - 1)
+			n1 = n0
+		default:
+			n0, n1 = normal(i-1), normal(i)
+		}
+		mid := n0.Add(n1)
+		if mid.Length() < 1e-9 {
+			left = append(left, p.Add(n0.Mul(h)), p.Add(n1.Mul(h)))
+			right = append(right, p.Sub(n0.Mul(h)), p.Sub(n1.Mul(h)))
+			continue
+		}
+		mid = mid.Norm(1)
+		if d := 1 / mid.Dot(n0); d <= limit {
+			left = append(left, p.Add(mid.Mul(h*d)))
+			right = append(right, p.Sub(mid.Mul(h*d)))
+		} else {
+			left = append(left, p.Add(n0.Mul(h)), p.Add(n1.Mul(h)))
+			right = append(right, p.Sub(n0.Mul(h)), p.Sub(n1.Mul(h)))
+		}
+	}
+
+	// down the right side and back up the left, clockwise whichever way it
+	// goes so the overlaps of many fill in with the nonzero rule
+	outline.MoveTo(right[0].X, right[0].Y)
+	for _, p := range right[1:] {
+		outline.LineTo(p.X, p.Y)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		outline.LineTo(left[i].X, left[i].Y)
+	}
+	outline.Close()
+	return outline.Transform(view.Inv())
+}
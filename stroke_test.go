@@ -0,0 +1,107 @@
+package gart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+const strokeRes = 4
+
+// drawn is true when the pixel at x,y mm is mostly covered
+func drawn(img image.Image, x, y float64) bool {
+	h := img.Bounds().Dy()
+	_, _, _, a := img.At(int(x*strokeRes), h-1-int(y*strokeRes)).RGBA()
+	return a > 0x8000
+}
+
+func TestStrokeStyle(t *testing.T) {
+	vee := func(d Drawer) { d.Polyline(3, 2, 5, 6, 7, 2) }
+	bar := func(d Drawer) { d.Polyline(2, 5, 8, 5) }
+	long := func(d Drawer) { d.Polyline(0, 5, 20, 5) }
+	tests := []struct {
+		name  string
+		style func(d Drawer)
+		path  func(d Drawer)
+		x, y  float64
+		drawn bool
+	}{
+		{"beveled past the default limit", func(d Drawer) {}, vee, 5, 7.5, false},
+		{"mitered", func(d Drawer) { d.SetMiterLimit(3) }, vee, 5, 7.5, true},
+		{"round join", func(d Drawer) { d.SetLineJoin(JoinRound) }, vee, 5, 6.8, true},
+		{"round join corner", func(d Drawer) { d.SetLineJoin(JoinRound) }, vee, 5, 7.5, false},
+		{"butt cap", func(d Drawer) {}, bar, 8.6, 5, false},
+		{"square cap", func(d Drawer) { d.SetLineCap(CapSquare) }, bar, 8.6, 5.8, true},
+		{"round cap", func(d Drawer) { d.SetLineCap(CapRound) }, bar, 8.6, 5, true},
+		{"round cap corner", func(d Drawer) { d.SetLineCap(CapRound) }, bar, 8.9, 5.9, false},
+		{"dash", func(d Drawer) { d.SetDashes(0, 2, 2) }, long, 1, 5, true},
+		{"gap", func(d Drawer) { d.SetDashes(0, 2, 2) }, long, 3, 5, false},
+		{"dash offset", func(d Drawer) { d.SetDashes(2, 2, 2) }, long, 1, 5, false},
+		{"popped", func(d Drawer) { d.Push(); d.SetDashes(0, 2, 2); d.Pop() }, long, 3, 5, true},
+	}
+	for _, test := range tests {
+		for _, d := range []Drawer{NewContext(20, 10), NewGGContext(20, 10, strokeRes), NewRecorder(20, 10)} {
+			d.SetStrokeColor(color.Black)
+			d.SetStrokeWidth(2)
+			test.style(d)
+			test.path(d)
+			d.Stroke()
+			img, err := rasterize(d, strokeRes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := drawn(img, test.x, test.y); got != test.drawn {
+				t.Errorf("%s: %T drawn at %v,%v = %v, want %v", test.name, d, test.x, test.y, got, test.drawn)
+			}
+		}
+	}
+}
+
+func TestTaperedPolyline(t *testing.T) {
+	for _, d := range []Drawer{NewContext(20, 10), NewGGContext(20, 10, strokeRes), NewRecorder(20, 10)} {
+		d.SetFillColor(color.Black)
+		d.Scale(2, 2) // the widths stay in mm
+		d.TaperedPolyline(func(i int) float64 { return []float64{4, 4, 0}[i] }, 1, 1, 1, 2.5, 9, 2.5)
+		d.Fill()
+		img, err := rasterize(d, strokeRes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range []struct {
+			x, y  float64
+			drawn bool
+		}{
+			{2, 3, true},     // the upright
+			{0.5, 6.5, true}, // the mitered corner
+			{3, 6.5, true},   // wide at the start
+			{16, 5, true},
+			{16, 6.5, false}, // narrow at the end
+			{18.5, 5, false},
+		} {
+			if got := drawn(img, test.x, test.y); got != test.drawn {
+				t.Errorf("%T drawn at %v,%v = %v, want %v", d, test.x, test.y, got, test.drawn)
+			}
+		}
+	}
+}
+
+func TestStrokeStyleSVG(t *testing.T) {
+	ctx := NewContext(20, 10)
+	ctx.SetStrokeColor(color.Black)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetMiterLimit(3)
+	ctx.SetDashes(1, 2, 1)
+	ctx.Polyline(2, 2, 5, 6, 8, 2)
+	ctx.Stroke()
+	var buf bytes.Buffer
+	if err := SVGWriter(&buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"stroke-miterlimit:3", "stroke-dasharray:2 1", "stroke-dashoffset:1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("SVG = %s, want it to contain %s", buf.String(), want)
+		}
+	}
+}
@@ -10,7 +10,6 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"math"
-	"math/rand"
 	"os"
 	//"sort"
 
@@ -18,8 +17,6 @@ import (
 )
 
 const (
-	width            = 215.9 // mm
-	height           = 279.4 // mm
 	defaultLineWidth = 0.3
 	startingCracks   = 10
 
@@ -31,10 +28,25 @@ const (
 	emptyAngle = -1
 )
 
+// sandResolution is 10 dots per mm for the sand grains, fine enough not to
+// look blocky printed. The cracks are drawn in mm as if they were the
+// original's pixels, so each grain is scattered as dots over its mm.
+var sandResolution = gart.DPI(254)
+
+// dotsPerGrain is how many dots a grain is scattered as, each as dark as
+// needed to put down the same ink as a mm sized grain.
+const dotsPerGrain = 25
+
 type degrees int
 
 var (
-	seedFlag = flag.String("seed", "", "Hex value for the seed to use")
+	seedFlag    = flag.String("seed", "", "Hex value for the seed to use")
+	formatsFlag = flag.String("formats", "png", "Comma separated output formats, ex. png,svg,pdf")
+	backendFlag = flag.String("backend", gart.CanvasBackend, "Drawing backend, canvas or gg (faster, png only)")
+	paperFlag   = gart.PaperFlag(gart.Letter)
+	pngFlags    = gart.PNGFlags()
+	captionFlag = gart.CaptionFlag()
+	pensFlag    = flag.Int("pens", 0, "Also save a file per pen, grouping the stroke colors into this many pens")
 )
 
 func main() {
@@ -43,9 +55,16 @@ func main() {
 	if err != nil {
 		fmt.Printf("Unable to set the seed: %v\n", err)
 	}
-	ctx := gart.NewContext(width, height)
+	page := gart.PageOptions{Paper: *paperFlag}
+	ctx, err := gart.NewPageDrawer(*backendFlag, page)
+	if err != nil {
+		fmt.Printf("Unable to create the drawer: %v\n", err)
+		return
+	}
 	ctx.SetFillColor(color.Gray{245})
-	ctx.FillRect(0, 0, width, height)
+	w, h := page.Size()
+	ctx.FillRect(0, 0, w, h)
+	ctx.ClipRect(0, 0, w, h) // the sand painter runs off the edges
 	ctx.SetStrokeColor(color.Black)
 	ctx.SetStrokeWidth(defaultLineWidth)
 
@@ -55,37 +74,47 @@ func main() {
 		fmt.Printf("Unable get palette: %v\n", err)
 		return
 	}
-	s := newSubstrate(ctx, dimx, dimy, maxnum, palette)
+	s := newSubstrate(ctx, g, dimx, dimy, maxnum, palette)
 	s.begin()
 	s.makeCrack()
 	s.draw()
 
-	if true {
-		if err := g.SafeWrite(ctx, "samples/substrate-", ".png"); err != nil {
-			fmt.Printf("Unable write image: %v\n", err)
-			return
-		}
-	} else {
-		if err := g.SafeWrite(ctx, "samples/substrate-", ".svg"); err != nil {
-			fmt.Printf("Unable write image: %v\n", err)
-			return
+	if err := g.SafeWriteAll(ctx, "samples/substrate-", gart.ParseFormats(*formatsFlag)...); err != nil {
+		fmt.Printf("Unable write image: %v\n", err)
+		return
+	}
+	if *pensFlag > 0 {
+		if err := g.SafeWriteLayers(ctx, "samples/substrate-", *pensFlag, gart.ParseFormats(*formatsFlag)...); err != nil {
+			fmt.Printf("Unable write the pens: %v\n", err)
 		}
 	}
 }
 
 type Substrate struct {
-	ctx   *gart.Context
+	ctx   gart.Drawer
 	cgrid []degrees
+	rnd   gart.Seed // cracks
+	sand  gart.Seed // sand painter, separate so it doesn't change the cracks
+	// grains is where the sand painter puts its grains, nil with the gg
+	// backend which draws them as paths
+	grains *gart.Raster
 
 	cracks             []*Crack
 	goodcolor          color.Palette
 	dimx, dimy, maxnum int
 }
 
-func newSubstrate(ctx *gart.Context, dimx, dimy, maxnum int, palette color.Palette) Substrate {
+func newSubstrate(ctx gart.Drawer, g gart.Seed, dimx, dimy, maxnum int, palette color.Palette) Substrate {
+	var grains *gart.Raster
+	if c, ok := ctx.(*gart.Context); ok {
+		grains = c.NewRaster(sandResolution)
+	}
 	return Substrate{
+		grains:    grains,
 		ctx:       ctx,
 		cgrid:     make([]degrees, dimy*dimx),
+		rnd:       g.Stream("cracks"),
+		sand:      g.Stream("sand"),
 		cracks:    make([]*Crack, 0, maxnum),
 		goodcolor: palette,
 		dimx:      dimx,
@@ -103,9 +132,9 @@ func (s *Substrate) begin() {
 	}
 	// make random crack seeds
 	for k := 0; k < 6; k++ {
-		x := rand.Intn(s.dimx)
-		y := rand.Intn(s.dimy)
-		s.setAngle(x, y, degrees(rand.Intn(360)))
+		x := s.rnd.Intn(s.dimx)
+		y := s.rnd.Intn(s.dimy)
+		s.setAngle(x, y, degrees(s.rnd.Intn(360)))
 	}
 
 	// make just three cracks
@@ -154,8 +183,8 @@ type Crack struct {
 func newCrack(s *Substrate) *Crack {
 	// find placement along existing crack
 	c := &Crack{
-		color: s.goodcolor[rand.Intn(len(s.goodcolor))],
-		grain: randRange(0.01, 0.1),
+		color: s.goodcolor[s.rnd.Intn(len(s.goodcolor))],
+		grain: s.rnd.Range(0.01, 0.1),
 	}
 	c.findStart(s)
 	return c
@@ -184,15 +213,15 @@ func (c *Crack) regionColor(s *Substrate) {
 		}
 	}
 	// render sand painter
-	c.render(s.ctx, rx, ry, c.x, c.y)
+	c.render(s, rx, ry, c.x, c.y)
 }
 
 func (c *Crack) findStart(s *Substrate) {
 	if px, py, found := s.findRandomPoint(); found {
 		// start crack
 		ang := s.getAngle(px, py)
-		randDeg := degrees(90 + randRange(-2, 2.1))
-		if rand.Intn(100) < 50 {
+		randDeg := degrees(90 + s.rnd.Range(-2, 2.1))
+		if s.rnd.Intn(100) < 50 {
 			ang -= randDeg
 		} else {
 			ang += randDeg
@@ -204,8 +233,8 @@ func (c *Crack) findStart(s *Substrate) {
 
 func (s *Substrate) findRandomPoint() (x, y int, found bool) {
 	for timeout := 0; timeout < 1000; timeout++ {
-		px := rand.Intn(s.dimx)
-		py := rand.Intn(s.dimy)
+		px := s.rnd.Intn(s.dimx)
+		py := s.rnd.Intn(s.dimy)
 		if s.getAngle(px, py) != emptyAngle {
 			return px, py, true
 		}
@@ -222,7 +251,9 @@ func (c *Crack) startCrack(s *Substrate, X, Y float64, T degrees) {
 	c.x += dx
 	c.y += dy
 
-	s.ctx.MoveTo(c.x, c.y)
+	if s.grains == nil {
+		s.ctx.MoveTo(c.x, c.y)
+	}
 }
 
 func (c *Crack) move(s *Substrate) {
@@ -233,8 +264,8 @@ func (c *Crack) move(s *Substrate) {
 
 	// bound check
 	const z = 0.33
-	cx := int(c.x + randRange(-z, z)) // add fuzz
-	cy := int(c.y + randRange(-z, z))
+	cx := int(c.x + s.rnd.Range(-z, z)) // add fuzz
+	cy := int(c.y + s.rnd.Range(-z, z))
 
 	// draw sand painter
 	c.regionColor(s)
@@ -242,7 +273,11 @@ func (c *Crack) move(s *Substrate) {
 	// draw black crack
 	s.ctx.SetStrokeColor(color.RGBA{0, 0, 0, 85})
 	//s.ctx.SetStrokeColor(c.color)
-	s.ctx.LineTo(c.x+randRange(-z, z), c.y+randRange(-z, z))
+	if s.grains != nil {
+		// the sand is on the Raster, there's no path for it to carry on from
+		s.ctx.MoveTo(c.x, c.y)
+	}
+	s.ctx.LineTo(c.x+s.rnd.Range(-z, z), c.y+s.rnd.Range(-z, z))
 	s.ctx.Stroke()
 
 	if s.inBounds(cx, cy) {
@@ -264,30 +299,44 @@ func (c *Crack) move(s *Substrate) {
 	s.ctx.Stroke()
 }
 
-func (c *Crack) render(ctx *gart.Context, x, y, ox, oy float64) {
+func (c *Crack) render(s *Substrate, x, y, ox, oy float64) {
+	ctx := s.ctx
 	// modulate grain
-	c.grain += gart.Clamp(randRange(-0.050, 0.050), 0, 1.0)
+	c.grain += gart.Clamp(s.sand.Range(-0.050, 0.050), 0, 1.0)
 
 	// calculate grains by distance
 	//int grains = int(sqrt((ox-x)*(ox-x)+(oy-y)*(oy-y)));
 	grains := 32.0
+	if s.grains == nil {
+		ctx.MoveTo(ox, oy)
+	}
+	res := float64(sandResolution)
+	dotAlpha := res * res / dotsPerGrain
 
 	// lay down grains of sand (transparent pixels)
 	w := c.grain / (grains - 1)
-	ctx.MoveTo(ox, oy)
+	rr, gg, bb, _ := c.color.RGBA()
 	for i := 0.0; i < grains; i++ {
 		aa := 0.1 - i/(grains*10.0)
-		rr, gg, bb, _ := c.color.RGBA()
+		siniw := math.Sin(math.Sin(i * w))
+		gx, gy := ox+(x-ox)*siniw, oy+(y-oy)*siniw
+		if s.grains != nil {
+			dot := color.NRGBA64{
+				R: uint16(rr),
+				G: uint16(gg),
+				B: uint16(bb),
+				A: uint16(gart.Clamp(aa*dotAlpha, 0, 1) * 0xffff)}
+			for j := 0; j < dotsPerGrain; j++ {
+				s.grains.Blend(gx+s.sand.Range(-0.5, 0.5), gy+s.sand.Range(-0.5, 0.5), dot)
+			}
+			continue
+		}
 		ctx.SetStrokeColor(color.RGBA64{
 			R: uint16(rr),
 			G: uint16(gg),
 			B: uint16(bb),
 			A: uint16(aa * 0xffff)})
-
-		siniw := math.Sin(math.Sin(i * w))
-		ctx.LineTo(
-			ox+(x-ox)*siniw,
-			oy+(y-oy)*siniw)
+		ctx.LineTo(gx, gy)
 	}
 }
 
@@ -326,7 +375,7 @@ func takeColors(fname string) (color.Palette, error) {
 	return pal[:maxPal], nil
 }
 
-func showPalette(ctx *gart.Context, palette color.Palette, w, h float64) {
+func showPalette(ctx gart.Drawer, palette color.Palette, w, h float64) {
 	rows := 16
 	cols := len(palette) / rows
 	dx := w / float64(cols)
@@ -348,13 +397,6 @@ func calcDxy(angle degrees, mag float64) (dx, dy float64) {
 	return mag * cos, mag * sin
 }
 
-func randRange(low, high float64) float64 {
-	if high < low {
-		low, high = high, low
-	}
-	return rand.Float64()*(high-low) + low
-}
-
 func angleDiff(ang1, ang2 degrees) float64 {
 	return math.Abs(float64(ang1 - ang2))
 }
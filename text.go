@@ -0,0 +1,218 @@
+package gart
+
+import (
+	"image/color"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/tdewolff/canvas"
+)
+
+// TextAlign is which end of a line of text is at the x given to DrawText.
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+)
+
+// offset is where a line `width` wide starts.
+func (a TextAlign) offset(width float64) float64 {
+	switch a {
+	case AlignCenter:
+		return -width / 2
+	case AlignRight:
+		return -width
+	}
+	return 0
+}
+
+func (a TextAlign) canvasAlign() canvas.TextAlign {
+	switch a {
+	case AlignCenter:
+		return canvas.Center
+	case AlignRight:
+		return canvas.Right
+	}
+	return canvas.Left
+}
+
+// Font is a TTF or OTF font, see LoadFont, or Hershey.
+type Font struct {
+	name   string
+	family *canvas.FontFamily // nil for Hershey
+}
+
+// Hershey is the built in single stroke font. It's stroked with the stroke
+// color and width, rather than filled, so a plotter draws it in one pass of the pen.
+// It only has the printable ASCII characters, the others are drawn as '?'.
+var Hershey = &Font{name: "Hershey Simplex"}
+
+// LoadFont reads a TTF or OTF file.
+func LoadFont(fname string) (*Font, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(strings.TrimSuffix(path.Base(fname), path.Ext(fname)), data)
+}
+
+// ParseFont reads a TTF or OTF font from `data`.
+func ParseFont(name string, data []byte) (*Font, error) {
+	family := canvas.NewFontFamily(name)
+	if err := family.LoadFont(data, canvas.FontRegular); err != nil {
+		return nil, err
+	}
+	return &Font{name, family}, nil
+}
+
+func (f *Font) Name() string {
+	return f.name
+}
+
+// face returns the canvas face for `size` units.
+func (f *Font) face(size float64, col color.Color) canvas.FontFace {
+	return f.family.Face(size/float64(Pt), col, canvas.FontRegular, canvas.FontNormal)
+}
+
+// Width returns how wide the longest line of `s` is at `size`.
+func (f *Font) Width(s string, size float64) float64 {
+	width := 0.0
+	for _, line := range strings.Split(s, "\n") {
+		w := 0.0
+		if f.family == nil {
+			w = hersheyWidth(line) * size * hersheyScale
+		} else {
+			w = f.face(size, canvas.Black).TextWidth(line)
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// SetFont sets the font for DrawText and its size in the drawing's units,
+// ex. 10*gart.Pt for 10 point text on a page in mm. Capitals are about 0.7 of it.
+func (ctx *Context) SetFont(f *Font, size float64) {
+	ctx.state.font = f
+	ctx.state.fontSize = size
+}
+
+// SetTextAlign sets which end of the lines DrawText puts at x.
+func (ctx *Context) SetTextAlign(a TextAlign) {
+	ctx.state.align = a
+}
+
+// font returns the font and size in effect, 12pt Hershey when none was set.
+func (ctx *Context) font() (*Font, float64) {
+	f, size := ctx.state.font, ctx.state.fontSize
+	if f == nil {
+		f = Hershey
+	}
+	if size <= 0 {
+		size = 12 * float64(Pt) / ctx.unit
+	}
+	return f, size
+}
+
+// DrawText draws `s` with the start of its baseline at x,y turned `angle`
+// radians counter clockwise about there, the lines are split at "\n".
+// TTF and OTF fonts are filled with the fill color or paint, Hershey is stroked.
+func (ctx *Context) DrawText(s string, x, y, angle float64) {
+	f, size := ctx.font()
+	view := ctx.ctx.View()
+	at := canvas.Identity.Translate(x, y).Rotate(Degrees(angle))
+	if f.family == nil {
+		style := ctx.ctx.Style
+		style.FillColor = canvas.Transparent
+		ctx.draw(hersheyPath(s, size, ctx.state.align).Transform(at), style, view, nil, ctx.state.stroke)
+		return
+	}
+	face := f.face(size, ctx.ctx.Style.FillColor)
+	for i, line := range strings.Split(s, "\n") {
+		text := canvas.NewTextLine(face, line, ctx.state.align.canvasAlign())
+		m := at.Translate(0, -float64(i)*size*1.2)
+		if ctx.state.fill == nil {
+			ctx.ctx.RenderText(text, view.Mul(m))
+			continue
+		}
+		// paints are in the drawing's coordinates, so the glyphs are moved there
+		style := ctx.ctx.Style
+		style.StrokeColor = canvas.Transparent
+		paths, _ := text.ToPaths()
+		for _, p := range paths {
+			ctx.draw(p.Transform(m), style, view, ctx.state.fill, nil)
+		}
+	}
+}
+
+// hersheyScale is the size of a Hershey unit for text of size 1, making
+// the 21 unit capitals 0.7 high like most fonts.
+const hersheyScale = 0.7 / 21
+
+// hersheyGlyph is the advance and pen strokes of a character, see hersheySimplex.
+type hersheyGlyph struct {
+	width  int8
+	coords []int8
+}
+
+func hersheyGlyphFor(r rune) hersheyGlyph {
+	if r < 32 || r > 126 {
+		r = '?'
+	}
+	return hersheySimplex[r-32]
+}
+
+// hersheyWidth is how wide `line` is in Hershey units.
+func hersheyWidth(line string) float64 {
+	width := 0
+	for _, r := range line {
+		width += int(hersheyGlyphFor(r).width)
+	}
+	return float64(width)
+}
+
+// hersheyLines returns the pen strokes of `s` at `size` with the start of
+// the first baseline at 0,0.
+func hersheyLines(s string, size float64, align TextAlign) [][]canvas.Point {
+	var lines [][]canvas.Point
+	scale := size * hersheyScale
+	for i, line := range strings.Split(s, "\n") {
+		x := align.offset(hersheyWidth(line) * scale)
+		y := -float64(i) * size * 1.2
+		for _, r := range line {
+			g := hersheyGlyphFor(r)
+			var cur []canvas.Point
+			for j := 0; j+1 < len(g.coords); j += 2 {
+				if g.coords[j] == -1 && g.coords[j+1] == -1 {
+					lines = append(lines, cur)
+					cur = nil
+					continue
+				}
+				cur = append(cur, canvas.Point{X: x + float64(g.coords[j])*scale, Y: y + float64(g.coords[j+1])*scale})
+			}
+			if len(cur) > 0 {
+				lines = append(lines, cur)
+			}
+			x += float64(g.width) * scale
+		}
+	}
+	return lines
+}
+
+func hersheyPath(s string, size float64, align TextAlign) *canvas.Path {
+	p := &canvas.Path{}
+	for _, line := range hersheyLines(s, size, align) {
+		for i, pt := range line {
+			if i == 0 {
+				p.MoveTo(pt.X, pt.Y)
+			} else {
+				p.LineTo(pt.X, pt.Y)
+			}
+		}
+	}
+	return p
+}
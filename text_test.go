@@ -0,0 +1,90 @@
+package gart
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestHersheyText(t *testing.T) {
+	if got, want := Hershey.Width("TT\nT", 21), 2*16*0.7; math.Abs(got-want) > 1e-9 {
+		t.Errorf("width = %v, want %v", got, want)
+	}
+	ctx := NewContext(100, 100)
+	ctx.SetStrokeColor(color.Black)
+	ctx.SetFont(Hershey, 10)
+	ctx.DrawText("T\nT", 10, 50, 0)
+	paths, err := PlotPaths(ctx, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 4 {
+		t.Fatalf("plotted %d paths, want the 2 strokes of each T", len(paths))
+	}
+	// the top of the first T's stem, 0.7 of the size up from the baseline
+	if p := paths[0].Points[0]; math.Abs(p.X-(10+8*10*hersheyScale)) > 1e-6 || math.Abs(p.Y-57) > 1e-6 {
+		t.Errorf("stem starts at %v", p)
+	}
+}
+
+func TestFontText(t *testing.T) {
+	f, err := ParseFont("Go", goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := f.Width("Go", 10); w <= 0 || w > 20 {
+		t.Errorf("width = %v", w)
+	}
+	ctx := NewContext(20, 10)
+	ctx.SetFont(f, 8)
+	ctx.SetTextAlign(AlignCenter)
+	ctx.DrawText("Go", 10, 2, 0)
+	img, err := PNGOptions{Resolution: 4}.Rasterize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(40, 25).RGBA(); a == 0 {
+		t.Error("nothing drawn at the middle of the text")
+	}
+	if _, _, _, a := img.At(2, 5).RGBA(); a != 0 {
+		t.Error("drawn left of the centered text")
+	}
+	if err := SVGWriter(&bytes.Buffer{}, ctx); err != nil {
+		t.Errorf("SVG failed: %v", err)
+	}
+}
+
+func TestCaption(t *testing.T) {
+	p := &Provenance{Sketch: "substrate", Seed: "1a2b3c4d", Timestamp: time.Date(2020, 10, 21, 12, 0, 0, 0, time.UTC)}
+	if got, want := (Caption{}).Text(p), "substrate  1a2b3c4d  2020-10-21"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	for s, want := range map[string]Corner{"tl": TopLeft, "Bottom-Right": BottomRight} {
+		if c, err := ParseCorner(s); err != nil || c != want {
+			t.Errorf("ParseCorner(%q) = %v, %v, want %v", s, c, err, want)
+		}
+	}
+	if _, err := ParseCorner("middle"); err == nil {
+		t.Error("ParseCorner(middle) didn't fail")
+	}
+
+	SetCaption(&Caption{Corner: TopLeft, Color: color.Black})
+	defer SetCaption(nil)
+	ctx := NewContext(100, 100)
+	for _, d := range []Drawer{ctx, NewRecorder(100, 100)} {
+		d.SetStrokeColor(color.Black)
+		d.MoveTo(0, 0)
+		d.LineTo(10, 10)
+		d.Stroke()
+		stamp := stamped(d, p)
+		orig, _ := PlotPaths(d, 0.1)
+		got, _ := PlotPaths(stamp, 0.1)
+		if len(orig) != 1 || len(got) <= 1 {
+			t.Errorf("%T plotted %d paths, %d stamped", d, len(orig), len(got))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package gart
+
+import (
+	"math"
+
+	"github.com/tdewolff/canvas"
+)
+
+// Matrix is an affine transformation, see canvas.Matrix
+type Matrix = canvas.Matrix
+
+// Identity is the matrix that doesn't transform anything
+var Identity = canvas.Identity
+
+// The transformations are composed with the current matrix and saved by Push
+// and restored by Pop. The matrix in effect when a path is stroked or filled
+// applies to the whole path. Stroke widths are not scaled.
+// Angles are in radians.
+
+// Translate moves the origin to x,y
+func (ctx *Context) Translate(x, y float64) {
+	ctx.ctx.Translate(x, y)
+}
+
+// Rotate rotates counter clockwise around the origin
+func (ctx *Context) Rotate(angle float64) {
+	ctx.ctx.Rotate(Degrees(angle))
+}
+
+// RotateAbout rotates counter clockwise around x,y
+func (ctx *Context) RotateAbout(angle, x, y float64) {
+	ctx.ctx.RotateAbout(Degrees(angle), x, y)
+}
+
+// Scale scales by sx,sy around the origin
+func (ctx *Context) Scale(sx, sy float64) {
+	ctx.ctx.Scale(sx, sy)
+}
+
+// Skew shears along X by angle ax and along Y by angle ay
+func (ctx *Context) Skew(ax, ay float64) {
+	ctx.ctx.Shear(math.Tan(ax), math.Tan(ay))
+}
+
+// Matrix returns the current transformation
+func (ctx *Context) Matrix() Matrix {
+	return ctx.ctx.View()
+}
+
+// SetMatrix replaces the current transformation
+func (ctx *Context) SetMatrix(m Matrix) {
+	ctx.ctx.SetView(m)
+}
+
+// ResetMatrix goes back to the identity transformation, or the page units
+func (ctx *Context) ResetMatrix() {
+	ctx.ctx.SetView(Identity.Scale(ctx.unit, ctx.unit))
+}
+
+// FitBounds maps the world space box minX,minY to maxX,maxY into the
+// width x height of the canvas less `margin` on every side, keeping the
+// aspect ratio and centering it.
+func (ctx *Context) FitBounds(minX, minY, maxX, maxY, margin float64) {
+	ctx.SetMatrix(ctx.Matrix().Mul(fitBounds(ctx.c.W/ctx.unit, ctx.c.H/ctx.unit, minX, minY, maxX, maxY, margin)))
+}
+
+func fitBounds(width, height, minX, minY, maxX, maxY, margin float64) Matrix {
+	w, h := maxX-minX, maxY-minY
+	innerW, innerH := width-2*margin, height-2*margin
+	scale := 1.0
+	if w > 0 && h > 0 {
+		scale = math.Min(innerW/w, innerH/h)
+	} else if w > 0 {
+		scale = innerW / w
+	} else if h > 0 {
+		scale = innerH / h
+	}
+	return Identity.
+		Translate(width/2, height/2).
+		Scale(scale, scale).
+		Translate(-(minX+maxX)/2, -(minY+maxY)/2)
+}
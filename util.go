@@ -11,6 +11,11 @@ func Radians(degrees float64) float64 {
 	return degrees * math.Pi / 180
 }
 
+// Degrees converts radians to degrees
+func Degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
 // Basename retrieves the basename of a file path.
 func Basename(fName string) string {
 	if lslash := strings.LastIndex(fName, "/"); lslash != -1 {
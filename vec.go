@@ -0,0 +1,89 @@
+package gart
+
+import "math"
+
+// Epsilon is how close, in mm, floats have to be to count as equal, so the
+// rounding errors of the float math don't decide whether lines touch.
+const Epsilon = 1e-9
+
+// Vec2 is a point or direction in the plane, in the same float coordinates
+// that are drawn with.
+type Vec2 struct {
+	X, Y float64
+}
+
+// Add returns v+o
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{v.X + o.X, v.Y + o.Y}
+}
+
+// Sub returns v-o
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{v.X - o.X, v.Y - o.Y}
+}
+
+// Scale returns v times f
+func (v Vec2) Scale(f float64) Vec2 {
+	return Vec2{v.X * f, v.Y * f}
+}
+
+// Neg returns -v
+func (v Vec2) Neg() Vec2 {
+	return Vec2{-v.X, -v.Y}
+}
+
+// Dot returns the dot product, |v| |o| cos of the angle between them.
+func (v Vec2) Dot(o Vec2) float64 {
+	return v.X*o.X + v.Y*o.Y
+}
+
+// Cross returns the z of the cross product, |v| |o| sin of the angle from v
+// to o, so it's positive when o is counter clockwise from v.
+func (v Vec2) Cross(o Vec2) float64 {
+	return v.X*o.Y - v.Y*o.X
+}
+
+// Len returns the length of v
+func (v Vec2) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// Normalize returns v with a length of 1, or the zero vector for one shorter than Epsilon.
+func (v Vec2) Normalize() Vec2 {
+	l := v.Len()
+	if l < Epsilon {
+		return Vec2{}
+	}
+	return Vec2{v.X / l, v.Y / l}
+}
+
+// Rotate returns v rotated counter clockwise by `angle` radians around the origin.
+func (v Vec2) Rotate(angle float64) Vec2 {
+	sin, cos := math.Sincos(angle)
+	return Vec2{v.X*cos - v.Y*sin, v.X*sin + v.Y*cos}
+}
+
+// Lerp interpolates from v to o as t goes from 0 to 1
+func (v Vec2) Lerp(o Vec2, t float64) Vec2 {
+	return Vec2{Lerp(v.X, o.X, t), Lerp(v.Y, o.Y, t)}
+}
+
+// Dist returns the distance between v and o
+func (v Vec2) Dist(o Vec2) float64 {
+	return o.Sub(v).Len()
+}
+
+// Angle returns the direction of v in radians, counter clockwise from the X axis, from -Pi to Pi.
+func (v Vec2) Angle() float64 {
+	return math.Atan2(v.Y, v.X)
+}
+
+// AngleTo returns the angle to turn v counter clockwise to point along o, from -Pi to Pi.
+func (v Vec2) AngleTo(o Vec2) float64 {
+	return math.Atan2(v.Cross(o), v.Dot(o))
+}
+
+// Equals is true when v and o are less than Epsilon apart.
+func (v Vec2) Equals(o Vec2) bool {
+	return v.Dist(o) < Epsilon
+}
@@ -0,0 +1,47 @@
+package gart
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVec2(t *testing.T) {
+	v, o := Vec2{3, 4}, Vec2{-1, 2}
+	tests := []struct {
+		name      string
+		got, want Vec2
+	}{
+		{"Add", v.Add(o), Vec2{2, 6}},
+		{"Sub", v.Sub(o), Vec2{4, 2}},
+		{"Scale", v.Scale(2), Vec2{6, 8}},
+		{"Neg", v.Neg(), Vec2{-3, -4}},
+		{"Normalize", v.Normalize(), Vec2{0.6, 0.8}},
+		{"Normalize zero", Vec2{}.Normalize(), Vec2{}},
+		{"Rotate", Vec2{1, 0}.Rotate(math.Pi / 2), Vec2{0, 1}},
+		{"Lerp", v.Lerp(o, 0.5), Vec2{1, 3}},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equals(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	floats := []struct {
+		name      string
+		got, want float64
+	}{
+		{"Dot", v.Dot(o), 5},
+		{"Cross", v.Cross(o), 10},
+		{"Cross clockwise", o.Cross(v), -10},
+		{"Len", v.Len(), 5},
+		{"Dist", v.Dist(o), math.Sqrt(20)},
+		{"Angle", Vec2{0, -2}.Angle(), -math.Pi / 2},
+		{"AngleTo", Vec2{1, 0}.AngleTo(Vec2{-1, 1}), 3 * math.Pi / 4},
+		{"AngleTo clockwise", Vec2{-1, 1}.AngleTo(Vec2{1, 0}), -3 * math.Pi / 4},
+	}
+	for _, tt := range floats {
+		if math.Abs(tt.got-tt.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package gart
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	webpMaxSize       = 1 << 14
+	webpGreenSymbols  = 256 + 24 // literals plus backward reference lengths
+	webpDistSymbols   = 40
+	webpMaxCodeLength = 15
+	webpMaxCLLength   = 7
+)
+
+// Order in which the code length code lengths are stored (see the VP8L spec).
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP writes img as a lossless (VP8L) WebP.
+// It's a deliberately simple encoder: no transforms, no color cache and no
+// backward references, just one set of Huffman codes over the ARGB values.
+// The files are bigger than libwebp's but decode everywhere.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return fmt.Errorf("webp: unsupported image size %dx%d", width, height)
+	}
+
+	pix := make([]color.NRGBA, 0, width*height)
+	hasAlpha := false
+	var green, red, blue, alpha [256]uint32
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pix = append(pix, c)
+			hasAlpha = hasAlpha || c.A != 0xff
+			green[c.G]++
+			red[c.R]++
+			blue[c.B]++
+			alpha[c.A]++
+		}
+	}
+
+	bw := &webpBitWriter{}
+	bw.write(0x2f, 8) // signature
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+	bw.write(0, 1) // no transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single group of prefix codes
+
+	greenCounts := make([]uint32, webpGreenSymbols)
+	copy(greenCounts, green[:])
+	greenCode := bw.writePrefixCode(greenCounts)
+	redCode := bw.writePrefixCode(red[:])
+	blueCode := bw.writePrefixCode(blue[:])
+	alphaCode := bw.writePrefixCode(alpha[:])
+	bw.writePrefixCode(make([]uint32, webpDistSymbols))
+
+	for _, c := range pix {
+		greenCode.emit(bw, int(c.G))
+		redCode.emit(bw, int(c.R))
+		blueCode.emit(bw, int(c.B))
+		alphaCode.emit(bw, int(c.A))
+	}
+	data := bw.flush()
+
+	pad := len(data) & 1
+	var hdr [20]byte
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(4+8+len(data)+pad))
+	copy(hdr[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(data)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// webpBitWriter packs bits least significant first.
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *webpBitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *webpBitWriter) flush() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
+
+// webpCode is a canonical prefix code, with the codes already bit reversed.
+type webpCode struct {
+	codes   []uint32
+	lengths []uint8
+}
+
+func (c webpCode) emit(bw *webpBitWriter, sym int) {
+	if n := c.lengths[sym]; n > 0 {
+		bw.write(c.codes[sym], uint(n))
+	}
+}
+
+// writePrefixCode writes the prefix code for the histogram `counts`
+// and returns the code to use for the symbols.
+func (bw *webpBitWriter) writePrefixCode(counts []uint32) webpCode {
+	var used []int
+	for sym, n := range counts {
+		if n > 0 {
+			used = append(used, sym)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	code := webpCode{
+		codes:   make([]uint32, len(counts)),
+		lengths: make([]uint8, len(counts)),
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		// Simple code, one symbol takes zero bits and two take one bit each.
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			code.codes[used[1]] = 1
+			code.lengths[used[0]] = 1
+			code.lengths[used[1]] = 1
+		}
+		return code
+	}
+
+	lengths := huffmanLengths(counts, webpMaxCodeLength)
+	var clCounts [19]uint32
+	for _, l := range lengths {
+		clCounts[l]++
+	}
+	clLengths := huffmanLengths(clCounts[:], webpMaxCLLength)
+	numCL := 4
+	for i, sym := range webpCodeLengthOrder {
+		if clLengths[sym] > 0 && i+1 > numCL {
+			numCL = i + 1
+		}
+	}
+	bw.write(0, 1) // normal code
+	bw.write(uint32(numCL-4), 4)
+	for _, sym := range webpCodeLengthOrder[:numCL] {
+		bw.write(uint32(clLengths[sym]), 3)
+	}
+	bw.write(0, 1) // code lengths for the whole alphabet follow
+
+	clCode := canonicalCode(clLengths)
+	for _, l := range lengths {
+		clCode.emit(bw, int(l))
+	}
+	return canonicalCode(lengths)
+}
+
+// canonicalCode assigns the canonical codes for `lengths`.
+// A lone symbol is decoded without reading any bits so it gets length zero.
+func canonicalCode(lengths []uint8) webpCode {
+	code := webpCode{
+		codes:   make([]uint32, len(lengths)),
+		lengths: make([]uint8, len(lengths)),
+	}
+	var hist [webpMaxCodeLength + 1]uint32
+	nonZero := 0
+	for _, l := range lengths {
+		if l > 0 {
+			hist[l]++
+			nonZero++
+		}
+	}
+	if nonZero <= 1 {
+		return code
+	}
+	var next [webpMaxCodeLength + 1]uint32
+	cur := uint32(0)
+	for l := 1; l <= webpMaxCodeLength; l++ {
+		cur = (cur + hist[l-1]) << 1
+		next[l] = cur
+	}
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		rev := uint32(0)
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | (c>>i)&1
+		}
+		code.codes[sym] = rev
+		code.lengths[sym] = l
+	}
+	return code
+}
+
+// huffmanLengths returns the Huffman code lengths for `counts` limited to `maxLen` bits.
+// When the tree is too deep the counts are flattened and the tree rebuilt.
+func huffmanLengths(counts []uint32, maxLen int) []uint8 {
+	counts = append([]uint32(nil), counts...)
+	for {
+		lengths, depth := huffmanTree(counts)
+		if depth <= maxLen {
+			return lengths
+		}
+		for i, n := range counts {
+			if n > 0 {
+				counts[i] = n/2 + 1
+			}
+		}
+	}
+}
+
+type huffNode struct {
+	weight      uint64
+	left, right int // -1 for leaves
+}
+
+type huffHeap struct {
+	nodes []huffNode
+	idx   []int
+}
+
+func (h huffHeap) Len() int { return len(h.idx) }
+func (h huffHeap) Less(i, j int) bool {
+	a, b := h.nodes[h.idx[i]], h.nodes[h.idx[j]]
+	if a.weight != b.weight {
+		return a.weight < b.weight
+	}
+	return h.idx[i] < h.idx[j]
+}
+func (h huffHeap) Swap(i, j int)       { h.idx[i], h.idx[j] = h.idx[j], h.idx[i] }
+func (h *huffHeap) Push(x interface{}) { h.idx = append(h.idx, x.(int)) }
+func (h *huffHeap) Pop() interface{} {
+	last := h.idx[len(h.idx)-1]
+	h.idx = h.idx[:len(h.idx)-1]
+	return last
+}
+
+func huffmanTree(counts []uint32) (lengths []uint8, depth int) {
+	lengths = make([]uint8, len(counts))
+	h := &huffHeap{}
+	syms := []int{}
+	for sym, n := range counts {
+		if n > 0 {
+			h.nodes = append(h.nodes, huffNode{weight: uint64(n), left: -1, right: -1})
+			h.idx = append(h.idx, len(h.nodes)-1)
+			syms = append(syms, sym)
+		}
+	}
+	if len(syms) == 0 {
+		return lengths, 0
+	}
+	if len(syms) == 1 {
+		lengths[syms[0]] = 1
+		return lengths, 1
+	}
+	heap.Init(h)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(int)
+		b := heap.Pop(h).(int)
+		h.nodes = append(h.nodes, huffNode{weight: h.nodes[a].weight + h.nodes[b].weight, left: a, right: b})
+		heap.Push(h, len(h.nodes)-1)
+	}
+	var walk func(n, d int)
+	walk = func(n, d int) {
+		node := h.nodes[n]
+		if node.left < 0 {
+			lengths[syms[n]] = uint8(d)
+			if d > depth {
+				depth = d
+			}
+			return
+		}
+		walk(node.left, d+1)
+		walk(node.right, d+1)
+	}
+	walk(h.idx[0], 0)
+	return lengths, depth
+}
@@ -0,0 +1,59 @@
+package gart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	gradient := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 37; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x * 7), uint8(y * 12), uint8(x * y), uint8(255 - x)})
+		}
+	}
+	flat := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0xff
+	}
+	noise := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(i*i*31 + i)
+	}
+	noise.Pix[3] = 0xff
+
+	tests := []struct {
+		name string
+		img  *image.NRGBA
+	}{
+		{"gradient", gradient},
+		{"flat", flat},
+		{"noise", noise},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := EncodeWebP(&buf, tt.img); err != nil {
+			t.Fatalf("%s: EncodeWebP: %v", tt.name, err)
+		}
+		got, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tt.name, err)
+		}
+		if got.Bounds() != tt.img.Bounds() {
+			t.Fatalf("%s: got bounds %v, want %v", tt.name, got.Bounds(), tt.img.Bounds())
+		}
+		b := tt.img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				want := tt.img.NRGBAAt(x, y)
+				if c := color.NRGBAModel.Convert(got.At(x, y)); c != want {
+					t.Fatalf("%s: pixel (%d, %d) = %v, want %v", tt.name, x, y, c, want)
+				}
+			}
+		}
+	}
+}